@@ -18,9 +18,17 @@ package abi
 
 import (
 	"bytes"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
+	"math/big"
+	"reflect"
+	"strings"
+
+	"github.com/enode/common"
+	"github.com/enode/common/hexutil"
+	"github.com/enode/core/types"
 )
 
 // The ABI holds information about a contract's context and available
@@ -30,6 +38,17 @@ type ABI struct {
 	Constructor Method
 	Methods     map[string]Method
 	Events      map[string]Event
+	Errors      map[string]Error
+
+	// MethodsOrdered and EventsOrdered hold the methods and events in the order
+	// they are declared in the JSON definition.
+	MethodsOrdered []Method
+	EventsOrdered  []Event
+
+	// Warnings lists the oddities tolerated while parsing the JSON definition,
+	// such as indexed flags on function parameters, which are dropped, or
+	// overloaded methods, which are stored under a numbered name.
+	Warnings []string
 }
 
 // JSON returns a parsed ABI interface and error if it failed.
@@ -50,10 +69,16 @@ func JSON(reader io.Reader) (ABI, error) {
 // Method ids are created from the first 4 bytes of the hash of the
 // methods string signature. (signature = baz(uint32,string32))
 func (abi ABI) Pack(name string, args ...interface{}) ([]byte, error) {
+	return abi.PackWithOptions(PackOptions{}, name, args...)
+}
+
+// PackWithOptions works like Pack, but converts the Go values according to the
+// given options.
+func (abi ABI) PackWithOptions(opts PackOptions, name string, args ...interface{}) ([]byte, error) {
 	// Fetch the ABI of the requested method
 	if name == "" {
 		// constructor
-		arguments, err := abi.Constructor.Inputs.Pack(args...)
+		arguments, err := abi.Constructor.Inputs.PackWithOptions(opts, args...)
 		if err != nil {
 			return nil, err
 		}
@@ -63,7 +88,7 @@ func (abi ABI) Pack(name string, args ...interface{}) ([]byte, error) {
 	if !exist {
 		return nil, fmt.Errorf("method '%s' not found", name)
 	}
-	arguments, err := method.Inputs.Pack(args...)
+	arguments, err := method.Inputs.PackWithOptions(opts, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -71,20 +96,66 @@ func (abi ABI) Pack(name string, args ...interface{}) ([]byte, error) {
 	return append(method.Id(), arguments...), nil
 }
 
+// MustPack works like Pack, but panics if the call cannot be packed. It is meant
+// for tests and fixtures with known good values, not for production code.
+func (abi ABI) MustPack(name string, args ...interface{}) []byte {
+	packed, err := abi.Pack(name, args...)
+	if err != nil {
+		panic(err)
+	}
+	return packed
+}
+
+// PackHex works like Pack, but returns the calldata as a 0x prefixed hex string.
+func (abi ABI) PackHex(name string, args ...interface{}) (string, error) {
+	packed, err := abi.Pack(name, args...)
+	if err != nil {
+		return "", err
+	}
+	return hexutil.Encode(packed), nil
+}
+
+// PackTx packs the call of the named method like Pack, additionally checking that
+// the value to be sent along fits the method: only payable methods may receive
+// ether, so a non-zero value for any other method is rejected. The empty name
+// refers to the constructor.
+func (abi ABI) PackTx(name string, value *big.Int, args ...interface{}) ([]byte, error) {
+	method, exist := abi.Methods[name]
+	if name == "" {
+		method, exist = abi.Constructor, true
+	}
+	if !exist {
+		return nil, fmt.Errorf("method '%s' not found", name)
+	}
+	if value != nil && value.Sign() != 0 && !method.IsPayable() {
+		if name == "" {
+			return nil, fmt.Errorf("abi: cannot send %v wei to non-payable constructor", value)
+		}
+		return nil, fmt.Errorf("abi: cannot send %v wei to non-payable method %s", value, name)
+	}
+	return abi.Pack(name, args...)
+}
+
 // Unpack output in v according to the abi specification
 func (abi ABI) Unpack(v interface{}, name string, output []byte) (err error) {
-	if len(output) == 0 {
-		return fmt.Errorf("abi: unmarshalling empty output")
-	}
+	return abi.UnpackWithOptions(UnpackOptions{}, v, name, output)
+}
+
+// UnpackWithOptions performs the same operation as Unpack, but allows tweaking
+// how the decoded values are assigned to v.
+func (abi ABI) UnpackWithOptions(opts UnpackOptions, v interface{}, name string, output []byte) (err error) {
 	// since there can't be naming collisions with contracts and events,
 	// we need to decide whether we're calling a method or an event
 	if method, ok := abi.Methods[name]; ok {
+		if len(output) == 0 {
+			return fmt.Errorf("abi: unmarshalling empty output")
+		}
 		if len(output)%32 != 0 {
 			return fmt.Errorf("abi: improperly formatted output: %s - Bytes: [%+v]", string(output), output)
 		}
-		return method.Outputs.Unpack(v, output)
+		return method.Outputs.UnpackWithOptions(opts, v, output)
 	} else if event, ok := abi.Events[name]; ok {
-		return event.Inputs.Unpack(v, output)
+		return event.unpackData(opts, v, output)
 	}
 	return fmt.Errorf("abi: could not locate named method or event")
 }
@@ -98,6 +169,9 @@ func (abi *ABI) UnmarshalJSON(data []byte) error {
 		Anonymous bool
 		Inputs    []Argument
 		Outputs   []Argument
+
+		StateMutability string
+		Payable         bool
 	}
 
 	if err := json.Unmarshal(data, &fields); err != nil {
@@ -106,37 +180,208 @@ func (abi *ABI) UnmarshalJSON(data []byte) error {
 
 	abi.Methods = make(map[string]Method)
 	abi.Events = make(map[string]Event)
+	abi.Errors = make(map[string]Error)
+	abi.MethodsOrdered, abi.EventsOrdered = nil, nil
+	abi.Warnings = nil
+
+	declared := make(map[string]bool)
+	for _, field := range fields {
+		if field.Type == "function" || field.Type == "" {
+			declared[field.Name] = true
+		}
+	}
 	for _, field := range fields {
 		switch field.Type {
 		case "constructor":
+			abi.Warnings = append(abi.Warnings, clearIndexed("constructor", field.Inputs)...)
 			abi.Constructor = Method{
-				Inputs: field.Inputs,
+				Inputs:          field.Inputs,
+				StateMutability: stateMutability(field.StateMutability, field.Constant, field.Payable),
 			}
 		// empty defaults to function according to the abi spec
 		case "function", "":
-			abi.Methods[field.Name] = Method{
-				Name:    field.Name,
-				Const:   field.Constant,
-				Inputs:  field.Inputs,
-				Outputs: field.Outputs,
+			abi.Warnings = append(abi.Warnings, clearIndexed("method "+field.Name, field.Inputs)...)
+			abi.Warnings = append(abi.Warnings, clearIndexed("method "+field.Name, field.Outputs)...)
+			method := Method{
+				Name:            field.Name,
+				StateMutability: stateMutability(field.StateMutability, field.Constant, field.Payable),
+				Inputs:          field.Inputs,
+				Outputs:         field.Outputs,
+			}
+			method.Const = method.IsConstant()
+			// Overloaded methods share their name, so the first one keeps it
+			// and the following ones are stored as name0, name1, etc, skipping
+			// the names of other declared methods.
+			key := field.Name
+			for idx := 0; ; idx++ {
+				existing, ok := abi.Methods[key]
+				if ok && existing.Sig() == method.Sig() {
+					return fmt.Errorf("abi: duplicate method %v", method.Sig())
+				}
+				if !ok && (key == field.Name || !declared[key]) {
+					break
+				}
+				key = fmt.Sprintf("%s%d", field.Name, idx)
+			}
+			if key != field.Name {
+				abi.Warnings = append(abi.Warnings, fmt.Sprintf("method %v stored as %s", method.Sig(), key))
 			}
+			abi.Methods[key] = method
+			abi.MethodsOrdered = append(abi.MethodsOrdered, method)
 		case "event":
-			abi.Events[field.Name] = Event{
+			event := Event{
 				Name:      field.Name,
 				Anonymous: field.Anonymous,
 				Inputs:    field.Inputs,
 			}
+			abi.Events[field.Name] = event
+			abi.EventsOrdered = append(abi.EventsOrdered, event)
+		case "error":
+			abi.Warnings = append(abi.Warnings, clearIndexed("error "+field.Name, field.Inputs)...)
+			abi.Errors[field.Name] = Error{Name: field.Name, Inputs: field.Inputs}
 		}
 	}
 
 	return nil
 }
 
+// clearIndexed drops the indexed flags from the given function parameters, the
+// flag only having a meaning for event parameters, and returns a warning for
+// each one dropped.
+func clearIndexed(owner string, args []Argument) []string {
+	var warnings []string
+	for i := range args {
+		if args[i].Indexed {
+			args[i].Indexed = false
+			warnings = append(warnings, fmt.Sprintf("abi: %s: ignoring indexed flag on parameter %d", owner, i))
+		}
+	}
+	return warnings
+}
+
+// stateMutability returns the state mutability of a method, falling back to the
+// legacy constant and payable flags for ABIs that predate the stateMutability
+// field.
+func stateMutability(mutability string, constant, payable bool) StateMutability {
+	switch {
+	case mutability != "":
+		return StateMutability(mutability)
+	case constant:
+		return MutabilityView
+	case payable:
+		return MutabilityPayable
+	}
+	return MutabilityNonPayable
+}
+
+// Validate checks the ABI for internal consistency: method selectors must be
+// unique, all types must be well formed, tuples must have components and events
+// must not index more arguments than the EVM has log topics for.
+func (abi *ABI) Validate() error {
+	if err := validateArguments("constructor", abi.Constructor.Inputs, false); err != nil {
+		return err
+	}
+	selectors := make(map[[4]byte]string)
+	for name, method := range abi.Methods {
+		if err := validateArguments(fmt.Sprintf("method %s", name), method.Inputs, false); err != nil {
+			return err
+		}
+		if err := validateArguments(fmt.Sprintf("method %s", name), method.Outputs, false); err != nil {
+			return err
+		}
+		selector := method.Selector()
+		if other, ok := selectors[selector]; ok {
+			return fmt.Errorf("abi: methods %s and %s share selector %#x", other, method.Sig(), selector)
+		}
+		selectors[selector] = method.Sig()
+	}
+	for name, event := range abi.Events {
+		if err := validateArguments(fmt.Sprintf("event %s", name), event.Inputs, true); err != nil {
+			return err
+		}
+		// Non-anonymous events use the first topic for the event signature
+		limit := 3
+		if event.Anonymous {
+			limit = 4
+		}
+		if indexed := len(event.Inputs) - event.Inputs.LengthNonIndexed(); indexed > limit {
+			return fmt.Errorf("abi: event %s has %d indexed arguments, at most %d allowed", name, indexed, limit)
+		}
+	}
+	return nil
+}
+
+// validateArguments checks that all argument types are well formed and that only
+// events have indexed arguments.
+func validateArguments(owner string, args Arguments, indexable bool) error {
+	for i, arg := range args {
+		if arg.Indexed && !indexable {
+			return fmt.Errorf("abi: %s: argument %d is indexed", owner, i)
+		}
+		if err := validateType(arg.Type); err != nil {
+			return fmt.Errorf("abi: %s: argument %d: %v", owner, i, err)
+		}
+	}
+	return nil
+}
+
+// validateType checks that the type and all its nested types are well formed.
+func validateType(t Type) error {
+	switch t.T {
+	case SliceTy, ArrayTy:
+		if t.Elem == nil {
+			return fmt.Errorf("array type %q without element type", t)
+		}
+		return validateType(*t.Elem)
+	case TupleTy:
+		if len(t.TupleElems) == 0 {
+			return fmt.Errorf("tuple type %q without components", t)
+		}
+		if len(t.TupleElems) != len(t.TupleRawNames) {
+			return fmt.Errorf("tuple type %q has %d components but %d names", t, len(t.TupleElems), len(t.TupleRawNames))
+		}
+		for _, elem := range t.TupleElems {
+			if err := validateType(*elem); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		if _, err := NewType(t.String(), nil); err != nil {
+			return fmt.Errorf("invalid type %q: %v", t, err)
+		}
+		return nil
+	}
+}
+
+// MethodsBySelector returns all methods of the ABI keyed by their 4 byte
+// selector. Unlike Methods, overloaded methods can be told apart this way.
+func (abi *ABI) MethodsBySelector() map[[4]byte]Method {
+	methods := make(map[[4]byte]Method, len(abi.Methods))
+	for _, method := range abi.Methods {
+		methods[method.Selector()] = method
+	}
+	return methods
+}
+
+// Selectors returns the canonical signatures of all methods and custom errors
+// of the ABI keyed by their 4 byte selector.
+func (abi *ABI) Selectors() map[[4]byte]string {
+	selectors := make(map[[4]byte]string, len(abi.Methods)+len(abi.Errors))
+	for _, method := range abi.Methods {
+		selectors[method.Selector()] = method.Sig()
+	}
+	for _, e := range abi.Errors {
+		selectors[e.ID()] = e.Sig()
+	}
+	return selectors
+}
+
 // MethodById looks up a method by the 4-byte id
 // returns nil if none found
 func (abi *ABI) MethodById(sigdata []byte) (*Method, error) {
 	if len(sigdata) < 4 {
-		return nil, fmt.Errorf("data too short (% bytes) for abi method lookup", len(sigdata))
+		return nil, errorf(ErrOutOfBounds, "data too short (%d bytes) for abi method lookup", len(sigdata))
 	}
 	for _, method := range abi.Methods {
 		if bytes.Equal(method.Id(), sigdata[:4]) {
@@ -145,3 +390,185 @@ func (abi *ABI) MethodById(sigdata []byte) (*Method, error) {
 	}
 	return nil, fmt.Errorf("no method with id: %#x", sigdata[:4])
 }
+
+// SplitCallData splits calldata into the 4 byte method selector and the encoded
+// arguments following it. The arguments can be decoded with the Inputs of the
+// method returned by MethodById.
+func SplitCallData(data []byte) ([4]byte, []byte, error) {
+	var selector [4]byte
+	if len(data) < 4 {
+		return selector, nil, errorf(ErrOutOfBounds, "abi: calldata too short (%d bytes) for method selector", len(data))
+	}
+	copy(selector[:], data[:4])
+	return selector, data[4:], nil
+}
+
+// EventTopic returns the first log topic emitted by the named event, to be used
+// when filtering logs. Anonymous events don't emit their ID and are rejected.
+func (abi *ABI) EventTopic(name string) (common.Hash, error) {
+	event, ok := abi.Events[name]
+	if !ok {
+		return common.Hash{}, fmt.Errorf("abi: could not locate named event %q", name)
+	}
+	if event.Anonymous {
+		return common.Hash{}, fmt.Errorf("abi: anonymous event %q has no topic", name)
+	}
+	return event.ID(), nil
+}
+
+// ParseLogToMap identifies the event of a log by its signature topic and decodes
+// all its parameters into a map keyed by parameter name, returning the name of
+// the event. Indexed parameters of dynamic or composite types are only present
+// as the Keccak256 hash of their encoding, so they are returned as common.Hash.
+// Anonymous events carry no signature topic and cannot be identified.
+func (abi ABI) ParseLogToMap(log types.Log) (string, map[string]interface{}, error) {
+	if len(log.Topics) == 0 {
+		return "", nil, errNoEventSignature
+	}
+	for _, event := range abi.Events {
+		if event.Anonymous || event.ID() != log.Topics[0] {
+			continue
+		}
+		out := make(map[string]interface{})
+		values, err := event.Inputs.UnpackValues(log.Data)
+		if err != nil {
+			return "", nil, err
+		}
+		for i, arg := range event.Inputs.NonIndexed() {
+			out[arg.Name] = values[i]
+		}
+		if err := parseTopicsIntoMap(out, event.IndexedArgs(), log.Topics[1:]); err != nil {
+			return "", nil, err
+		}
+		return event.Name, out, nil
+	}
+	return "", nil, fmt.Errorf("abi: no event with signature %x", log.Topics[0])
+}
+
+// UnpackHex decodes the arguments of a call of the named method from the hex
+// encoded calldata, which may or may not be 0x prefixed. The calldata must be
+// addressed to the method, as told by its selector.
+func (abi *ABI) UnpackHex(name string, hexData string) ([]interface{}, error) {
+	method, ok := abi.Methods[name]
+	if !ok {
+		return nil, fmt.Errorf("abi: could not locate named method %q", name)
+	}
+	data, err := hex.DecodeString(strings.TrimPrefix(strings.TrimPrefix(hexData, "0x"), "0X"))
+	if err != nil {
+		return nil, fmt.Errorf("abi: invalid hex calldata: %v", err)
+	}
+	selector, args, err := SplitCallData(data)
+	if err != nil {
+		return nil, err
+	}
+	if selector != method.Selector() {
+		return nil, fmt.Errorf("abi: calldata selector %#x does not match method %s (%#x)", selector, method.Sig(), method.Selector())
+	}
+	return method.Inputs.UnpackValues(args)
+}
+
+// DecodedCall is the decoded form of the calldata of a method call.
+type DecodedCall struct {
+	Method string
+	Inputs []DecodedArg
+}
+
+// DecodedArg is a single decoded argument of a method call, along with its name
+// and canonical type.
+type DecodedArg struct {
+	Name  string
+	Type  string
+	Value interface{}
+}
+
+// DecodeCall looks up the method called by the given calldata and decodes its
+// arguments.
+func (abi *ABI) DecodeCall(data []byte) (*DecodedCall, error) {
+	method, err := abi.MethodById(data)
+	if err != nil {
+		return nil, err
+	}
+	values, err := method.Inputs.UnpackValues(data[4:])
+	if err != nil {
+		return nil, err
+	}
+	call := &DecodedCall{Method: method.Name, Inputs: make([]DecodedArg, len(values))}
+	for i, value := range values {
+		call.Inputs[i] = DecodedArg{
+			Name:  method.Inputs[i].Name,
+			Type:  method.Inputs[i].Type.String(),
+			Value: value,
+		}
+	}
+	return call, nil
+}
+
+// Format renders the call as name(arg: value, ...) for logging, byte values
+// being shown as hex. The values of the arguments with the given names are
+// replaced by a note on their length, keeping sensitive inputs out of logs.
+func (call *DecodedCall) Format(redact ...string) string {
+	hidden := make(map[string]bool, len(redact))
+	for _, name := range redact {
+		hidden[name] = true
+	}
+	args := make([]string, len(call.Inputs))
+	for i, arg := range call.Inputs {
+		name := arg.Name
+		if name == "" {
+			name = fmt.Sprintf("arg%d", i)
+		}
+		if hidden[name] {
+			args[i] = fmt.Sprintf("%s: <redacted %d bytes>", name, redactedSize(arg))
+		} else {
+			args[i] = fmt.Sprintf("%s: %s", name, formatValue(reflect.ValueOf(arg.Value)))
+		}
+	}
+	return fmt.Sprintf("%s(%s)", call.Method, strings.Join(args, ", "))
+}
+
+// redactedSize returns the length of a redacted argument: the number of bytes
+// of byte and string values and the size of the encoding of any other.
+func redactedSize(arg DecodedArg) int {
+	v := reflect.ValueOf(arg.Value)
+	switch {
+	case v.Kind() == reflect.String:
+		return v.Len()
+	case (v.Kind() == reflect.Slice || v.Kind() == reflect.Array) && v.Type().Elem().Kind() == reflect.Uint8:
+		return v.Len()
+	}
+	typ, err := NewType(arg.Type, nil)
+	if err != nil {
+		return 0
+	}
+	size, err := Arguments{{Type: typ}}.EncodedSize(arg.Value)
+	if err != nil {
+		return 0
+	}
+	return size
+}
+
+// formatValue renders a decoded value for Format.
+func formatValue(v reflect.Value) string {
+	switch {
+	case !v.IsValid():
+		return "<nil>"
+	case v.Type() == addressT:
+		return v.Interface().(common.Address).Hex()
+	case v.Type() == bigT:
+		return v.Interface().(*big.Int).String()
+	case (v.Kind() == reflect.Slice || v.Kind() == reflect.Array) && v.Type().Elem().Kind() == reflect.Uint8:
+		if v.Kind() == reflect.Array {
+			v = mustArrayToByteSlice(v)
+		}
+		return hexutil.Encode(v.Bytes())
+	case v.Kind() == reflect.Slice || v.Kind() == reflect.Array:
+		elems := make([]string, v.Len())
+		for i := range elems {
+			elems[i] = formatValue(v.Index(i))
+		}
+		return "[" + strings.Join(elems, ", ") + "]"
+	case v.Kind() == reflect.String:
+		return fmt.Sprintf("%q", v.String())
+	}
+	return fmt.Sprint(v.Interface())
+}
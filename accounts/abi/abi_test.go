@@ -19,6 +19,7 @@ package abi
 import (
 	"bytes"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"log"
 	"math/big"
@@ -27,6 +28,7 @@ import (
 	"testing"
 
 	"github.com/enode/common"
+	"github.com/enode/core/types"
 	"github.com/enode/crypto"
 )
 
@@ -62,12 +64,12 @@ func TestReader(t *testing.T) {
 	exp := ABI{
 		Methods: map[string]Method{
 			"balance": {
-				"balance", true, nil, nil,
+				"balance", true, nil, nil, MutabilityView,
 			},
 			"send": {
 				"send", false, []Argument{
 					{"amount", Uint256, false},
-				}, nil,
+				}, nil, MutabilityNonPayable,
 			},
 		},
 	}
@@ -180,7 +182,7 @@ func TestTestSlice(t *testing.T) {
 
 func TestMethodSignature(t *testing.T) {
 	String, _ := NewType("string", nil)
-	m := Method{"foo", false, []Argument{{"bar", String, false}, {"baz", String, false}}, nil}
+	m := Method{"foo", false, []Argument{{"bar", String, false}, {"baz", String, false}}, nil, ""}
 	exp := "foo(string,string)"
 	if m.Sig() != exp {
 		t.Error("signature mismatch", exp, "!=", m.Sig())
@@ -192,7 +194,7 @@ func TestMethodSignature(t *testing.T) {
 	}
 
 	uintt, _ := NewType("uint256", nil)
-	m = Method{"foo", false, []Argument{{"bar", uintt, false}}, nil}
+	m = Method{"foo", false, []Argument{{"bar", uintt, false}}, nil, ""}
 	exp = "foo(uint256)"
 	if m.Sig() != exp {
 		t.Error("signature mismatch", exp, "!=", m.Sig())
@@ -211,7 +213,7 @@ func TestMethodSignature(t *testing.T) {
 			{Name: "y", Type: "int256"},
 		}},
 	})
-	m = Method{"foo", false, []Argument{{"s", s, false}, {"bar", String, false}}, nil}
+	m = Method{"foo", false, []Argument{{"s", s, false}, {"bar", String, false}}, nil, ""}
 	exp = "foo((int256,int256[],(int256,int256)[],(int256,int256)[2]),string)"
 	if m.Sig() != exp {
 		t.Error("signature mismatch", exp, "!=", m.Sig())
@@ -735,8 +737,8 @@ func TestABI_MethodById(t *testing.T) {
 		}
 	}
 	// Also test empty
-	if _, err := abi.MethodById([]byte{0x00}); err == nil {
-		t.Errorf("Expected error, too short to decode data")
+	if _, err := abi.MethodById([]byte{0x00}); err == nil || err.Error() != "data too short (1 bytes) for abi method lookup" {
+		t.Errorf("Expected error, too short to decode data, got %v", err)
 	}
 	if _, err := abi.MethodById([]byte{}); err == nil {
 		t.Errorf("Expected error, too short to decode data")
@@ -745,3 +747,780 @@ func TestABI_MethodById(t *testing.T) {
 		t.Errorf("Expected error, nil is short to decode data")
 	}
 }
+
+func TestOverloadedMethods(t *testing.T) {
+	const abiJSON = `[
+		{"type":"function","name":"transfer","constant":false,"inputs":[{"name":"to","type":"address"},{"name":"value","type":"uint256"}]},
+		{"type":"function","name":"transfer","constant":false,"inputs":[{"name":"to","type":"address"},{"name":"value","type":"uint256"},{"name":"data","type":"bytes"}]},
+		{"type":"function","name":"transfer","constant":false,"inputs":[{"name":"value","type":"uint256"}]}
+	]`
+	abi, err := JSON(strings.NewReader(abiJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{
+		"transfer":  "transfer(address,uint256)",
+		"transfer0": "transfer(address,uint256,bytes)",
+		"transfer1": "transfer(uint256)",
+	}
+	if len(abi.Methods) != len(expected) {
+		t.Fatalf("method count mismatch: have %d, want %d", len(abi.Methods), len(expected))
+	}
+	for name, sig := range expected {
+		method, ok := abi.Methods[name]
+		if !ok {
+			t.Errorf("method %s missing", name)
+			continue
+		}
+		if method.Sig() != sig {
+			t.Errorf("method %s: signature mismatch: have %s, want %s", name, method.Sig(), sig)
+		}
+		if method.Name != "transfer" {
+			t.Errorf("method %s: name mismatch: have %s, want transfer", name, method.Name)
+		}
+		bySelector, ok := abi.MethodsBySelector()[method.Selector()]
+		if !ok || bySelector.Sig() != sig {
+			t.Errorf("method %s not found by selector %x", name, method.Selector())
+		}
+	}
+	// Every renamed overload is reported
+	warnings := []string{
+		"method transfer(address,uint256,bytes) stored as transfer0",
+		"method transfer(uint256) stored as transfer1",
+	}
+	if !reflect.DeepEqual(abi.Warnings, warnings) {
+		t.Errorf("warnings mismatch: have %q, want %q", abi.Warnings, warnings)
+	}
+	packed, err := abi.Pack("transfer0", common.Address{1}, big.NewInt(1), []byte{1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(packed[:4], abi.Methods["transfer0"].Id()) {
+		t.Errorf("packed selector mismatch: have %x, want %x", packed[:4], abi.Methods["transfer0"].Id())
+	}
+
+	const duplicateJSON = `[
+		{"type":"function","name":"transfer","inputs":[{"name":"to","type":"address"}]},
+		{"type":"function","name":"transfer","inputs":[{"name":"from","type":"address"}]}
+	]`
+	if _, err := JSON(strings.NewReader(duplicateJSON)); err == nil {
+		t.Error("expected error for duplicate method signature")
+	}
+
+	// Overloads must not take the name of a declared method
+	const collidingJSON = `[
+		{"type":"function","name":"f","inputs":[{"name":"a","type":"uint256"}]},
+		{"type":"function","name":"f","inputs":[{"name":"a","type":"address"}]},
+		{"type":"function","name":"f0","inputs":[]}
+	]`
+	colliding, err := JSON(strings.NewReader(collidingJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, sig := range map[string]string{"f": "f(uint256)", "f1": "f(address)", "f0": "f0()"} {
+		if method, ok := colliding.Methods[name]; !ok || method.Sig() != sig {
+			t.Errorf("method %s mismatch: have %v, want %s", name, method.Sig(), sig)
+		}
+	}
+	if want := []string{"method f(address) stored as f1"}; !reflect.DeepEqual(colliding.Warnings, want) {
+		t.Errorf("warnings mismatch: have %q, want %q", colliding.Warnings, want)
+	}
+	if len(colliding.Methods) != 3 {
+		t.Errorf("method count mismatch: have %d, want 3", len(colliding.Methods))
+	}
+	packed, err = colliding.Pack("f0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := crypto.Keccak256([]byte("f0()"))[:4]; !bytes.Equal(packed, want) {
+		t.Errorf("packed selector mismatch: have %x, want %x", packed, want)
+	}
+}
+
+func TestArgumentsEqual(t *testing.T) {
+	abi, err := JSON(strings.NewReader(`[
+		{"type":"function","name":"a","inputs":[{"name":"to","type":"address"},{"name":"value","type":"uint256"},{"name":"s","type":"tuple[]","components":[{"name":"x","type":"bytes"}]}]},
+		{"type":"function","name":"b","inputs":[{"name":"recipient","type":"address"},{"name":"","type":"uint256"},{"name":"t","type":"tuple[]","components":[{"name":"y","type":"bytes"}]}]},
+		{"type":"function","name":"c","inputs":[{"name":"to","type":"address"},{"name":"value","type":"uint128"},{"name":"s","type":"tuple[]","components":[{"name":"x","type":"bytes"}]}]},
+		{"type":"function","name":"d","inputs":[{"name":"to","type":"address"},{"name":"value","type":"uint256"}]},
+		{"type":"function","name":"e","inputs":[{"name":"value","type":"uint256"},{"name":"to","type":"address"},{"name":"s","type":"tuple[]","components":[{"name":"x","type":"bytes"}]}]},
+		{"type":"function","name":"f","inputs":[{"name":"to","type":"address"},{"name":"value","type":"uint256"},{"name":"s","type":"tuple[]","components":[{"name":"x","type":"bytes32"}]}]},
+		{"type":"event","name":"g","inputs":[{"name":"to","type":"address","indexed":true},{"name":"value","type":"uint256"},{"name":"s","type":"tuple[]","components":[{"name":"x","type":"bytes"}]}]}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := abi.Methods["a"].Inputs
+	for _, test := range []struct {
+		args  Arguments
+		equal bool
+	}{
+		{base, true},
+		{abi.Methods["b"].Inputs, true},  // different names only
+		{abi.Methods["c"].Inputs, false}, // different integer width
+		{abi.Methods["d"].Inputs, false}, // missing argument
+		{abi.Methods["e"].Inputs, false}, // different order
+		{abi.Methods["f"].Inputs, false}, // different tuple component
+		{abi.Events["g"].Inputs, false},  // indexed argument
+		{nil, false},
+	} {
+		if have := base.Equal(test.args); have != test.equal {
+			t.Errorf("%v: equality mismatch: have %v, want %v", test.args, have, test.equal)
+		}
+		if have := test.args.Equal(base); have != test.equal {
+			t.Errorf("%v: reverse equality mismatch: have %v, want %v", test.args, have, test.equal)
+		}
+	}
+	if !Arguments(nil).Equal(Arguments{}) {
+		t.Error("empty argument lists should be equal")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := `[
+		{"type":"constructor","inputs":[{"name":"owner","type":"address"}]},
+		{"type":"function","name":"transfer","inputs":[{"name":"to","type":"address"},{"name":"value","type":"uint256"}],"outputs":[{"name":"","type":"bool"}]},
+		{"type":"function","name":"transfer","inputs":[{"name":"s","type":"tuple[]","components":[{"name":"a","type":"bytes32[2]"}]}]},
+		{"type":"event","name":"Transfer","inputs":[{"name":"from","type":"address","indexed":true},{"name":"to","type":"address","indexed":true},{"name":"id","type":"uint256","indexed":true},{"name":"data","type":"bytes"}]},
+		{"type":"event","name":"Raw","anonymous":true,"inputs":[{"name":"a","type":"uint8","indexed":true},{"name":"b","type":"uint8","indexed":true},{"name":"c","type":"uint8","indexed":true},{"name":"d","type":"uint8","indexed":true}]}
+	]`
+	abi, err := JSON(strings.NewReader(valid))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := abi.Validate(); err != nil {
+		t.Fatalf("unexpected validation error: %v", err)
+	}
+	for i, test := range []struct {
+		def string
+		err string
+	}{
+		{
+			// burn(uint256) and collate_propagate_storage(bytes16) share selector 0x42966c68
+			`[{"type":"function","name":"burn","inputs":[{"name":"a","type":"uint256"}]},{"type":"function","name":"collate_propagate_storage","inputs":[{"name":"a","type":"bytes16"}]}]`,
+			"share selector 0x42966c68",
+		},
+		{
+			`[{"type":"function","name":"f","inputs":[{"name":"a","type":"tuple"}]}]`,
+			"method f: argument 0: tuple type \"()\" without components",
+		},
+		{
+			`[{"type":"function","name":"f","outputs":[{"name":"a","type":"tuple[2]","components":[{"name":"b","type":"tuple"}]}]}]`,
+			"method f: argument 0: tuple type \"()\" without components",
+		},
+		{
+			`[{"type":"event","name":"E","inputs":[{"name":"a","type":"uint8","indexed":true},{"name":"b","type":"uint8","indexed":true},{"name":"c","type":"uint8","indexed":true},{"name":"d","type":"uint8","indexed":true}]}]`,
+			"event E has 4 indexed arguments, at most 3 allowed",
+		},
+		{
+			`[{"type":"event","name":"E","anonymous":true,"inputs":[{"name":"a","type":"uint8","indexed":true},{"name":"b","type":"uint8","indexed":true},{"name":"c","type":"uint8","indexed":true},{"name":"d","type":"uint8","indexed":true},{"name":"e","type":"uint8","indexed":true}]}]`,
+			"event E has 5 indexed arguments, at most 4 allowed",
+		},
+	} {
+		abi, err := JSON(strings.NewReader(test.def))
+		if err != nil {
+			t.Fatalf("test %d: failed to parse abi: %v", i, err)
+		}
+		err = abi.Validate()
+		if err == nil {
+			t.Errorf("test %d: expected validation error", i)
+			continue
+		}
+		if !strings.Contains(err.Error(), test.err) {
+			t.Errorf("test %d: error mismatch: have %v, want %s", i, err, test.err)
+		}
+	}
+	// Hand assembled ABIs may contain types the parser would never produce
+	broken := ABI{Methods: map[string]Method{"f": {Name: "f", Inputs: Arguments{{Name: "a", Type: Type{T: BoolTy}, Indexed: true}}}}}
+	if err := broken.Validate(); err == nil || !strings.Contains(err.Error(), "method f: argument 0 is indexed") {
+		t.Errorf("error mismatch: have %v, want argument 0 is indexed", err)
+	}
+	broken = ABI{Methods: map[string]Method{"f": {Name: "f", Inputs: Arguments{{Name: "a", Type: Type{T: SliceTy}}}}}}
+	if err := broken.Validate(); err == nil || !strings.Contains(err.Error(), "without element type") {
+		t.Errorf("error mismatch: have %v, want without element type", err)
+	}
+	broken = ABI{Methods: map[string]Method{"f": {Name: "f", Inputs: Arguments{{Name: "a", Type: Type{T: UintTy}}}}}}
+	if err := broken.Validate(); err == nil || !strings.Contains(err.Error(), "invalid type") {
+		t.Errorf("error mismatch: have %v, want invalid type", err)
+	}
+}
+
+func TestPackCall(t *testing.T) {
+	token, err := JSON(strings.NewReader(`[
+		{"type":"function","name":"balanceOf","stateMutability":"view","inputs":[{"name":"owner","type":"address"}],"outputs":[{"name":"","type":"uint256"}]},
+		{"type":"function","name":"totalSupply","stateMutability":"view","outputs":[{"name":"","type":"uint256"}]}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	multicall, err := JSON(strings.NewReader(`[
+		{"type":"function","name":"aggregate","stateMutability":"payable","inputs":[{"name":"calls","type":"tuple[]","components":[{"name":"target","type":"address"},{"name":"callData","type":"bytes"}]}],"outputs":[{"name":"blockNumber","type":"uint256"},{"name":"returnData","type":"bytes[]"}]}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr, owner := common.Address{0x01}, common.Address{0x02}
+
+	balance, err := PackCall(addr, token, "balanceOf", owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	supply, err := PackCall(addr, token, "totalSupply")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if balance.Target != addr {
+		t.Errorf("target mismatch: have %x, want %x", balance.Target, addr)
+	}
+	want, _ := token.Pack("balanceOf", owner)
+	if !bytes.Equal(balance.CallData, want) {
+		t.Errorf("call data mismatch: have %x, want %x", balance.CallData, want)
+	}
+	if _, err := PackCall(addr, token, "missing"); err == nil {
+		t.Error("expected error for unknown method")
+	}
+	packed, err := multicall.Pack("aggregate", []Call3{balance, supply})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Decode the calls back through a struct mirroring the aggregate tuple.
+	var decoded []struct {
+		Target   common.Address
+		CallData []byte
+	}
+	if err := multicall.Methods["aggregate"].Inputs.Unpack(&decoded, packed[4:]); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded) != 2 || decoded[0].Target != addr || !bytes.Equal(decoded[0].CallData, balance.CallData) || !bytes.Equal(decoded[1].CallData, supply.CallData) {
+		t.Errorf("decoded calls mismatch: have %+v", decoded)
+	}
+}
+
+func TestSplitCallData(t *testing.T) {
+	abi, err := JSON(strings.NewReader(jsondata2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := abi.Pack("send", big.NewInt(42))
+	if err != nil {
+		t.Fatal(err)
+	}
+	selector, args, err := SplitCallData(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	method := abi.Methods["send"]
+	if selector != method.Selector() {
+		t.Errorf("selector mismatch: have %x, want %x", selector, method.Selector())
+	}
+	var amount *big.Int
+	if err := method.Inputs.Unpack(&amount, args); err != nil {
+		t.Fatal(err)
+	}
+	if amount.Cmp(big.NewInt(42)) != 0 {
+		t.Errorf("argument mismatch: have %v, want 42", amount)
+	}
+	if _, args, err := SplitCallData(data[:4]); err != nil || len(args) != 0 {
+		t.Errorf("selector only calldata: have %x (%v), want no arguments", args, err)
+	}
+	for _, short := range [][]byte{nil, {0x01}, {0x01, 0x02, 0x03}} {
+		if _, _, err := SplitCallData(short); err == nil {
+			t.Errorf("expected error for %d bytes of calldata", len(short))
+		}
+	}
+}
+
+func TestDeclarationOrder(t *testing.T) {
+	abi, err := JSON(strings.NewReader(`[
+		{"type":"function","name":"zeta"},
+		{"type":"event","name":"Second"},
+		{"type":"function","name":"alpha","inputs":[{"name":"a","type":"uint256"}]},
+		{"type":"constructor","inputs":[]},
+		{"type":"function","name":"mid"},
+		{"type":"event","name":"First"},
+		{"type":"function","name":"alpha","inputs":[{"name":"a","type":"bool"}]}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var methods []string
+	for _, method := range abi.MethodsOrdered {
+		methods = append(methods, method.Sig())
+	}
+	if want := []string{"zeta()", "alpha(uint256)", "mid()", "alpha(bool)"}; !reflect.DeepEqual(methods, want) {
+		t.Errorf("method order mismatch: have %v, want %v", methods, want)
+	}
+	var events []string
+	for _, event := range abi.EventsOrdered {
+		events = append(events, event.Name)
+	}
+	if want := []string{"Second", "First"}; !reflect.DeepEqual(events, want) {
+		t.Errorf("event order mismatch: have %v, want %v", events, want)
+	}
+	if len(abi.Methods) != 4 || abi.Methods["alpha0"].Sig() != "alpha(bool)" {
+		t.Errorf("method lookup mismatch: have %v", abi.Methods)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	old, err := JSON(strings.NewReader(`[
+		{"type":"function","name":"balanceOf","inputs":[{"name":"owner","type":"address"}],"outputs":[{"name":"","type":"uint128"}]},
+		{"type":"function","name":"transfer","inputs":[{"name":"to","type":"address"},{"name":"value","type":"uint256"}],"outputs":[{"name":"","type":"bool"}]},
+		{"type":"function","name":"burn","inputs":[{"name":"value","type":"uint256"}]}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	new, err := JSON(strings.NewReader(`[
+		{"type":"function","name":"balanceOf","inputs":[{"name":"account","type":"address"}],"outputs":[{"name":"","type":"uint256"}]},
+		{"type":"function","name":"transfer","inputs":[{"name":"recipient","type":"address"},{"name":"amount","type":"uint256"}],"outputs":[{"name":"success","type":"bool"}]},
+		{"type":"function","name":"burn","inputs":[{"name":"value","type":"uint128"}]},
+		{"type":"function","name":"mint","inputs":[{"name":"to","type":"address"},{"name":"value","type":"uint256"}]}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	diff := Diff(old, new)
+
+	sigs := func(methods []Method) (sigs []string) {
+		for _, method := range methods {
+			sigs = append(sigs, method.Sig())
+		}
+		return sigs
+	}
+	if have, want := sigs(diff.Added), []string{"burn(uint128)", "mint(address,uint256)"}; !reflect.DeepEqual(have, want) {
+		t.Errorf("added mismatch: have %v, want %v", have, want)
+	}
+	if have, want := sigs(diff.Removed), []string{"burn(uint256)"}; !reflect.DeepEqual(have, want) {
+		t.Errorf("removed mismatch: have %v, want %v", have, want)
+	}
+	// Renamed arguments are not a change, a different return type is
+	if len(diff.Changed) != 1 || diff.Changed[0].Old.Sig() != "balanceOf(address)" {
+		t.Fatalf("changed mismatch: have %v", diff.Changed)
+	}
+	if have := diff.Changed[0].New.Outputs[0].Type.String(); have != "uint256" {
+		t.Errorf("changed output mismatch: have %s, want uint256", have)
+	}
+	if diff.Empty() || !Diff(old, old).Empty() {
+		t.Error("emptiness mismatch")
+	}
+}
+
+func TestSelectors(t *testing.T) {
+	abi, err := JSON(strings.NewReader(`[
+		{"type":"constructor","inputs":[{"name":"owner","type":"address"}]},
+		{"type":"function","name":"transfer","inputs":[{"name":"to","type":"address"},{"name":"value","type":"uint256"}]},
+		{"type":"function","name":"balanceOf","inputs":[{"name":"owner","type":"address"}]},
+		{"type":"function","name":"balanceOf","inputs":[{"name":"owner","type":"address"},{"name":"id","type":"uint256"}]},
+		{"type":"event","name":"Transfer","inputs":[{"name":"from","type":"address","indexed":true}]},
+		{"type":"error","name":"InsufficientBalance","inputs":[{"name":"available","type":"uint256"},{"name":"required","type":"uint256"}]}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[[4]byte]string{
+		{0xa9, 0x05, 0x9c, 0xbb}: "transfer(address,uint256)",
+		{0x70, 0xa0, 0x82, 0x31}: "balanceOf(address)",
+		{0x00, 0xfd, 0xd5, 0x8e}: "balanceOf(address,uint256)",
+		{0xcf, 0x47, 0x91, 0x81}: "InsufficientBalance(uint256,uint256)",
+	}
+	if have := abi.Selectors(); !reflect.DeepEqual(have, want) {
+		t.Errorf("selectors mismatch: have %x, want %x", have, want)
+	}
+	// ABIs declaring only errors have selectors as well
+	errorsOnly, err := JSON(strings.NewReader(`[{"type":"error","name":"Unauthorized","inputs":[]}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have := errorsOnly.Selectors(); len(have) != 1 || have[errorsOnly.Errors["Unauthorized"].ID()] != "Unauthorized()" {
+		t.Errorf("error selectors mismatch: have %v", have)
+	}
+}
+
+func TestErrorKinds(t *testing.T) {
+	abi, err := JSON(strings.NewReader(`[
+		{"type":"function","name":"f","inputs":[{"name":"a","type":"uint8"},{"name":"b","type":"string"}],"outputs":[{"name":"a","type":"uint8"},{"name":"b","type":"string"}]}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	output, err := abi.Methods["f"].Outputs.Pack(uint8(1), "hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out struct {
+		A uint8
+		B string
+	}
+	var table = []struct {
+		name string
+		err  error
+		kind error
+	}{
+		{"overflow", func() error { _, err := abi.Pack("f", "256", "s"); return err }(), ErrOverflow},
+		{"mismatch", func() error { _, err := abi.Pack("f", true, "s"); return err }(), ErrTypeMismatch},
+		{"arity", func() error { _, err := abi.Pack("f", uint8(1)); return err }(), ErrArity},
+		{"bounds", abi.Unpack(&out, "f", output[:64]), ErrOutOfBounds},
+		{"unpack mismatch", abi.Unpack(&struct{ A, B bool }{}, "f", output), ErrTypeMismatch},
+	}
+	kinds := []error{ErrOverflow, ErrTypeMismatch, ErrArity, ErrOutOfBounds}
+	for _, test := range table {
+		if test.err == nil {
+			t.Errorf("%s: expected error", test.name)
+			continue
+		}
+		for _, kind := range kinds {
+			if errors.Is(test.err, kind) != (kind == test.kind) {
+				t.Errorf("%s: errors.Is(%v, %v) = %v", test.name, test.err, kind, !(kind == test.kind))
+			}
+		}
+	}
+}
+
+func TestIndexedFunctionParams(t *testing.T) {
+	abi, err := JSON(strings.NewReader(`[
+		{"type":"function","name":"get","inputs":[{"name":"id","type":"uint256","indexed":true}],"outputs":[{"name":"owner","type":"address","indexed":true}]},
+		{"type":"event","name":"Set","inputs":[{"name":"id","type":"uint256","indexed":true}]}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	method := abi.Methods["get"]
+	if method.Inputs[0].Indexed || method.Outputs[0].Indexed {
+		t.Error("indexed flag kept on function parameters")
+	}
+	if !abi.Events["Set"].Inputs[0].Indexed {
+		t.Error("indexed flag dropped from event parameter")
+	}
+	if len(abi.Warnings) != 2 {
+		t.Errorf("warning count mismatch: have %d, want 2: %v", len(abi.Warnings), abi.Warnings)
+	}
+	if err := abi.Validate(); err != nil {
+		t.Errorf("validation failed: %v", err)
+	}
+	// The output must still decode, it would be skipped as an indexed value
+	output := common.LeftPadBytes(common.Address{1}.Bytes(), 32)
+	var owner common.Address
+	if err := abi.Unpack(&owner, "get", output); err != nil {
+		t.Fatal(err)
+	}
+	if owner != (common.Address{1}) {
+		t.Errorf("owner mismatch: have %x", owner)
+	}
+}
+
+func TestDecodeCall(t *testing.T) {
+	abi, err := JSON(strings.NewReader(`[
+		{"type":"function","name":"transfer","inputs":[{"name":"to","type":"address"},{"name":"value","type":"uint256"}],"outputs":[{"name":"","type":"bool"}]}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := common.FromHex("a9059cbb" +
+		"000000000000000000000000ab5801a7d398351b8be11c439e05c5b3259aec9b" +
+		"0000000000000000000000000000000000000000000000000de0b6b3a7640000")
+
+	call, err := abi.DecodeCall(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &DecodedCall{
+		Method: "transfer",
+		Inputs: []DecodedArg{
+			{Name: "to", Type: "address", Value: common.HexToAddress("0xab5801a7d398351b8be11c439e05c5b3259aec9b")},
+			{Name: "value", Type: "uint256", Value: big.NewInt(1000000000000000000)},
+		},
+	}
+	if !reflect.DeepEqual(call, want) {
+		t.Errorf("decoded call mismatch: have %+v, want %+v", call, want)
+	}
+	if _, err := abi.DecodeCall(common.FromHex("deadbeef")); err == nil {
+		t.Error("expected error for unknown selector")
+	}
+	if _, err := abi.DecodeCall(data[:40]); err == nil {
+		t.Error("expected error for truncated arguments")
+	}
+}
+
+func TestPackTx(t *testing.T) {
+	abi, err := JSON(strings.NewReader(`[
+		{"type":"constructor","inputs":[{"name":"owner","type":"address"}],"stateMutability":"nonpayable"},
+		{"type":"function","name":"deposit","inputs":[{"name":"memo","type":"string"}],"stateMutability":"payable"},
+		{"type":"function","name":"withdraw","inputs":[{"name":"amount","type":"uint256"}],"stateMutability":"nonpayable"}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := abi.Pack("deposit", "hi")
+	data, err := abi.PackTx("deposit", big.NewInt(1), "hi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, want) {
+		t.Errorf("calldata mismatch: have %x, want %x", data, want)
+	}
+	if _, err := abi.PackTx("deposit", nil, "hi"); err != nil {
+		t.Errorf("payable method without value rejected: %v", err)
+	}
+	if _, err := abi.PackTx("withdraw", nil, big.NewInt(1)); err != nil {
+		t.Errorf("non-payable method without value rejected: %v", err)
+	}
+	if _, err := abi.PackTx("withdraw", new(big.Int), big.NewInt(1)); err != nil {
+		t.Errorf("non-payable method with zero value rejected: %v", err)
+	}
+	if _, err := abi.PackTx("withdraw", big.NewInt(1), big.NewInt(1)); err == nil {
+		t.Error("expected error for value sent to non-payable method")
+	}
+	if _, err := abi.PackTx("", big.NewInt(1), common.Address{}); err == nil {
+		t.Error("expected error for value sent to non-payable constructor")
+	}
+	if _, err := abi.PackTx("missing", nil); err == nil {
+		t.Error("expected error for unknown method")
+	}
+}
+
+func TestInvalidComponentType(t *testing.T) {
+	for i, test := range []struct {
+		def string
+		err string
+	}{
+		{
+			`[{"type":"function","name":"f","inputs":[{"name":"a","type":"tuple","components":[{"name":"b","type":"uint2566"}]}]}]`,
+			`tuple component "b": unsupported arg type: uint2566`,
+		},
+		{
+			`[{"type":"function","name":"f","outputs":[{"name":"a","type":"tuple[]","components":[{"name":"b","type":"tuple","components":[{"name":"c","type":"bytes33"}]}]}]}]`,
+			`tuple component "c": unsupported arg type: bytes33`,
+		},
+		{
+			`[{"type":"event","name":"E","inputs":[{"name":"a","type":"int0"}]}]`,
+			`unsupported arg type: int0`,
+		},
+	} {
+		_, err := JSON(strings.NewReader(test.def))
+		if err == nil {
+			t.Errorf("test %d: expected error", i)
+			continue
+		}
+		if !strings.Contains(err.Error(), test.err) {
+			t.Errorf("test %d: error mismatch: have %v, want %s", i, err, test.err)
+		}
+	}
+}
+
+func TestPackHex(t *testing.T) {
+	abi, err := JSON(strings.NewReader(`[
+		{"type":"constructor","inputs":[{"name":"owner","type":"address"}]},
+		{"type":"function","name":"transfer","inputs":[{"name":"to","type":"address"},{"name":"value","type":"uint256"}]}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	to, value := common.Address{1}, big.NewInt(100)
+
+	packed, _ := abi.Pack("transfer", to, value)
+	encoded, err := abi.PackHex("transfer", to, value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "0x" + hex.EncodeToString(packed); encoded != want {
+		t.Errorf("calldata mismatch: have %s, want %s", encoded, want)
+	}
+	packed, _ = abi.Methods["transfer"].Inputs.Pack(to, value)
+	if encoded, err = abi.Methods["transfer"].Inputs.PackHex(to, value); err != nil {
+		t.Fatal(err)
+	}
+	if want := "0x" + hex.EncodeToString(packed); encoded != want {
+		t.Errorf("arguments mismatch: have %s, want %s", encoded, want)
+	}
+	if _, err := abi.PackHex("transfer", to); err == nil {
+		t.Error("expected error for missing argument")
+	}
+}
+
+func TestUnpackHex(t *testing.T) {
+	abi, err := JSON(strings.NewReader(`[
+		{"type":"function","name":"transfer","inputs":[{"name":"to","type":"address"},{"name":"value","type":"uint256"}]},
+		{"type":"function","name":"approve","inputs":[{"name":"spender","type":"address"},{"name":"value","type":"uint256"}]}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	to, value := common.Address{1}, big.NewInt(100)
+	encoded, err := abi.PackHex("transfer", to, value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, data := range []string{encoded, strings.TrimPrefix(encoded, "0x")} {
+		values, err := abi.UnpackHex("transfer", data)
+		if err != nil {
+			t.Fatalf("%s: %v", data, err)
+		}
+		if !reflect.DeepEqual(values, []interface{}{to, value}) {
+			t.Errorf("%s: values mismatch: have %v", data, values)
+		}
+	}
+	if _, err := abi.UnpackHex("approve", encoded); err == nil || !strings.Contains(err.Error(), "does not match method approve(address,uint256)") {
+		t.Errorf("expected selector mismatch error, got %v", err)
+	}
+	if _, err := abi.UnpackHex("transfer", "0xa9059c"); err == nil {
+		t.Error("expected error for short calldata")
+	}
+	if _, err := abi.UnpackHex("transfer", "0xzz"); err == nil {
+		t.Error("expected error for invalid hex")
+	}
+}
+
+func TestDecodedCallFormat(t *testing.T) {
+	abi, err := JSON(strings.NewReader(`[
+		{"type":"function","name":"unlock","inputs":[{"name":"owner","type":"address"},{"name":"secret","type":"bytes"},{"name":"nonce","type":"uint256"},{"name":"tag","type":"string"}]}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	owner := common.HexToAddress("0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed")
+	data, err := abi.Pack("unlock", owner, []byte("hunter2"), big.NewInt(7), "x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	call, err := abi.DecodeCall(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `unlock(owner: 0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed, secret: <redacted 7 bytes>, nonce: 7, tag: "x")`
+	if have := call.Format("secret"); have != want {
+		t.Errorf("redacted format mismatch:\nhave %s\nwant %s", have, want)
+	}
+	want = `unlock(owner: 0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed, secret: 0x68756e74657232, nonce: <redacted 32 bytes>, tag: "x")`
+	if have := call.Format("nonce"); have != want {
+		t.Errorf("format mismatch:\nhave %s\nwant %s", have, want)
+	}
+}
+
+func TestErrorSigAndID(t *testing.T) {
+	abi, err := JSON(strings.NewReader(`[
+		{"type":"error","name":"InsufficientBalance","inputs":[{"name":"available","type":"uint256"},{"name":"required","type":"uint256"}]},
+		{"type":"error","name":"BadOrders","inputs":[{"name":"orders","type":"tuple[]","components":[{"name":"maker","type":"address"},{"name":"ids","type":"uint8[2]"}]}]},
+		{"type":"error","name":"Unauthorized","inputs":[]}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	insufficient := abi.Errors["InsufficientBalance"]
+	if sig := insufficient.Sig(); sig != "InsufficientBalance(uint256,uint256)" {
+		t.Errorf("signature mismatch: have %s", sig)
+	}
+	if id := insufficient.ID(); id != [4]byte{0xcf, 0x47, 0x91, 0x81} {
+		t.Errorf("id mismatch: have %x, want cf479181", id)
+	}
+	if s := insufficient.String(); s != "error InsufficientBalance(uint256 available, uint256 required)" {
+		t.Errorf("string mismatch: have %s", s)
+	}
+	if sig := abi.Errors["BadOrders"].Sig(); sig != "BadOrders((address,uint8[2])[])" {
+		t.Errorf("tuple signature mismatch: have %s", sig)
+	}
+	if id := abi.Errors["Unauthorized"].ID(); !bytes.Equal(id[:], crypto.Keccak256([]byte("Unauthorized()"))[:4]) {
+		t.Errorf("id mismatch: have %x", id)
+	}
+}
+
+func TestMustPack(t *testing.T) {
+	abi, err := JSON(strings.NewReader(`[
+		{"type":"function","name":"set","inputs":[{"name":"value","type":"uint256"}]}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := abi.Pack("set", big.NewInt(1))
+	if packed := abi.MustPack("set", big.NewInt(1)); !bytes.Equal(packed, want) {
+		t.Errorf("calldata mismatch: have %x, want %x", packed, want)
+	}
+	if packed := abi.Methods["set"].Inputs.MustPack(big.NewInt(1)); !bytes.Equal(packed, want[4:]) {
+		t.Errorf("arguments mismatch: have %x, want %x", packed, want[4:])
+	}
+	mustPanic := func(name string, fn func()) {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("%s: expected panic", name)
+			}
+		}()
+		fn()
+	}
+	mustPanic("abi", func() { abi.MustPack("set", "not a number") })
+	mustPanic("arguments", func() { abi.Methods["set"].Inputs.MustPack() })
+}
+
+func TestParseLogToMap(t *testing.T) {
+	parsed, err := JSON(strings.NewReader(`[
+		{"type":"event","name":"Transfer","inputs":[{"name":"from","type":"address","indexed":true},{"name":"to","type":"address","indexed":true},{"name":"value","type":"uint256"}]},
+		{"type":"event","name":"Mixed","inputs":[{"name":"delta","type":"int8","indexed":true},{"name":"amount","type":"uint256"},{"name":"tag","type":"string","indexed":true},{"name":"note","type":"string"},{"name":"flag","type":"bool","indexed":true}]}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Plain Transfer with indexed addresses
+	transfer := types.Log{
+		Topics: []common.Hash{
+			parsed.Events["Transfer"].ID(),
+			common.BytesToHash(common.Address{1}.Bytes()),
+			common.BytesToHash(common.Address{2}.Bytes()),
+		},
+		Data: common.LeftPadBytes(big.NewInt(100).Bytes(), 32),
+	}
+	name, values, err := parsed.ParseLogToMap(transfer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "Transfer" || len(values) != 3 {
+		t.Fatalf("transfer mismatch: have %s with %v", name, values)
+	}
+	if values["from"] != (common.Address{1}) || values["to"] != (common.Address{2}) || values["value"].(*big.Int).Int64() != 100 {
+		t.Errorf("transfer values mismatch: have %v", values)
+	}
+	// Mixed event with signed, hashed and boolean topics
+	data, err := parsed.Events["Mixed"].Inputs.NonIndexed().Pack(big.NewInt(7), "hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	delta, err := Arguments{parsed.Events["Mixed"].Inputs[0]}.Pack(int8(-5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mixed := types.Log{
+		Topics: []common.Hash{
+			parsed.Events["Mixed"].ID(),
+			common.BytesToHash(delta),
+			crypto.Keccak256Hash([]byte("memo")),
+			common.BigToHash(common.Big1),
+		},
+		Data: data,
+	}
+	name, values, err = parsed.ParseLogToMap(mixed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "Mixed" || len(values) != 5 {
+		t.Fatalf("mixed mismatch: have %s with %v", name, values)
+	}
+	if values["delta"] != int8(-5) || values["tag"] != crypto.Keccak256Hash([]byte("memo")) || values["flag"] != true {
+		t.Errorf("mixed topics mismatch: have %v", values)
+	}
+	if values["amount"].(*big.Int).Int64() != 7 || values["note"] != "hello" {
+		t.Errorf("mixed data mismatch: have %v", values)
+	}
+	// Unknown and malformed logs are rejected
+	if _, _, err := parsed.ParseLogToMap(types.Log{Topics: []common.Hash{{1}}}); err == nil {
+		t.Error("expected error for unknown event")
+	}
+	if _, _, err := parsed.ParseLogToMap(types.Log{Data: data}); err == nil {
+		t.Error("expected error for log without topics")
+	}
+	if _, _, err := parsed.ParseLogToMap(types.Log{Topics: transfer.Topics[:2], Data: transfer.Data}); err == nil {
+		t.Error("expected error for missing topic")
+	}
+	// ABIs built by hand are resolved too
+	handBuilt := ABI{Events: map[string]Event{"Transfer": parsed.Events["Transfer"]}}
+	if name, _, err := handBuilt.ParseLogToMap(transfer); err != nil || name != "Transfer" {
+		t.Errorf("hand-built ABI mismatch: have %s, %v", name, err)
+	}
+}
@@ -17,10 +17,17 @@
 package abi
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"math/big"
 	"reflect"
 	"strings"
+	"unicode"
+
+	"github.com/enode/common"
+	"github.com/enode/common/hexutil"
 )
 
 // Argument holds the name of the argument and the corresponding type.
@@ -81,6 +88,21 @@ func (arguments Arguments) NonIndexed() Arguments {
 	return ret
 }
 
+// Equal reports whether both argument lists have the same shape: the same number
+// of arguments with identical canonical types and indexed flags, in the same
+// order. Argument names are ignored as they don't affect the encoding.
+func (arguments Arguments) Equal(other Arguments) bool {
+	if len(arguments) != len(other) {
+		return false
+	}
+	for i, arg := range arguments {
+		if arg.Type.String() != other[i].Type.String() || arg.Indexed != other[i].Indexed {
+			return false
+		}
+	}
+	return true
+}
+
 // isTuple returns true for non-atomic constructs, like (uint,uint) or uint[]
 func (arguments Arguments) isTuple() bool {
 	return len(arguments) > 1
@@ -88,36 +110,75 @@ func (arguments Arguments) isTuple() bool {
 
 // Unpack performs the operation hexdata -> Go format
 func (arguments Arguments) Unpack(v interface{}, data []byte) error {
+	return arguments.UnpackWithOptions(UnpackOptions{}, v, data)
+}
+
+// UnpackWithOptions performs the same operation as Unpack, but allows tweaking
+// how the decoded values are assigned to v.
+func (arguments Arguments) UnpackWithOptions(opts UnpackOptions, v interface{}, data []byte) error {
 	// make sure the passed value is arguments pointer
 	if reflect.Ptr != reflect.ValueOf(v).Kind() {
 		return fmt.Errorf("abi: Unpack(non-pointer %T)", v)
 	}
-	marshalledValues, err := arguments.UnpackValues(data)
+	marshalledValues, err := arguments.unpackValues(context.Background(), opts, data)
 	if err != nil {
 		return err
 	}
+	if len(marshalledValues) == 0 {
+		return nil
+	}
 	if arguments.isTuple() {
-		return arguments.unpackTuple(v, marshalledValues)
+		return arguments.unpackTuple(opts, v, marshalledValues)
 	}
-	return arguments.unpackAtomic(v, marshalledValues[0])
+	if opts.KeyField != "" && arguments.LengthNonIndexed() == 1 && reflect.ValueOf(v).Elem().Kind() == reflect.Map {
+		argument := arguments.NonIndexed()[0]
+		return unpackKeyed(opts, &argument.Type, reflect.ValueOf(v).Elem(), reflect.ValueOf(marshalledValues[0]), opts.KeyField)
+	}
+	return arguments.unpackAtomic(opts, v, marshalledValues[0])
 }
 
 // unpack sets the unmarshalled value to go format.
 // Note the dst here must be settable.
-func unpack(t *Type, dst interface{}, src interface{}) error {
+func unpack(opts UnpackOptions, t *Type, dst interface{}, src interface{}) error {
 	var (
 		dstVal = reflect.ValueOf(dst).Elem()
 		srcVal = reflect.ValueOf(src)
 	)
 
-	if t.T != TupleTy && !((t.T == SliceTy || t.T == ArrayTy) && t.Elem.T == TupleTy) {
+	if t.T == FixedBytesTy {
+		return setFixedBytes(dstVal, srcVal)
+	}
+	if t.T == BytesTy {
+		if w := readerFrom(dstVal); w != nil {
+			_, err := w.ReadFrom(bytes.NewReader(srcVal.Bytes()))
+			return err
+		}
+	}
+	if opts.NumberFactory != nil && (t.T == IntTy || t.T == UintTy) && requiresFactory(dstVal, srcVal) {
+		return setFromFactory(opts.NumberFactory, t, dstVal, srcVal)
+	}
+	if t.T == IntTy {
+		return setSignedInteger(dstVal, srcVal)
+	}
+	if !requiresConversion(t) {
 		return set(dstVal, srcVal)
 	}
 
 	switch t.T {
+	case FixedPointTy:
+		return setFixedPoint(t, dstVal, srcVal)
 	case TupleTy:
+		// pointer destinations get a freshly allocated struct at every level
+		if dstVal.Kind() == reflect.Ptr {
+			ptr := reflect.New(dstVal.Type().Elem())
+			if err := unpack(opts, t, ptr.Interface(), src); err != nil {
+				return err
+			}
+			dstVal.Set(ptr)
+			return nil
+		}
 		if dstVal.Kind() != reflect.Struct {
-			return fmt.Errorf("abi: invalid dst value for unpack, want struct, got %s", dstVal.Kind())
+			return errorf(ErrTypeMismatch, "abi: invalid dst value for unpack, want struct, got %s", dstVal.Kind())
 		}
 		fieldmap, err := mapArgNamesToStructFields(t.TupleRawNames, dstVal)
 		if err != nil {
@@ -125,33 +186,35 @@ func unpack(t *Type, dst interface{}, src interface{}) error {
 		}
 		for i, elem := range t.TupleElems {
 			fname := fieldmap[t.TupleRawNames[i]]
-			field := dstVal.FieldByName(fname)
-			if !field.IsValid() {
+			if !dstVal.FieldByName(fname).IsValid() {
 				return fmt.Errorf("abi: field %s can't found in the given value", t.TupleRawNames[i])
 			}
-			if err := unpack(elem, field.Addr().Interface(), srcVal.Field(i).Interface()); err != nil {
+			if err := unpackField(opts, elem, dstVal, fname, srcVal.Field(i).Interface()); err != nil {
 				return err
 			}
 		}
 		return nil
 	case SliceTy:
 		if dstVal.Kind() != reflect.Slice {
-			return fmt.Errorf("abi: invalid dst value for unpack, want slice, got %s", dstVal.Kind())
+			return errorf(ErrTypeMismatch, "abi: invalid dst value for unpack, want slice, got %s", dstVal.Kind())
 		}
 		slice := reflect.MakeSlice(dstVal.Type(), srcVal.Len(), srcVal.Len())
 		for i := 0; i < slice.Len(); i++ {
-			if err := unpack(t.Elem, slice.Index(i).Addr().Interface(), srcVal.Index(i).Interface()); err != nil {
+			if err := unpack(opts, t.Elem, slice.Index(i).Addr().Interface(), srcVal.Index(i).Interface()); err != nil {
 				return err
 			}
 		}
 		dstVal.Set(slice)
 	case ArrayTy:
 		if dstVal.Kind() != reflect.Array {
-			return fmt.Errorf("abi: invalid dst value for unpack, want array, got %s", dstVal.Kind())
+			return errorf(ErrTypeMismatch, "abi: invalid dst value for unpack, want array, got %s", dstVal.Kind())
+		}
+		if dstVal.Len() != srcVal.Len() {
+			return errorf(ErrTypeMismatch, "abi: cannot unmarshal %v in to array of length %d", t, dstVal.Len())
 		}
 		array := reflect.New(dstVal.Type()).Elem()
 		for i := 0; i < array.Len(); i++ {
-			if err := unpack(t.Elem, array.Index(i).Addr().Interface(), srcVal.Index(i).Interface()); err != nil {
+			if err := unpack(opts, t.Elem, array.Index(i).Addr().Interface(), srcVal.Index(i).Interface()); err != nil {
 				return err
 			}
 		}
@@ -160,8 +223,74 @@ func unpack(t *Type, dst interface{}, src interface{}) error {
 	return nil
 }
 
+// unpackKeyed sets the elements of a decoded tuple array as the values of the
+// map dst, keyed by the tuple component named key.
+func unpackKeyed(opts UnpackOptions, t *Type, dst reflect.Value, src reflect.Value, key string) error {
+	if (t.T != SliceTy && t.T != ArrayTy) || t.Elem.T != TupleTy {
+		return fmt.Errorf("abi: cannot unpack %v into map, want tuple array", t)
+	}
+	index := -1
+	for i, name := range t.Elem.TupleRawNames {
+		if name == key {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return fmt.Errorf("abi: key field %s can't be found in %v", key, t)
+	}
+	m := reflect.MakeMapWithSize(dst.Type(), src.Len())
+	for i := 0; i < src.Len(); i++ {
+		elem := src.Index(i)
+		k := reflect.New(dst.Type().Key()).Elem()
+		if err := set(k, elem.Field(index)); err != nil {
+			return err
+		}
+		if m.MapIndex(k).IsValid() {
+			return fmt.Errorf("abi: duplicate key %v in field %s", k, key)
+		}
+		value := reflect.New(dst.Type().Elem())
+		if err := unpack(opts, t.Elem, value.Interface(), elem.Interface()); err != nil {
+			return err
+		}
+		m.SetMapIndex(k, value.Elem())
+	}
+	dst.Set(m)
+	return nil
+}
+
+// unpackField unpacks src into the named field of the struct dst, honouring the
+// options of the field's abi:"" tag and the type hints for interface fields.
+func unpackField(opts UnpackOptions, t *Type, dst reflect.Value, name string, src interface{}) error {
+	field := dst.FieldByName(name)
+	if sf, _ := dst.Type().FieldByName(name); hasTagOption(sf, "unixtime") {
+		return setUnixTime(field, reflect.ValueOf(src))
+	}
+	if hint, ok := opts.TypeHints[name]; ok && field.Kind() == reflect.Interface {
+		return unpackHinted(opts, t, field, hint, src)
+	}
+	return unpack(opts, t, field.Addr().Interface(), src)
+}
+
+// unpackHinted unpacks src into a newly allocated value of the hinted type and
+// stores it in the interface field.
+func unpackHinted(opts UnpackOptions, t *Type, field reflect.Value, hint reflect.Type, src interface{}) error {
+	if !hint.Implements(field.Type()) {
+		return errorf(ErrTypeMismatch, "abi: type hint %v does not implement %v", hint, field.Type())
+	}
+	ptr := reflect.New(hint)
+	if hint.Kind() == reflect.Ptr {
+		ptr.Elem().Set(reflect.New(hint.Elem()))
+	}
+	if err := unpack(opts, t, ptr.Interface(), src); err != nil {
+		return err
+	}
+	field.Set(ptr.Elem())
+	return nil
+}
+
 // unpackAtomic unpacks ( hexdata -> go ) a single value
-func (arguments Arguments) unpackAtomic(v interface{}, marshalledValues interface{}) error {
+func (arguments Arguments) unpackAtomic(opts UnpackOptions, v interface{}, marshalledValues interface{}) error {
 	if arguments.LengthNonIndexed() == 0 {
 		return nil
 	}
@@ -173,17 +302,19 @@ func (arguments Arguments) unpackAtomic(v interface{}, marshalledValues interfac
 		if err != nil {
 			return err
 		}
-		field := elem.FieldByName(fieldmap[argument.Name])
-		if !field.IsValid() {
+		if !elem.FieldByName(fieldmap[argument.Name]).IsValid() {
 			return fmt.Errorf("abi: field %s can't be found in the given value", argument.Name)
 		}
-		return unpack(&argument.Type, field.Addr().Interface(), marshalledValues)
+		if err := unpackField(opts, &argument.Type, elem, fieldmap[argument.Name], marshalledValues); err != nil {
+			return fieldError(argument.Name, fieldmap[argument.Name], err)
+		}
+		return nil
 	}
-	return unpack(&argument.Type, elem.Addr().Interface(), marshalledValues)
+	return unpack(opts, &argument.Type, elem.Addr().Interface(), marshalledValues)
 }
 
 // unpackTuple unpacks ( hexdata -> go ) a batch of values.
-func (arguments Arguments) unpackTuple(v interface{}, marshalledValues []interface{}) error {
+func (arguments Arguments) unpackTuple(opts UnpackOptions, v interface{}, marshalledValues []interface{}) error {
 	var (
 		value = reflect.ValueOf(v).Elem()
 		typ   = value.Type()
@@ -211,26 +342,25 @@ func (arguments Arguments) unpackTuple(v interface{}, marshalledValues []interfa
 	for i, arg := range arguments.NonIndexed() {
 		switch kind {
 		case reflect.Struct:
-			field := value.FieldByName(abi2struct[arg.Name])
-			if !field.IsValid() {
+			if !value.FieldByName(abi2struct[arg.Name]).IsValid() {
 				return fmt.Errorf("abi: field %s can't be found in the given value", arg.Name)
 			}
-			if err := unpack(&arg.Type, field.Addr().Interface(), marshalledValues[i]); err != nil {
-				return err
+			if err := unpackField(opts, &arg.Type, value, abi2struct[arg.Name], marshalledValues[i]); err != nil {
+				return fieldError(arg.Name, abi2struct[arg.Name], err)
 			}
 		case reflect.Slice, reflect.Array:
 			if value.Len() < i {
-				return fmt.Errorf("abi: insufficient number of arguments for unpack, want %d, got %d", len(arguments), value.Len())
+				return errorf(ErrArity, "abi: insufficient number of arguments for unpack, want %d, got %d", len(arguments), value.Len())
 			}
 			v := value.Index(i)
 			if err := requireAssignable(v, reflect.ValueOf(marshalledValues[i])); err != nil {
 				return err
 			}
-			if err := unpack(&arg.Type, v.Addr().Interface(), marshalledValues[i]); err != nil {
+			if err := unpack(opts, &arg.Type, v.Addr().Interface(), marshalledValues[i]); err != nil {
 				return err
 			}
 		default:
-			return fmt.Errorf("abi:[2] cannot unmarshal tuple in to %v", typ)
+			return errorf(ErrTypeMismatch, "abi:[2] cannot unmarshal tuple in to %v", typ)
 		}
 	}
 	return nil
@@ -240,11 +370,87 @@ func (arguments Arguments) unpackTuple(v interface{}, marshalledValues []interfa
 // UnpackValues can be used to unpack ABI-encoded hexdata according to the ABI-specification,
 // without supplying a struct to unpack into. Instead, this method returns a list containing the
 // values. An atomic argument will be a list with one element.
+//
+// Every value has the Go type reported by its argument's Type.Type: sized Go integers
+// for int8 to int64 (and their unsigned variants), *big.Int for all other integers,
+// Go arrays and slices for ABI arrays and anonymous structs for tuples. Use
+// UnpackMapped to get tuples as maps instead.
 func (arguments Arguments) UnpackValues(data []byte) ([]interface{}, error) {
+	return arguments.UnpackContext(context.Background(), data)
+}
+
+// UnpackUint256 decodes the output of a method returning a single uint256, such
+// as an ERC20 balanceOf, without going through the generic reflection based
+// decoder.
+func (arguments Arguments) UnpackUint256(data []byte) (*big.Int, error) {
+	word, err := arguments.singleWord(data, "uint256")
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(word), nil
+}
+
+// UnpackAddress decodes the output of a method returning a single address, such
+// as an owner getter, without going through the generic reflection based
+// decoder.
+func (arguments Arguments) UnpackAddress(data []byte) (common.Address, error) {
+	word, err := arguments.singleWord(data, "address")
+	if err != nil {
+		return common.Address{}, err
+	}
+	return common.BytesToAddress(word), nil
+}
+
+// types returns the canonical types of the arguments as a tuple signature.
+func (arguments Arguments) types() string {
+	types := make([]string, len(arguments))
+	for i, arg := range arguments {
+		types[i] = arg.Type.String()
+	}
+	return "(" + strings.Join(types, ",") + ")"
+}
+
+// singleWord checks that the arguments consist of a single value of the given
+// type and returns the word encoding it.
+func (arguments Arguments) singleWord(data []byte, typ string) ([]byte, error) {
+	if len(arguments) != 1 || arguments[0].Indexed || arguments[0].Type.String() != typ {
+		return nil, errorf(ErrTypeMismatch, "abi: cannot unpack %s as a single %s", arguments.types(), typ)
+	}
+	if len(data) < 32 {
+		return nil, errorf(ErrOutOfBounds, "abi: cannot unmarshal %s from %d bytes", typ, len(data))
+	}
+	return data[:32], nil
+}
+
+// UnpackContext works like UnpackValues, but aborts with the context's error if
+// ctx is cancelled or times out while decoding. This allows bounding the time
+// spent on decoding large, untrusted inputs.
+func (arguments Arguments) UnpackContext(ctx context.Context, data []byte) ([]interface{}, error) {
+	return arguments.unpackValues(ctx, UnpackOptions{}, data)
+}
+
+// UnpackWithWords works like UnpackValues, but also returns the 32 byte words
+// of the head of the encoding in order, holding static values in place and the
+// offsets of dynamic ones. It is meant for debugging encodings.
+func (arguments Arguments) UnpackWithWords(data []byte) ([]interface{}, [][32]byte, error) {
+	values, err := arguments.UnpackValues(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	words := make([][32]byte, arguments.NonIndexed().headSize()/32)
+	for i := range words {
+		copy(words[i][:], data[i*32:])
+	}
+	return values, words, nil
+}
+
+// unpackValues decodes the non-indexed arguments from data into their Go
+// representations.
+func (arguments Arguments) unpackValues(ctx context.Context, opts UnpackOptions, data []byte) ([]interface{}, error) {
 	retval := make([]interface{}, 0, arguments.LengthNonIndexed())
 	virtualArgs := 0
 	for index, arg := range arguments.NonIndexed() {
-		marshalledValue, err := toGoType((index+virtualArgs)*32, arg.Type, data)
+		marshalledValue, err := toGoType(ctx, opts, (index+virtualArgs)*32, arg.Type, data)
 		if arg.Type.T == ArrayTy && !isDynamicType(arg.Type) {
 			// If we have a static array, like [3]uint256, these are coded as
 			// just like uint256,uint256,uint256.
@@ -270,6 +476,51 @@ func (arguments Arguments) UnpackValues(data []byte) ([]interface{}, error) {
 	return retval, nil
 }
 
+// UnpackMapped works like UnpackValues, but returns every tuple, including the
+// ones nested in arrays or other tuples, as a map[string]interface{} keyed by
+// the raw component names. Arrays containing tuples are returned as
+// []interface{}, all other values are left as UnpackValues produces them.
+func (arguments Arguments) UnpackMapped(data []byte) ([]interface{}, error) {
+	values, err := arguments.UnpackValues(data)
+	if err != nil {
+		return nil, err
+	}
+	for i, arg := range arguments.NonIndexed() {
+		values[i] = toMappedValue(arg.Type, reflect.ValueOf(values[i]))
+	}
+	return values, nil
+}
+
+// toMappedValue converts the unpacked value v of type t into its map based
+// representation used by UnpackMapped.
+func toMappedValue(t Type, v reflect.Value) interface{} {
+	switch {
+	case t.T == TupleTy:
+		fields := make(map[string]interface{}, len(t.TupleElems))
+		for i, elem := range t.TupleElems {
+			fields[t.TupleRawNames[i]] = toMappedValue(*elem, v.Field(i))
+		}
+		return fields
+	case (t.T == SliceTy || t.T == ArrayTy) && containsTuple(*t.Elem):
+		elems := make([]interface{}, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			elems[i] = toMappedValue(*t.Elem, v.Index(i))
+		}
+		return elems
+	default:
+		return v.Interface()
+	}
+}
+
+// containsTuple returns whether t is a tuple or an (arbitrarily nested) array
+// of tuples.
+func containsTuple(t Type) bool {
+	if t.T == SliceTy || t.T == ArrayTy {
+		return containsTuple(*t.Elem)
+	}
+	return t.T == TupleTy
+}
+
 // PackValues performs the operation Go format -> Hexdata
 // It is the semantic opposite of UnpackValues
 func (arguments Arguments) PackValues(args []interface{}) ([]byte, error) {
@@ -278,11 +529,119 @@ func (arguments Arguments) PackValues(args []interface{}) ([]byte, error) {
 
 // Pack performs the operation Go format -> Hexdata
 func (arguments Arguments) Pack(args ...interface{}) ([]byte, error) {
+	return arguments.PackWithOptions(PackOptions{}, args...)
+}
+
+// PackWithOptions works like Pack, but converts the Go values according to the
+// given options.
+func (arguments Arguments) PackWithOptions(opts PackOptions, args ...interface{}) ([]byte, error) {
 	// Make sure arguments match up and pack them
 	abiArgs := arguments
 	if len(args) != len(abiArgs) {
-		return nil, fmt.Errorf("argument count mismatch: %d for %d", len(args), len(abiArgs))
+		return nil, errorf(ErrArity, "argument count mismatch: %d for %d", len(args), len(abiArgs))
+	}
+	if arguments.isStatic() {
+		return arguments.packStatic(opts, args)
+	}
+	return arguments.packDynamic(opts, args)
+}
+
+// MustPack works like Pack, but panics if the arguments cannot be packed. It is
+// meant for tests and fixtures with known good values, not for production code.
+func (arguments Arguments) MustPack(args ...interface{}) []byte {
+	packed, err := arguments.Pack(args...)
+	if err != nil {
+		panic(err)
+	}
+	return packed
+}
+
+// PackHex works like Pack, but returns the encoding as a 0x prefixed hex string.
+func (arguments Arguments) PackHex(args ...interface{}) (string, error) {
+	packed, err := arguments.Pack(args...)
+	if err != nil {
+		return "", err
+	}
+	return hexutil.Encode(packed), nil
+}
+
+// PackHead packs the first n arguments only, which must all be of static types.
+// As static arguments are encoded in place, the result is a prefix of the full
+// encoding of any call sharing these values, and can be reused as a template
+// with the remaining arguments packed and appended later.
+func (arguments Arguments) PackHead(n int, args ...interface{}) ([]byte, error) {
+	if n < 0 || n > len(arguments) {
+		return nil, errorf(ErrArity, "abi: cannot pack %d leading arguments of %d", n, len(arguments))
+	}
+	for i, arg := range arguments[:n] {
+		if isDynamicType(arg.Type) {
+			return nil, fmt.Errorf("abi: argument %d of type %v is dynamic and cannot be packed as a head", i, arg.Type)
+		}
+	}
+	return arguments[:n].Pack(args...)
+}
+
+// EncodedSize returns the number of bytes Pack produces for the given arguments,
+// without encoding them. The values are only inspected as far as needed to
+// compute the size, so packing them may still fail.
+func (arguments Arguments) EncodedSize(args ...interface{}) (int, error) {
+	if len(args) != len(arguments) {
+		return 0, errorf(ErrArity, "argument count mismatch: %d for %d", len(args), len(arguments))
+	}
+	size := arguments.headSize()
+	for i, arg := range arguments {
+		if !isDynamicType(arg.Type) {
+			continue
+		}
+		tail, err := arg.Type.encodedSize(reflect.ValueOf(args[i]))
+		if err != nil {
+			return 0, err
+		}
+		size += tail
+	}
+	return size, nil
+}
+
+// headSize returns the number of bytes the heads of the arguments occupy in
+// their encoding, which is the minimum length of any valid encoding.
+func (arguments Arguments) headSize() int {
+	size := 0
+	for _, arg := range arguments {
+		size += getTypeSize(arg.Type)
+	}
+	return size
+}
+
+// isStatic returns whether none of the arguments is of a dynamic type, in which
+// case the encoding is the plain concatenation of the packed arguments.
+func (arguments Arguments) isStatic() bool {
+	for _, arg := range arguments {
+		if isDynamicType(arg.Type) {
+			return false
+		}
+	}
+	return true
+}
+
+// packStatic packs arguments that are all of static types, skipping the offset
+// bookkeeping needed for dynamic ones.
+func (arguments Arguments) packStatic(opts PackOptions, args []interface{}) ([]byte, error) {
+	ret := make([]byte, 0, arguments.headSize())
+	for i, a := range args {
+		packed, err := arguments[i].Type.packWithOptions(reflect.ValueOf(a), opts)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, packed...)
 	}
+	return ret, nil
+}
+
+// packDynamic packs arguments of any type, placing the contents of dynamic
+// arguments after the head and referencing them by offset.
+func (arguments Arguments) packDynamic(opts PackOptions, args []interface{}) ([]byte, error) {
+	abiArgs := arguments
+
 	// variable input is the output appended at the end of packed
 	// output. This is used for strings and bytes types input.
 	var variableInput []byte
@@ -296,7 +655,7 @@ func (arguments Arguments) Pack(args ...interface{}) ([]byte, error) {
 	for i, a := range args {
 		input := abiArgs[i]
 		// pack the input
-		packed, err := input.Type.pack(reflect.ValueOf(a))
+		packed, err := input.Type.packWithOptions(reflect.ValueOf(a), opts)
 		if err != nil {
 			return nil, err
 		}
@@ -319,6 +678,57 @@ func (arguments Arguments) Pack(args ...interface{}) ([]byte, error) {
 	return ret, nil
 }
 
+// tupleType assembles a tuple type from the arguments. Arguments which have no
+// name usable as a struct field are called argN, N being their position or the
+// first one after it that leaves the name unique.
+func (arguments Arguments) tupleType() (Type, error) {
+	var (
+		fields []reflect.StructField
+		elems  = make([]*Type, len(arguments))
+		names  = make([]string, len(arguments))
+		types  = make([]string, len(arguments))
+		used   = make(map[string]bool)
+	)
+	for i, arg := range arguments {
+		name := arg.Name
+		for n := i; !isFieldName(ToCamelCase(name)) || used[ToCamelCase(name)]; n++ {
+			name = fmt.Sprintf("arg%d", n)
+		}
+		used[ToCamelCase(name)] = true
+
+		elem := arg.Type
+		if elem.Type == nil {
+			return Type{}, fmt.Errorf("abi: argument %d has no Go type", i)
+		}
+		fields = append(fields, reflect.StructField{Name: ToCamelCase(name), Type: elem.Type})
+		elems[i] = &elem
+		names[i] = name
+		types[i] = elem.String()
+	}
+	return Type{
+		Kind:          reflect.Struct,
+		Type:          reflect.StructOf(fields),
+		T:             TupleTy,
+		stringKind:    "(" + strings.Join(types, ",") + ")",
+		TupleElems:    elems,
+		TupleRawNames: names,
+	}, nil
+}
+
+// isFieldName returns whether name is an exported Go identifier, as required
+// for the fields of a struct built by reflection.
+func isFieldName(name string) bool {
+	for i, c := range name {
+		if i == 0 && !unicode.IsUpper(c) {
+			return false
+		}
+		if !unicode.IsLetter(c) && !unicode.IsDigit(c) && c != '_' {
+			return false
+		}
+	}
+	return name != ""
+}
+
 // ToCamelCase converts an under-score string to a camel-case string
 func ToCamelCase(input string) string {
 	parts := strings.Split(input, "_")
@@ -326,18 +326,28 @@ func (c *BoundContract) WatchLogs(opts *WatchOpts, name string, query ...[]inter
 
 // UnpackLog unpacks a retrieved log into the provided output structure.
 func (c *BoundContract) UnpackLog(out interface{}, event string, log types.Log) error {
-	if len(log.Data) > 0 {
-		if err := c.abi.Unpack(out, event, log.Data); err != nil {
-			return err
-		}
+	ev, ok := c.abi.Events[event]
+	if !ok {
+		return fmt.Errorf("abi: could not locate event %s", event)
 	}
-	var indexed abi.Arguments
-	for _, arg := range c.abi.Events[event].Inputs {
-		if arg.Indexed {
-			indexed = append(indexed, arg)
-		}
+	return ev.UnpackLog(out, log)
+}
+
+// UnpackLogs unpacks a batch of logs of the same event into out, which must be a
+// pointer to a slice of structs or struct pointers. See abi.Event.UnpackLogs.
+func (c *BoundContract) UnpackLogs(out interface{}, event string, logs []types.Log) error {
+	ev, ok := c.abi.Events[event]
+	if !ok {
+		return fmt.Errorf("abi: could not locate event %s", event)
 	}
-	return parseTopics(out, indexed, log.Topics[1:])
+	return ev.UnpackLogs(out, logs)
+}
+
+// ParseLogToMap identifies the event of a log by its signature topic and decodes
+// all its parameters into a map keyed by parameter name, returning the name of
+// the event. See abi.ABI.ParseLogToMap.
+func (c *BoundContract) ParseLogToMap(log types.Log) (string, map[string]interface{}, error) {
+	return c.abi.ParseLogToMap(log)
 }
 
 // ensureContext is a helper method to ensure a context is not nil, even if the
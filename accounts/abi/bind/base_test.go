@@ -3,12 +3,14 @@ package bind_test
 import (
 	"context"
 	"math/big"
+	"strings"
 	"testing"
 
 	ethereum "github.com/enode"
 	"github.com/enode/accounts/abi"
 	"github.com/enode/accounts/abi/bind"
 	"github.com/enode/common"
+	"github.com/enode/core/types"
 )
 
 type mockCaller struct {
@@ -62,3 +64,120 @@ func TestPassingBlockNumber(t *testing.T) {
 		t.Fatalf("CodeAt() was passed a block number when it should not have been")
 	}
 }
+
+func TestUnpackLogMalformed(t *testing.T) {
+	parsed, err := abi.JSON(strings.NewReader(`[
+		{"type":"event","name":"Transfer","inputs":[{"name":"from","type":"address","indexed":true},{"name":"value","type":"uint256"},{"name":"memo","type":"string"}]}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bc := bind.NewBoundContract(common.Address{}, parsed, nil, nil, nil)
+
+	data, err := parsed.Events["Transfer"].Inputs.NonIndexed().Pack(big.NewInt(1), "hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	topics := []common.Hash{parsed.Events["Transfer"].ID(), common.BytesToHash(common.Address{1}.Bytes())}
+
+	var out struct {
+		From  common.Address
+		Value *big.Int
+		Memo  string
+	}
+	if err := bc.UnpackLog(&out, "Transfer", types.Log{Topics: topics, Data: data}); err != nil {
+		t.Fatalf("failed to unpack valid log: %v", err)
+	}
+	for _, size := range []int{0, 31, 32, 63, 64, 95} {
+		err := bc.UnpackLog(&out, "Transfer", types.Log{Topics: topics, Data: data[:size]})
+		if err == nil {
+			t.Errorf("expected error for %d bytes of data", size)
+		}
+	}
+	if err := bc.UnpackLog(&out, "Transfer", types.Log{Topics: topics, Data: data[:63]}); err == nil || !strings.Contains(err.Error(), "insufficient data for event Transfer") {
+		t.Errorf("error mismatch: have %v, want insufficient data", err)
+	}
+	if err := bc.UnpackLog(&out, "Transfer", types.Log{Data: data}); err == nil {
+		t.Error("expected error for log without topics")
+	}
+}
+
+func TestUnpackLogs(t *testing.T) {
+	parsed, err := abi.JSON(strings.NewReader(`[
+		{"type":"event","name":"Transfer","inputs":[{"name":"from","type":"address","indexed":true},{"name":"to","type":"address","indexed":true},{"name":"value","type":"uint256"}]}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bc := bind.NewBoundContract(common.Address{}, parsed, nil, nil, nil)
+
+	type transfer struct {
+		From  common.Address
+		To    common.Address
+		Value *big.Int
+	}
+	var logs []types.Log
+	for i := 1; i <= 3; i++ {
+		data, err := parsed.Events["Transfer"].Inputs.NonIndexed().Pack(big.NewInt(int64(i * 100)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		topics := []common.Hash{
+			parsed.Events["Transfer"].ID(),
+			common.BytesToHash(common.Address{byte(i)}.Bytes()),
+			common.BytesToHash(common.Address{byte(i + 1)}.Bytes()),
+		}
+		logs = append(logs, types.Log{Topics: topics, Data: data})
+	}
+	transfers := []transfer{{Value: big.NewInt(1)}}
+	if err := bc.UnpackLogs(&transfers, "Transfer", logs); err != nil {
+		t.Fatal(err)
+	}
+	if len(transfers) != 4 {
+		t.Fatalf("transfer count mismatch: have %d, want 4", len(transfers))
+	}
+	for i, tr := range transfers[1:] {
+		if tr.From != (common.Address{byte(i + 1)}) || tr.To != (common.Address{byte(i + 2)}) || tr.Value.Int64() != int64((i+1)*100) {
+			t.Errorf("transfer %d mismatch: have %+v", i, tr)
+		}
+	}
+	var pointers []*transfer
+	if err := bc.UnpackLogs(&pointers, "Transfer", logs); err != nil {
+		t.Fatal(err)
+	}
+	if len(pointers) != 3 || pointers[2].Value.Int64() != 300 {
+		t.Errorf("pointer transfers mismatch: have %v", pointers)
+	}
+	logs[1].Data = logs[1].Data[:16]
+	if err := bc.UnpackLogs(&pointers, "Transfer", logs); err == nil || !strings.HasPrefix(err.Error(), "log 1:") {
+		t.Errorf("error mismatch: have %v, want log 1 failure", err)
+	}
+	if len(pointers) != 3 {
+		t.Errorf("failed batch modified output: have %d transfers, want 3", len(pointers))
+	}
+	if err := bc.UnpackLogs(transfers, "Transfer", logs); err == nil {
+		t.Error("expected error for non-pointer output")
+	}
+}
+
+func TestParseLogToMap(t *testing.T) {
+	parsed, err := abi.JSON(strings.NewReader(`[
+		{"type":"event","name":"Transfer","inputs":[{"name":"from","type":"address","indexed":true},{"name":"value","type":"uint256"}]}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bc := bind.NewBoundContract(common.Address{}, parsed, nil, nil, nil)
+
+	transfer := types.Log{
+		Topics: []common.Hash{parsed.Events["Transfer"].ID(), common.BytesToHash(common.Address{1}.Bytes())},
+		Data:   common.LeftPadBytes(big.NewInt(100).Bytes(), 32),
+	}
+	name, values, err := bc.ParseLogToMap(transfer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "Transfer" || values["from"] != (common.Address{1}) || values["value"].(*big.Int).Int64() != 100 {
+		t.Errorf("transfer mismatch: have %s with %v", name, values)
+	}
+}
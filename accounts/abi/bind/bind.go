@@ -318,21 +318,19 @@ var bindTopicType = map[Lang]func(kind abi.Type) string{
 // bindTypeGo converts a Solidity topic type to a Go one. It is almost the same
 // funcionality as for simple types, but dynamic types get converted to hashes.
 func bindTopicTypeGo(kind abi.Type) string {
-	bound := bindTypeGo(kind)
-	if bound == "string" || bound == "[]byte" {
-		bound = "common.Hash"
+	if kind.HashedTopic() {
+		return "common.Hash"
 	}
-	return bound
+	return bindTypeGo(kind)
 }
 
 // bindTypeGo converts a Solidity topic type to a Java one. It is almost the same
 // funcionality as for simple types, but dynamic types get converted to hashes.
 func bindTopicTypeJava(kind abi.Type) string {
-	bound := bindTypeJava(kind)
-	if bound == "String" || bound == "Bytes" {
-		bound = "Hash"
+	if kind.HashedTopic() {
+		return "Hash"
 	}
-	return bound
+	return bindTypeJava(kind)
 }
 
 // namedType is a set of functions that transform language specific types to
@@ -0,0 +1,320 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package abi
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/enode/common"
+	"github.com/enode/common/hexutil"
+)
+
+// literal is a single parsed argument of a call string. It is either a plain
+// token (number, hex blob, bool), a quoted string, or a bracketed ('[') or
+// parenthesized ('(') list of nested literals.
+type literal struct {
+	text   string
+	quoted bool
+	open   byte
+	elems  []*literal
+}
+
+// PackFromCallString parses a Solidity style call such as
+//
+//	transfer(0x8A8eAFb1cf62BfBeb1741769DAE1a9dd47996192, 1000)
+//
+// and packs it as calldata of the named method. Addresses and bytes are given
+// as hex, integers as decimal or 0x prefixed hex, strings double quoted, arrays
+// as [a, b, ...] and tuples as (a, b, ...). If the method is overloaded, the
+// first overload whose inputs accept the arguments is used.
+func (abi ABI) PackFromCallString(call string) ([]byte, error) {
+	call = strings.TrimSpace(call)
+	open := strings.IndexByte(call, '(')
+	if open <= 0 || !strings.HasSuffix(call, ")") {
+		return nil, fmt.Errorf("abi: malformed call %q", call)
+	}
+	name := strings.TrimSpace(call[:open])
+	args, err := parseLiterals(call[open:])
+	if err != nil {
+		return nil, err
+	}
+	var (
+		found   bool
+		lastErr error
+	)
+	for _, method := range abi.orderedMethods() {
+		if method.Name != name {
+			continue
+		}
+		found = true
+		if len(method.Inputs) != len(args.elems) {
+			lastErr = errorf(ErrArity, "abi: %s expects %d arguments, got %d", name, len(method.Inputs), len(args.elems))
+			continue
+		}
+		values, err := args.values(method.Inputs)
+		if err != nil {
+			lastErr = fmt.Errorf("abi: %s: %v", name, err)
+			continue
+		}
+		arguments, err := method.Inputs.Pack(values...)
+		if err != nil {
+			return nil, err
+		}
+		return append(method.Id(), arguments...), nil
+	}
+	if !found {
+		return nil, fmt.Errorf("method '%s' not found", name)
+	}
+	return nil, lastErr
+}
+
+// orderedMethods returns the methods in declaration order. ABIs not parsed from
+// JSON carry no declaration order, so their methods are ordered by key, which
+// still places every overload after the one it is numbered from.
+func (abi ABI) orderedMethods() []Method {
+	if len(abi.MethodsOrdered) > 0 {
+		return abi.MethodsOrdered
+	}
+	keys := make([]string, 0, len(abi.Methods))
+	for key := range abi.Methods {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	methods := make([]Method, len(keys))
+	for i, key := range keys {
+		methods[i] = abi.Methods[key]
+	}
+	return methods
+}
+
+// parseLiterals parses a complete parenthesized argument list.
+func parseLiterals(s string) (*literal, error) {
+	lit, rest, err := parseLiteral(s)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(rest) != "" {
+		return nil, fmt.Errorf("abi: unexpected %q after arguments", rest)
+	}
+	return lit, nil
+}
+
+// parseLiteral parses a single literal from the start of s, returning the
+// unconsumed remainder.
+func parseLiteral(s string) (*literal, string, error) {
+	s = strings.TrimLeft(s, " \t\n")
+	if s == "" {
+		return nil, "", fmt.Errorf("abi: unexpected end of call string")
+	}
+	switch s[0] {
+	case '[', '(':
+		closing := byte(']')
+		if s[0] == '(' {
+			closing = ')'
+		}
+		lit := &literal{open: s[0]}
+		rest := strings.TrimLeft(s[1:], " \t\n")
+		if strings.HasPrefix(rest, string(closing)) {
+			return lit, rest[1:], nil
+		}
+		for {
+			elem, r, err := parseLiteral(rest)
+			if err != nil {
+				return nil, "", err
+			}
+			lit.elems = append(lit.elems, elem)
+			r = strings.TrimLeft(r, " \t\n")
+			switch {
+			case strings.HasPrefix(r, ","):
+				rest = r[1:]
+			case strings.HasPrefix(r, string(closing)):
+				return lit, r[1:], nil
+			default:
+				return nil, "", fmt.Errorf("abi: expected ',' or '%c' at %q", closing, r)
+			}
+		}
+	case '"':
+		// Find the closing quote, skipping over escaped characters
+		end := 1
+		for end < len(s) && s[end] != '"' {
+			if s[end] == '\\' {
+				end++
+			}
+			end++
+		}
+		if end >= len(s) {
+			return nil, "", fmt.Errorf("abi: unterminated string at %q", s)
+		}
+		text, err := strconv.Unquote(s[:end+1])
+		if err != nil {
+			return nil, "", fmt.Errorf("abi: invalid string %s: %v", s[:end+1], err)
+		}
+		return &literal{text: text, quoted: true}, s[end+1:], nil
+	default:
+		end := strings.IndexAny(s, ",)] \t\n")
+		if end < 0 {
+			end = len(s)
+		}
+		if end == 0 {
+			return nil, "", fmt.Errorf("abi: unexpected %q", s)
+		}
+		return &literal{text: s[:end]}, s[end:], nil
+	}
+}
+
+// values converts the elements of an argument list into Go values matching
+// the given inputs.
+func (lit *literal) values(inputs Arguments) ([]interface{}, error) {
+	values := make([]interface{}, len(lit.elems))
+	for i, elem := range lit.elems {
+		v, err := elem.value(inputs[i].Type)
+		if err != nil {
+			return nil, fmt.Errorf("argument %d: %v", i, err)
+		}
+		values[i] = v.Interface()
+	}
+	return values, nil
+}
+
+// value converts the literal into a Go value of the reflection type of t.
+func (lit *literal) value(t Type) (reflect.Value, error) {
+	switch t.T {
+	case SliceTy, ArrayTy:
+		if lit.open != '[' {
+			return reflect.Value{}, fmt.Errorf("expected array for %v, got %s", t, lit)
+		}
+		var out reflect.Value
+		if t.T == SliceTy {
+			out = reflect.MakeSlice(t.Type, len(lit.elems), len(lit.elems))
+		} else {
+			if len(lit.elems) != t.Size {
+				return reflect.Value{}, fmt.Errorf("expected %d elements for %v, got %d", t.Size, t, len(lit.elems))
+			}
+			out = reflect.New(t.Type).Elem()
+		}
+		for i, elem := range lit.elems {
+			v, err := elem.value(*t.Elem)
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			out.Index(i).Set(v)
+		}
+		return out, nil
+
+	case TupleTy:
+		if lit.open != '(' {
+			return reflect.Value{}, fmt.Errorf("expected tuple for %v, got %s", t, lit)
+		}
+		if len(lit.elems) != len(t.TupleElems) {
+			return reflect.Value{}, fmt.Errorf("expected %d components for %v, got %d", len(t.TupleElems), t, len(lit.elems))
+		}
+		out := reflect.New(t.Type).Elem()
+		for i, elem := range lit.elems {
+			v, err := elem.value(*t.TupleElems[i])
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			out.Field(i).Set(v)
+		}
+		return out, nil
+	}
+	if lit.open != 0 {
+		return reflect.Value{}, fmt.Errorf("expected %v, got %s", t, lit)
+	}
+	out := reflect.New(t.Type).Elem()
+	switch t.T {
+	case IntTy, UintTy:
+		if lit.quoted {
+			return reflect.Value{}, fmt.Errorf("expected %v, got string %s", t, lit)
+		}
+		num, err := parseNumString(lit.text)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		if err := checkIntRange(t, num); err != nil {
+			return reflect.Value{}, err
+		}
+		switch {
+		case t.Type == bigT:
+			out.Set(reflect.ValueOf(num))
+		case t.unsigned():
+			out.SetUint(num.Uint64())
+		default:
+			out.SetInt(num.Int64())
+		}
+	case BoolTy:
+		b, err := strconv.ParseBool(lit.text)
+		if err != nil || lit.quoted {
+			return reflect.Value{}, fmt.Errorf("expected bool, got %s", lit)
+		}
+		out.SetBool(b)
+	case StringTy:
+		if !lit.quoted {
+			return reflect.Value{}, fmt.Errorf("expected quoted string, got %s", lit)
+		}
+		out.SetString(lit.text)
+	case AddressTy:
+		if lit.quoted || !common.IsHexAddress(lit.text) {
+			return reflect.Value{}, fmt.Errorf("expected address, got %s", lit)
+		}
+		out.Set(reflect.ValueOf(common.HexToAddress(lit.text)))
+	case BytesTy, FixedBytesTy, FunctionTy:
+		var blob []byte
+		if lit.quoted {
+			blob = []byte(lit.text)
+		} else {
+			var err error
+			if blob, err = hexutil.Decode(lit.text); err != nil {
+				return reflect.Value{}, fmt.Errorf("expected hex bytes, got %s", lit)
+			}
+		}
+		if t.T == BytesTy {
+			out.SetBytes(blob)
+			break
+		}
+		if len(blob) != out.Len() {
+			return reflect.Value{}, fmt.Errorf("expected %d bytes for %v, got %d", out.Len(), t, len(blob))
+		}
+		reflect.Copy(out, reflect.ValueOf(blob))
+	default:
+		return reflect.Value{}, fmt.Errorf("unsupported argument type %v", t)
+	}
+	return out, nil
+}
+
+// String implements fmt.Stringer, rendering the literal back in call syntax.
+func (lit *literal) String() string {
+	if lit.open == 0 {
+		if lit.quoted {
+			return strconv.Quote(lit.text)
+		}
+		return lit.text
+	}
+	elems := make([]string, len(lit.elems))
+	for i, elem := range lit.elems {
+		elems[i] = elem.String()
+	}
+	closing := "]"
+	if lit.open == '(' {
+		closing = ")"
+	}
+	return string(lit.open) + strings.Join(elems, ", ") + closing
+}
@@ -0,0 +1,140 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package abi
+
+import (
+	"bytes"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/enode/common"
+)
+
+const callStringABI = `[
+	{"type":"function","name":"transfer","inputs":[{"name":"to","type":"address"},{"name":"value","type":"uint256"}]},
+	{"type":"function","name":"sum","inputs":[{"name":"values","type":"uint8[]"},{"name":"label","type":"string"}]},
+	{"type":"function","name":"submit","inputs":[{"name":"order","type":"tuple","components":[{"name":"id","type":"bytes4"},{"name":"amounts","type":"int64[2]"}]},{"name":"ok","type":"bool"}]},
+	{"type":"function","name":"set","inputs":[{"name":"value","type":"uint256"}]},
+	{"type":"function","name":"set","inputs":[{"name":"value","type":"string"}]}
+]`
+
+func TestPackFromCallString(t *testing.T) {
+	abi, err := JSON(strings.NewReader(callStringABI))
+	if err != nil {
+		t.Fatal(err)
+	}
+	to := common.HexToAddress("0x8A8eAFb1cf62BfBeb1741769DAE1a9dd47996192")
+
+	// transfer(address,uint256)
+	have, err := abi.PackFromCallString("transfer(0x8A8eAFb1cf62BfBeb1741769DAE1a9dd47996192, 1000)")
+	if err != nil {
+		t.Fatalf("transfer: %v", err)
+	}
+	if want := abi.MustPack("transfer", to, big.NewInt(1000)); !bytes.Equal(have, want) {
+		t.Errorf("transfer mismatch:\nhave %x\nwant %x", have, want)
+	}
+	// sum(uint8[],string)
+	have, err = abi.PackFromCallString(`sum([1, 2, 0x3], "a, b")`)
+	if err != nil {
+		t.Fatalf("sum: %v", err)
+	}
+	if want := abi.MustPack("sum", []uint8{1, 2, 3}, "a, b"); !bytes.Equal(have, want) {
+		t.Errorf("sum mismatch:\nhave %x\nwant %x", have, want)
+	}
+	// submit((bytes4,int64[2]),bool)
+	have, err = abi.PackFromCallString("submit((0xdeadbeef, [-1, 2]), true)")
+	if err != nil {
+		t.Fatalf("submit: %v", err)
+	}
+	order := struct {
+		Id      [4]byte
+		Amounts [2]int64
+	}{[4]byte{0xde, 0xad, 0xbe, 0xef}, [2]int64{-1, 2}}
+	if want := abi.MustPack("submit", order, true); !bytes.Equal(have, want) {
+		t.Errorf("submit mismatch:\nhave %x\nwant %x", have, want)
+	}
+	// Overloads are picked by the arguments they accept
+	have, err = abi.PackFromCallString(`set("x")`)
+	if err != nil {
+		t.Fatalf("set: %v", err)
+	}
+	if want := append(abi.Methods["set0"].Id(), abi.Methods["set0"].Inputs.MustPack("x")...); !bytes.Equal(have, want) {
+		t.Errorf("overloaded set mismatch:\nhave %x\nwant %x", have, want)
+	}
+	// Strings may contain escaped quotes and backslashes
+	have, err = abi.PackFromCallString(`set("a \"b\", c\\")`)
+	if err != nil {
+		t.Fatalf("set escaped: %v", err)
+	}
+	if want := append(abi.Methods["set0"].Id(), abi.Methods["set0"].Inputs.MustPack(`a "b", c\`)...); !bytes.Equal(have, want) {
+		t.Errorf("escaped string mismatch:\nhave %x\nwant %x", have, want)
+	}
+}
+
+func TestPackFromCallStringHandBuilt(t *testing.T) {
+	uint256, _ := NewType("uint256", nil)
+	str, _ := NewType("string", nil)
+	abi := ABI{Methods: map[string]Method{
+		"set":  {Name: "set", Inputs: Arguments{{Name: "value", Type: uint256}}},
+		"set0": {Name: "set", Inputs: Arguments{{Name: "value", Type: str}}},
+	}}
+	have, err := abi.PackFromCallString("set(7)")
+	if err != nil {
+		t.Fatalf("set(uint256): %v", err)
+	}
+	if want := abi.MustPack("set", big.NewInt(7)); !bytes.Equal(have, want) {
+		t.Errorf("set(uint256) mismatch:\nhave %x\nwant %x", have, want)
+	}
+	have, err = abi.PackFromCallString(`set("x")`)
+	if err != nil {
+		t.Fatalf("set(string): %v", err)
+	}
+	if want := abi.MustPack("set0", "x"); !bytes.Equal(have, want) {
+		t.Errorf("set(string) mismatch:\nhave %x\nwant %x", have, want)
+	}
+	if _, err := abi.PackFromCallString("missing(1)"); err == nil {
+		t.Error("expected error for missing method")
+	}
+}
+
+func TestPackFromCallStringErrors(t *testing.T) {
+	abi, err := JSON(strings.NewReader(callStringABI))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, call := range []string{
+		"transfer",
+		"missing(1)",
+		"transfer(0x01, 1000)",
+		"transfer(0x8A8eAFb1cf62BfBeb1741769DAE1a9dd47996192)",
+		"transfer(0x8A8eAFb1cf62BfBeb1741769DAE1a9dd47996192, -1)",
+		`sum([1, 256], "x")`,
+		`sum([1, 2, "x")`,
+		`sum([1], x)`,
+		"submit((0xdead, [1, 2]), true)",
+		"submit((0xdeadbeef, [1]), true)",
+		"set([1])",
+		`set("x)`,
+		`set("x\")`,
+		`set("\q")`,
+	} {
+		if _, err := abi.PackFromCallString(call); err == nil {
+			t.Errorf("%s: expected error", call)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package abi
+
+import (
+	"sort"
+)
+
+// ABIDiff lists the differences between the methods of two ABIs. Methods are
+// matched by their signature, so a method whose input types change shows up as
+// removed and added. All lists are sorted by signature.
+type ABIDiff struct {
+	Added   []Method     // Methods only present in the new ABI
+	Removed []Method     // Methods only present in the old ABI
+	Changed []MethodDiff // Methods present in both, with different arguments
+}
+
+// MethodDiff is a method whose selector is kept, but whose arguments differ.
+type MethodDiff struct {
+	Old Method
+	New Method
+}
+
+// Empty returns whether the ABIs have no differences.
+func (diff ABIDiff) Empty() bool {
+	return len(diff.Added) == 0 && len(diff.Removed) == 0 && len(diff.Changed) == 0
+}
+
+// Diff compares the methods of two ABIs. Arguments are compared by their
+// canonical types, so renaming an argument is not reported as a change.
+func Diff(old, new ABI) ABIDiff {
+	oldMethods, newMethods := methodsBySig(old), methodsBySig(new)
+
+	var diff ABIDiff
+	for sig, method := range oldMethods {
+		updated, ok := newMethods[sig]
+		if !ok {
+			diff.Removed = append(diff.Removed, method)
+			continue
+		}
+		if !method.Inputs.Equal(updated.Inputs) || !method.Outputs.Equal(updated.Outputs) {
+			diff.Changed = append(diff.Changed, MethodDiff{Old: method, New: updated})
+		}
+	}
+	for sig, method := range newMethods {
+		if _, ok := oldMethods[sig]; !ok {
+			diff.Added = append(diff.Added, method)
+		}
+	}
+	sortMethods(diff.Added)
+	sortMethods(diff.Removed)
+	sort.Slice(diff.Changed, func(i, j int) bool {
+		return diff.Changed[i].Old.Sig() < diff.Changed[j].Old.Sig()
+	})
+	return diff
+}
+
+// methodsBySig returns the methods of the ABI keyed by their signature.
+func methodsBySig(abi ABI) map[string]Method {
+	methods := make(map[string]Method, len(abi.Methods))
+	for _, method := range abi.Methods {
+		methods[method.Sig()] = method
+	}
+	return methods
+}
+
+// sortMethods sorts the methods by their signature.
+func sortMethods(methods []Method) {
+	sort.Slice(methods, func(i, j int) bool {
+		return methods[i].Sig() < methods[j].Sig()
+	})
+}
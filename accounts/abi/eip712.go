@@ -0,0 +1,78 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package abi
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/enode/common"
+	"github.com/enode/crypto"
+)
+
+// EIP712Domain is the domain of EIP-712 typed data. Empty strings and nil
+// fields are absent from the domain and omitted from its type.
+type EIP712Domain struct {
+	Name              string
+	Version           string
+	ChainId           *big.Int
+	VerifyingContract *common.Address
+	Salt              *common.Hash
+}
+
+// DomainSeparator returns the EIP-712 domain separator, the hash of the domain
+// struct, which prefixes the struct hashes of all messages signed for it.
+func DomainSeparator(domain EIP712Domain) (common.Hash, error) {
+	var (
+		bytes32, _ = NewType("bytes32", nil)
+		uint256, _ = NewType("uint256", nil)
+		address, _ = NewType("address", nil)
+
+		fields []string
+		args   Arguments
+		values []interface{}
+	)
+	add := func(name, typ string, encType Type, value interface{}) {
+		fields = append(fields, typ+" "+name)
+		args = append(args, Argument{Name: name, Type: encType})
+		values = append(values, value)
+	}
+	// Dynamic values are encoded as their hashes, so all fields are static
+	if domain.Name != "" {
+		add("name", "string", bytes32, crypto.Keccak256Hash([]byte(domain.Name)))
+	}
+	if domain.Version != "" {
+		add("version", "string", bytes32, crypto.Keccak256Hash([]byte(domain.Version)))
+	}
+	if domain.ChainId != nil {
+		add("chainId", "uint256", uint256, domain.ChainId)
+	}
+	if domain.VerifyingContract != nil {
+		add("verifyingContract", "address", address, *domain.VerifyingContract)
+	}
+	if domain.Salt != nil {
+		add("salt", "bytes32", bytes32, *domain.Salt)
+	}
+	typeHash := crypto.Keccak256Hash([]byte(fmt.Sprintf("EIP712Domain(%s)", strings.Join(fields, ","))))
+
+	encoded, err := args.Pack(values...)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return crypto.Keccak256Hash(typeHash.Bytes(), encoded), nil
+}
@@ -0,0 +1,54 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package abi
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/enode/common"
+	"github.com/enode/crypto"
+)
+
+func TestDomainSeparator(t *testing.T) {
+	contract := common.HexToAddress("0xCcCCccccCCCCcCCCCCCcCcCccCcCCCcCcccccccC")
+	salt := common.HexToHash("0x01")
+
+	// Domain of the example in the EIP-712 specification
+	separator, err := DomainSeparator(EIP712Domain{
+		Name:              "Ether Mail",
+		Version:           "1",
+		ChainId:           big.NewInt(1),
+		VerifyingContract: &contract,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := common.HexToHash("0xf2cee375fa42b42143804025fc449deafd50cc031ca257e0b194a650a912090f"); separator != want {
+		t.Errorf("domain separator mismatch: have %x, want %x", separator, want)
+	}
+	// Absent fields are omitted from the type and the encoding
+	separator, err = DomainSeparator(EIP712Domain{Name: "Ether Mail", Salt: &salt})
+	if err != nil {
+		t.Fatal(err)
+	}
+	typeHash := crypto.Keccak256([]byte("EIP712Domain(string name,bytes32 salt)"))
+	want := crypto.Keccak256Hash(typeHash, crypto.Keccak256([]byte("Ether Mail")), salt.Bytes())
+	if separator != want {
+		t.Errorf("partial domain separator mismatch: have %x, want %x", separator, want)
+	}
+}
@@ -20,12 +20,98 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"strings"
+
+	"github.com/enode/crypto"
 )
 
 var (
 	errBadBool = errors.New("abi: improperly encoded boolean value")
 )
 
+// Kinds of errors returned by the package. Errors carry a detailed message, but
+// can be matched against these using errors.Is.
+var (
+	ErrOverflow     = errors.New("abi: value overflows type")
+	ErrTypeMismatch = errors.New("abi: type mismatch")
+	ErrArity        = errors.New("abi: argument count mismatch")
+	ErrOutOfBounds  = errors.New("abi: data out of bounds")
+)
+
+// kindError is an error with a detailed message of one of the error kinds.
+type kindError struct {
+	kind error
+	msg  string
+}
+
+func (err *kindError) Error() string { return err.msg }
+func (err *kindError) Unwrap() error { return err.kind }
+
+// errorf formats an error of the given kind.
+func errorf(kind error, format string, args ...interface{}) error {
+	return &kindError{kind: kind, msg: fmt.Sprintf(format, args...)}
+}
+
+// hasKind reports whether err is of the given error kind, possibly annotated by
+// fieldError.
+func hasKind(err, kind error) bool {
+	for err != nil {
+		if err == kind {
+			return true
+		}
+		kerr, ok := err.(*kindError)
+		if !ok {
+			return false
+		}
+		err = kerr.kind
+	}
+	return false
+}
+
+// fieldError annotates an error assigning the output arg to the struct field
+// of the given name. The original error, and thereby its kind, stays
+// matchable with errors.Is.
+func fieldError(arg, field string, err error) error {
+	msg := fmt.Sprintf("abi: output %q -> field %s: %s", arg, field, strings.TrimPrefix(err.Error(), "abi: "))
+	return &kindError{kind: err, msg: msg}
+}
+
+// Error is a custom Solidity error, which a contract reverts with to report its
+// failure. The revert data starts with the error's 4 byte ID, followed by the
+// encoded inputs.
+type Error struct {
+	Name   string
+	Inputs Arguments
+}
+
+func (e Error) String() string {
+	inputs := make([]string, len(e.Inputs))
+	for i, input := range e.Inputs {
+		inputs[i] = fmt.Sprintf("%v %v", input.Type, input.Name)
+	}
+	return fmt.Sprintf("error %v(%v)", e.Name, strings.Join(inputs, ", "))
+}
+
+// Sig returns the error string signature according to the ABI spec.
+//
+// Example
+//
+//	error InsufficientBalance(uint256 available, uint256 required) = "InsufficientBalance(uint256,uint256)"
+func (e Error) Sig() string {
+	types := make([]string, len(e.Inputs))
+	for i, input := range e.Inputs {
+		types[i] = input.Type.String()
+	}
+	return fmt.Sprintf("%v(%v)", e.Name, strings.Join(types, ","))
+}
+
+// ID returns the 4 byte selector of the error, the first bytes of the keccak256
+// hash of Sig, which prefixes the revert data.
+func (e Error) ID() (id [4]byte) {
+	copy(id[:], crypto.Keccak256([]byte(e.Sig())))
+	return id
+}
+
 // formatSliceString formats the reflection kind with the given slice size
 // and returns a formatted string representation.
 func formatSliceString(kind reflect.Kind, sliceSize int) string {
@@ -51,10 +137,33 @@ func sliceTypeCheck(t Type, val reflect.Value) error {
 			return sliceTypeCheck(*t.Elem, val.Index(0))
 		}
 	} else if t.Elem.T == ArrayTy {
-		return sliceTypeCheck(*t.Elem, val.Index(0))
+		// every element is checked again when it is packed, the first one is
+		// only inspected here to report mismatches early
+		if val.Len() > 0 {
+			return sliceTypeCheck(*t.Elem, val.Index(0))
+		}
+		return nil
 	}
 
-	if elemKind := val.Type().Elem().Kind(); elemKind != t.Elem.Kind {
+	if t.Elem.T == TupleTy {
+		// tuples may be given in several shapes, each element is checked when
+		// it is packed
+		return nil
+	}
+	elemKind := val.Type().Elem().Kind()
+	if elemKind == reflect.Ptr && t.Elem.Kind != reflect.Ptr {
+		// pointers are dereferenced when the elements are packed
+		elemKind = val.Type().Elem().Elem().Kind()
+	}
+	if t.Elem.T == FixedBytesTy && elemKind == reflect.Slice && val.Type().Elem().Elem().Kind() == reflect.Uint8 {
+		// byte slices are accepted as fixed size byte arrays, their lengths are
+		// validated one by one when packing the elements
+		return nil
+	}
+	if boolIntMismatch(*t.Elem, val.Type().Elem()) {
+		return errorf(ErrTypeMismatch, "abi: cannot pack %v into %v arg", val.Type(), t)
+	}
+	if elemKind != t.Elem.Kind {
 		return typeErr(formatSliceString(t.Elem.Kind, t.Size), val.Type())
 	}
 	return nil
@@ -68,6 +177,20 @@ func typeCheck(t Type, value reflect.Value) error {
 	}
 
 	// Check base type validity. Element types will be checked later on.
+	if t.T == FixedBytesTy && value.Kind() == reflect.Slice && value.Type().Elem().Kind() == reflect.Uint8 {
+		if t.Size != value.Len() {
+			return errorf(ErrTypeMismatch, "abi: cannot use %d bytes as type %v as argument", value.Len(), t)
+		}
+		return nil
+	}
+	// Any array of 20 bytes is accepted as an address, not just common.Address.
+	// Tuple components and array elements are checked when they are packed.
+	if t.T == AddressTy && value.Kind() == reflect.Array && (value.Type().Elem().Kind() != reflect.Uint8 || value.Len() != 20) {
+		return typeErr(t, value.Type())
+	}
+	if value.IsValid() && boolIntMismatch(t, value.Type()) {
+		return errorf(ErrTypeMismatch, "abi: cannot pack %v into %v arg", value.Type(), t)
+	}
 	if t.Kind != value.Kind() {
 		return typeErr(t.Kind, value.Kind())
 	} else if t.T == FixedBytesTy && t.Size != value.Len() {
@@ -78,7 +201,20 @@ func typeCheck(t Type, value reflect.Value) error {
 
 }
 
+// boolIntMismatch reports whether values of the Go type typ are booleans given
+// for the integer type t, or integers given for a bool, which is always a
+// mistake worth a precise error.
+func boolIntMismatch(t Type, typ reflect.Type) bool {
+	switch t.T {
+	case IntTy, UintTy:
+		return typ.Kind() == reflect.Bool
+	case BoolTy:
+		return isSignedKind(typ.Kind()) || isUnsignedKind(typ.Kind()) || typ == bigT
+	}
+	return false
+}
+
 // typeErr returns a formatted type casting error.
 func typeErr(expected, got interface{}) error {
-	return fmt.Errorf("abi: cannot use %v as type %v as argument", got, expected)
+	return errorf(ErrTypeMismatch, "abi: cannot use %v as type %v as argument", got, expected)
 }
@@ -17,13 +17,20 @@
 package abi
 
 import (
+	"errors"
 	"fmt"
+	"reflect"
 	"strings"
 
 	"github.com/enode/common"
+	"github.com/enode/core/types"
 	"github.com/enode/crypto"
 )
 
+// errNoEventSignature is returned when unpacking a log that has no topics, thus
+// not even the event signature.
+var errNoEventSignature = errors.New("no event signature")
+
 // Event is an event potentially triggered by the EVM's LOG mechanism. The Event
 // holds type information (inputs) about the yielded output. Anonymous events
 // don't get the signature canonical representation as the first LOG topic.
@@ -44,14 +51,101 @@ func (e Event) String() string {
 	return fmt.Sprintf("event %v(%v)", e.Name, strings.Join(inputs, ", "))
 }
 
+// Sig returns the event string signature according to the ABI spec.
+//
+// Example
+//
+//	event Transfer(address indexed from, address indexed to, uint256 value) = "Transfer(address,address,uint256)"
+func (e Event) Sig() string {
+	types := make([]string, len(e.Inputs))
+	for i, input := range e.Inputs {
+		types[i] = input.Type.String()
+	}
+	return fmt.Sprintf("%v(%v)", e.Name, strings.Join(types, ","))
+}
+
+// ID returns the canonical representation of the event's signature used by the
+// abi definition to identify event names and types. This is the keccak256 hash
+// of Sig and is emitted as the first log topic of non-anonymous events.
+func (e Event) ID() common.Hash {
+	return common.BytesToHash(crypto.Keccak256([]byte(e.Sig())))
+}
+
 // Id returns the canonical representation of the event's signature used by the
 // abi definition to identify event names and types.
+//
+// Deprecated: use ID instead.
 func (e Event) Id() common.Hash {
-	types := make([]string, len(e.Inputs))
-	i := 0
-	for _, input := range e.Inputs {
-		types[i] = input.Type.String()
-		i++
+	return e.ID()
+}
+
+// IndexedArgs returns the indexed inputs of the event, in the order their
+// values appear in the log topics following the event ID.
+func (e Event) IndexedArgs() Arguments {
+	var ret Arguments
+	for _, arg := range e.Inputs {
+		if arg.Indexed {
+			ret = append(ret, arg)
+		}
+	}
+	return ret
+}
+
+// unpackData unpacks the non-indexed arguments of the event from log data.
+func (e Event) unpackData(opts UnpackOptions, v interface{}, data []byte) error {
+	// log data comes from remote nodes, so make sure that at least the
+	// heads of all non-indexed arguments are present
+	if size := e.Inputs.NonIndexed().headSize(); len(data) < size {
+		return errorf(ErrOutOfBounds, "abi: insufficient data for event %s: have %d bytes, want at least %d", e.Name, len(data), size)
+	}
+	// offsets within the data may still point past its end
+	err := e.Inputs.UnpackWithOptions(opts, v, data)
+	if hasKind(err, ErrOutOfBounds) {
+		return errorf(ErrOutOfBounds, "abi: insufficient data for event %s: %s", e.Name, strings.TrimPrefix(err.Error(), "abi: "))
+	}
+	return err
+}
+
+// UnpackLog unpacks a log of the event into out, which must be a pointer to a
+// struct. Non-indexed arguments are decoded from the log data and indexed ones
+// from the topics following the event signature.
+func (e Event) UnpackLog(out interface{}, log types.Log) error {
+	if len(log.Topics) == 0 {
+		return errNoEventSignature
+	}
+	if len(log.Data) > 0 || e.Inputs.LengthNonIndexed() > 0 {
+		if err := e.unpackData(UnpackOptions{}, out, log.Data); err != nil {
+			return err
+		}
+	}
+	return parseTopics(out, e.IndexedArgs(), log.Topics[1:])
+}
+
+// UnpackLogs unpacks a batch of logs of the event into out, which must be a
+// pointer to a slice of structs or struct pointers. The decoded logs are
+// appended to the slice in order, which is left untouched if any log fails to
+// unpack.
+func (e Event) UnpackLogs(out interface{}, logs []types.Log) error {
+	slice := reflect.ValueOf(out)
+	if slice.Kind() != reflect.Ptr || slice.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("abi: UnpackLogs(non-pointer-to-slice %T)", out)
+	}
+	slice = slice.Elem()
+	elemType := slice.Type().Elem()
+	if elemType.Kind() == reflect.Ptr {
+		elemType = elemType.Elem()
+	}
+	result := slice
+	for i, log := range logs {
+		elem := reflect.New(elemType)
+		if err := e.UnpackLog(elem.Interface(), log); err != nil {
+			return fmt.Errorf("log %d: %v", i, err)
+		}
+		if slice.Type().Elem().Kind() != reflect.Ptr {
+			elem = elem.Elem()
+		}
+		result = reflect.Append(result, elem)
 	}
-	return common.BytesToHash(crypto.Keccak256([]byte(fmt.Sprintf("%v(%v)", e.Name, strings.Join(types, ",")))))
+	slice.Set(result)
+	return nil
 }
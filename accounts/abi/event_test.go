@@ -20,12 +20,15 @@ import (
 	"bytes"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"math/big"
 	"reflect"
 	"strings"
 	"testing"
 
 	"github.com/enode/common"
+	"github.com/enode/core/types"
 	"github.com/enode/crypto"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -111,6 +114,47 @@ func TestEventId(t *testing.T) {
 	}
 }
 
+func TestEventSigAndID(t *testing.T) {
+	var table = []struct {
+		definition string
+		sig        string
+		id         common.Hash
+	}{
+		{
+			`[{ "type" : "event", "name" : "Transfer", "inputs": [{ "name" : "from", "type": "address", "indexed": true }, { "name" : "to", "type": "address", "indexed": true }, { "name" : "value", "type": "uint256" }] }]`,
+			"Transfer(address,address,uint256)",
+			common.HexToHash("0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef"),
+		},
+		{
+			`[{ "type" : "event", "name" : "Approval", "inputs": [{ "name" : "owner", "type": "address", "indexed": true }, { "name" : "spender", "type": "address", "indexed": true }, { "name" : "value", "type": "uint256" }] }]`,
+			"Approval(address,address,uint256)",
+			common.HexToHash("0x8c5be1e5ebec7d5bd14f71427d1e84f3dd0314c0f7b2291e5b200ac8c7c3b925"),
+		},
+		{
+			`[{ "type" : "event", "name" : "Nested", "inputs": [{ "name" : "s", "type": "tuple[2][]", "components": [{ "name" : "a", "type": "uint256" }, { "name" : "b", "type": "bytes32[]" }] }, { "name" : "c", "type": "int8[3]" }] }]`,
+			"Nested((uint256,bytes32[])[2][],int8[3])",
+			crypto.Keccak256Hash([]byte("Nested((uint256,bytes32[])[2][],int8[3])")),
+		},
+	}
+	for i, test := range table {
+		abi, err := JSON(strings.NewReader(test.definition))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, event := range abi.Events {
+			if sig := event.Sig(); sig != test.sig {
+				t.Errorf("test %d: signature mismatch: have %s, want %s", i, sig, test.sig)
+			}
+			if id := event.ID(); id != test.id {
+				t.Errorf("test %d: id mismatch: have %x, want %x", i, id, test.id)
+			}
+			if event.Id() != event.ID() {
+				t.Errorf("test %d: Id and ID disagree: %x != %x", i, event.Id(), event.ID())
+			}
+		}
+	}
+}
+
 func TestEventString(t *testing.T) {
 	var table = []struct {
 		definition   string
@@ -305,7 +349,7 @@ func TestEventTupleUnpack(t *testing.T) {
 		&BadEventPledge{},
 		&BadEventPledge{},
 		jsonEventPledge,
-		"abi: cannot unmarshal common.Address in to string",
+		"abi: output \"who\" -> field Who: cannot unmarshal common.Address in to string",
 		"Can not unpack Pledge event into struct with wrong filed types",
 	}, {
 		pledgeData1,
@@ -426,3 +470,131 @@ func TestEventIndexedWithArrayUnpack(t *testing.T) {
 	require.Equal(t, [2]uint8{0, 0}, rst.Value1)
 	require.Equal(t, stringOut, rst.Value2)
 }
+
+func TestEventUnpackTruncated(t *testing.T) {
+	definition := `[{"name": "test", "type": "event", "inputs": [{"indexed": true, "name":"value1", "type":"uint8"},{"name":"value2", "type":"uint256[2]"},{"name":"value3", "type":"string"}]}]`
+	abi, err := JSON(strings.NewReader(definition))
+	require.NoError(t, err)
+	data, err := abi.Events["test"].Inputs.NonIndexed().Pack([2]*big.Int{big.NewInt(1), big.NewInt(2)}, "hello")
+	require.NoError(t, err)
+
+	var rst struct {
+		Value2 [2]*big.Int
+		Value3 string
+	}
+	require.NoError(t, abi.Unpack(&rst, "test", data))
+	for _, size := range []int{0, 1, 32, 64, 95} {
+		err := abi.Unpack(&rst, "test", data[:size])
+		require.EqualError(t, err, fmt.Sprintf("abi: insufficient data for event test: have %d bytes, want at least 96", size))
+	}
+	// Truncation beyond the heads is still reported as an error, as long as it
+	// cuts into the string itself rather than its padding
+	for size := 96; size < 96+32+len("hello"); size++ {
+		require.Error(t, abi.Unpack(&rst, "test", data[:size]), "size %d", size)
+	}
+	// Offsets pointing past the data are rejected instead of panicking
+	for _, typ := range []string{"string[2]", "bytes[2]"} {
+		abi, err := JSON(strings.NewReader(`[{"name": "test", "type": "event", "inputs": [{"name":"value", "type":"` + typ + `"}]}]`))
+		require.NoError(t, err)
+		for _, offset := range []int64{0xff, 0x1000} {
+			data := append(common.LeftPadBytes(big.NewInt(offset).Bytes(), 32), make([]byte, 64)...)
+			_, err := abi.Events["test"].Inputs.UnpackValues(data)
+			require.Error(t, err, "%s offset %#x", typ, offset)
+			var out struct{ Value interface{} }
+			err = abi.Unpack(&out, "test", data)
+			require.True(t, errors.Is(err, ErrOutOfBounds), "%s offset %#x: %v", typ, offset, err)
+			require.True(t, strings.HasPrefix(err.Error(), "abi: insufficient data for event test: "), "%s offset %#x: %v", typ, offset, err)
+		}
+		// Element offsets and lengths are checked too
+		data := append(common.LeftPadBytes([]byte{0x20}, 32), common.LeftPadBytes([]byte{0x40}, 32)...)
+		data = append(data, common.LeftPadBytes([]byte{0x10, 0x00}, 32)...)
+		data = append(data, common.LeftPadBytes([]byte{0x60}, 32)...)
+		_, err = abi.Events["test"].Inputs.UnpackValues(data)
+		require.Error(t, err, "%s element offset", typ)
+	}
+	// Events without non-indexed arguments accept empty data
+	abi, err = JSON(strings.NewReader(`[{"name": "test", "type": "event", "inputs": [{"indexed": true, "name":"value1", "type":"uint8"}]}]`))
+	require.NoError(t, err)
+	require.NoError(t, abi.Unpack(&rst, "test", nil))
+}
+
+func TestEventTopicAndIndexedArgs(t *testing.T) {
+	abi, err := JSON(strings.NewReader(`[
+		{"type":"event","name":"Transfer","inputs":[{"name":"from","type":"address","indexed":true},{"name":"to","type":"address","indexed":true},{"name":"value","type":"uint256"}]},
+		{"type":"event","name":"Anon","anonymous":true,"inputs":[{"name":"id","type":"uint256","indexed":true}]}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	topic, err := abi.EventTopic("Transfer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := common.HexToHash("0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef"); topic != want {
+		t.Errorf("topic mismatch: have %x, want %x", topic, want)
+	}
+	indexed := abi.Events["Transfer"].IndexedArgs()
+	if len(indexed) != 2 || indexed[0].Name != "from" || indexed[1].Name != "to" {
+		t.Errorf("indexed args mismatch: have %v", indexed)
+	}
+	if _, err := abi.EventTopic("Anon"); err == nil {
+		t.Error("expected error for anonymous event")
+	}
+	if _, err := abi.EventTopic("Missing"); err == nil {
+		t.Error("expected error for unknown event")
+	}
+}
+
+func TestEventUnpackLogs(t *testing.T) {
+	parsed, err := JSON(strings.NewReader(`[
+		{"type":"event","name":"Transfer","inputs":[{"name":"from","type":"address","indexed":true},{"name":"to","type":"address","indexed":true},{"name":"value","type":"uint256"}]}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	event := parsed.Events["Transfer"]
+
+	var logs []types.Log
+	for i := 1; i <= 3; i++ {
+		logs = append(logs, types.Log{
+			Topics: []common.Hash{
+				event.ID(),
+				common.BytesToHash(common.Address{byte(i)}.Bytes()),
+				common.BytesToHash(common.Address{byte(i + 1)}.Bytes()),
+			},
+			Data: common.LeftPadBytes(big.NewInt(int64(i*100)).Bytes(), 32),
+		})
+	}
+	var transfers []struct {
+		From  common.Address
+		To    common.Address
+		Value *big.Int
+	}
+	if err := event.UnpackLogs(&transfers, logs); err != nil {
+		t.Fatal(err)
+	}
+	if len(transfers) != 3 {
+		t.Fatalf("transfer count mismatch: have %d, want 3", len(transfers))
+	}
+	for i, tr := range transfers {
+		if tr.From != (common.Address{byte(i + 1)}) || tr.To != (common.Address{byte(i + 2)}) || tr.Value.Int64() != int64((i+1)*100) {
+			t.Errorf("transfer %d mismatch: have %+v", i, tr)
+		}
+	}
+	// Indexed arguments without a field are reported rather than panicking
+	var partial struct {
+		To    common.Address
+		Value *big.Int
+	}
+	if err := event.UnpackLog(&partial, logs[0]); err == nil || !strings.Contains(err.Error(), "field From") {
+		t.Errorf("error mismatch: have %v, want missing field From", err)
+	}
+	// A log missing its signature topic fails the whole batch
+	logs[2].Topics = nil
+	if err := event.UnpackLogs(&transfers, logs); err == nil || !strings.HasPrefix(err.Error(), "log 2:") {
+		t.Errorf("error mismatch: have %v, want log 2 failure", err)
+	}
+	if len(transfers) != 3 {
+		t.Errorf("failed batch modified output: have %d transfers, want 3", len(transfers))
+	}
+}
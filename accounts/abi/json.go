@@ -0,0 +1,111 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package abi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"reflect"
+
+	"github.com/enode/common"
+	"github.com/enode/common/hexutil"
+)
+
+// UnpackJSON decodes the arguments from data into a JSON object keyed by their
+// names, unnamed ones being called argN. Values are rendered the way web APIs
+// expect them: integers as decimal strings, byte values as 0x prefixed hex and
+// addresses in their checksummed form.
+func (arguments Arguments) UnpackJSON(data []byte) (json.RawMessage, error) {
+	values, err := arguments.UnpackValues(data)
+	if err != nil {
+		return nil, err
+	}
+	var obj jsonObject
+	for i, arg := range arguments.NonIndexed() {
+		name := arg.Name
+		if name == "" {
+			name = fmt.Sprintf("arg%d", i)
+		}
+		obj.keys = append(obj.keys, name)
+		obj.values = append(obj.values, jsonValue(arg.Type, reflect.ValueOf(values[i])))
+	}
+	return json.Marshal(obj)
+}
+
+// jsonObject is a JSON object whose keys are marshalled in order.
+type jsonObject struct {
+	keys   []string
+	values []interface{}
+}
+
+// MarshalJSON implements json.Marshaler.
+func (obj jsonObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range obj.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		v, err := json.Marshal(obj.values[i])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// jsonValue converts the decoded value v of type t into its JSON representation.
+func jsonValue(t Type, v reflect.Value) interface{} {
+	switch t.T {
+	case IntTy, UintTy:
+		return fmt.Sprint(v.Interface())
+	case FixedPointTy:
+		scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(t.Decimals)), nil)
+		return new(big.Rat).SetFrac(v.Interface().(*big.Int), scale).FloatString(t.Decimals)
+	case AddressTy:
+		return v.Interface().(common.Address).Hex()
+	case BytesTy, FixedBytesTy, FunctionTy, HashTy:
+		if v.Kind() == reflect.Array {
+			v = mustArrayToByteSlice(v)
+		}
+		return hexutil.Encode(v.Bytes())
+	case SliceTy, ArrayTy:
+		list := make([]interface{}, v.Len())
+		for i := range list {
+			list[i] = jsonValue(*t.Elem, v.Index(i))
+		}
+		return list
+	case TupleTy:
+		obj := jsonObject{keys: t.TupleRawNames}
+		for i, elem := range t.TupleElems {
+			obj.values = append(obj.values, jsonValue(*elem, v.Field(i)))
+		}
+		return obj
+	default:
+		return v.Interface()
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package abi
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/enode/common"
+)
+
+func TestUnpackJSON(t *testing.T) {
+	abi, err := JSON(strings.NewReader(`[
+		{"type":"function","name":"info","outputs":[
+			{"name":"balance","type":"uint256"},
+			{"name":"owner","type":"address"},
+			{"name":"data","type":"bytes"},
+			{"name":"","type":"bytes4"},
+			{"name":"entries","type":"tuple[]","components":[{"name":"delta","type":"int8"},{"name":"ok","type":"bool"}]}
+		]}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	type entry struct {
+		Delta int8
+		Ok    bool
+	}
+	balance, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	owner := common.HexToAddress("0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed")
+
+	output, err := abi.Methods["info"].Outputs.Pack(balance, owner, []byte{0xde, 0xad}, [4]byte{1, 2, 3, 4}, []entry{{-1, true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := abi.Methods["info"].Outputs.UnpackJSON(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"balance":"123456789012345678901234567890","owner":"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed","data":"0xdead","arg3":"0x01020304","entries":[{"delta":"-1","ok":true}]}`
+	if string(decoded) != want {
+		t.Errorf("json mismatch:\nhave %s\nwant %s", decoded, want)
+	}
+}
@@ -31,13 +31,28 @@ import (
 // network. A method such as `Transact` does require a Tx and thus will
 // be flagged `true`.
 // Input specifies the required input parameters for this gives method.
+//
+// StateMutability is derived from the constant and payable flags for legacy
+// ABIs.
 type Method struct {
-	Name    string
-	Const   bool
-	Inputs  Arguments
-	Outputs Arguments
+	Name            string
+	Const           bool
+	Inputs          Arguments
+	Outputs         Arguments
+	StateMutability StateMutability
 }
 
+// StateMutability tells how a method may interact with the blockchain state, as
+// declared by the stateMutability field of its ABI definition.
+type StateMutability string
+
+const (
+	MutabilityPure       StateMutability = "pure"       // neither reads nor modifies state
+	MutabilityView       StateMutability = "view"       // reads but does not modify state
+	MutabilityNonPayable StateMutability = "nonpayable" // modifies state, rejects ether
+	MutabilityPayable    StateMutability = "payable"    // modifies state, accepts ether
+)
+
 // Sig returns the methods string signature according to the ABI spec.
 //
 // Example
@@ -53,25 +68,73 @@ func (method Method) Sig() string {
 	return fmt.Sprintf("%v(%v)", method.Name, strings.Join(types, ","))
 }
 
+// String returns a readable Solidity like declaration of the method, including
+// the parameter names and the state mutability, e.g.
+//
+//     function transfer(address to, uint256 amount) returns (bool)
+//
+// Use Sig for the canonical form the selector is derived from.
 func (method Method) String() string {
-	inputs := make([]string, len(method.Inputs))
-	for i, input := range method.Inputs {
-		inputs[i] = fmt.Sprintf("%v %v", input.Type, input.Name)
+	decl := fmt.Sprintf("function %v(%v)", method.Name, declareArguments(method.Inputs))
+	mutability := method.StateMutability
+	if mutability == "" && method.Const {
+		mutability = MutabilityView
 	}
-	outputs := make([]string, len(method.Outputs))
-	for i, output := range method.Outputs {
-		outputs[i] = output.Type.String()
-		if len(output.Name) > 0 {
-			outputs[i] += fmt.Sprintf(" %v", output.Name)
-		}
+	if mutability != "" && mutability != MutabilityNonPayable {
+		decl += " " + string(mutability)
+	}
+	if len(method.Outputs) > 0 {
+		decl += fmt.Sprintf(" returns (%v)", declareArguments(method.Outputs))
 	}
-	constant := ""
-	if method.Const {
-		constant = "constant "
+	return decl
+}
+
+// declareArguments lists the types and, if given, names of the arguments as
+// they are declared in Solidity.
+func declareArguments(args Arguments) string {
+	decls := make([]string, len(args))
+	for i, arg := range args {
+		decls[i] = arg.Type.String()
+		if arg.Name != "" {
+			decls[i] += " " + arg.Name
+		}
 	}
-	return fmt.Sprintf("function %v(%v) %sreturns(%v)", method.Name, strings.Join(inputs, ", "), constant, strings.Join(outputs, ", "))
+	return strings.Join(decls, ", ")
+}
+
+// IsConstant returns whether the method does not modify state and can thus be
+// executed with a call instead of a transaction.
+func (method Method) IsConstant() bool {
+	return method.StateMutability == MutabilityView || method.StateMutability == MutabilityPure || method.Const
+}
+
+// IsPayable returns whether the method accepts ether sent along with the call.
+func (method Method) IsPayable() bool {
+	return method.StateMutability == MutabilityPayable
 }
 
 func (method Method) Id() []byte {
 	return crypto.Keccak256([]byte(method.Sig()))[:4]
 }
+
+// Selector returns the 4 byte method selector, the first 4 bytes of the hash of
+// the method's signature. Unlike Id, the result is directly comparable.
+func (method Method) Selector() (selector [4]byte) {
+	copy(selector[:], method.Id())
+	return selector
+}
+
+// PackOutput packs the given values as the return values of the method, as
+// returned by a call to it. Unlike packing the inputs, no selector is prepended.
+func (method Method) PackOutput(values ...interface{}) ([]byte, error) {
+	return method.Outputs.Pack(values...)
+}
+
+// InputTuple returns a synthetic tuple type whose components are the inputs of
+// the method, so they can be given as a single struct or map. Packed as a lone
+// argument, the tuple encodes the same as the inputs one by one if they are all
+// static. Otherwise the tuple itself is dynamic and its encoding is preceded by
+// a one word offset pointing at it.
+func (method Method) InputTuple() (Type, error) {
+	return method.Inputs.tupleType()
+}
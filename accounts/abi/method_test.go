@@ -17,15 +17,22 @@
 package abi
 
 import (
+	"bytes"
+	"math/big"
+	"reflect"
 	"strings"
 	"testing"
+
+	"github.com/enode/common"
 )
 
 const methoddata = `
 [
 	{ "type" : "function", "name" : "balance", "constant" : true },
 	{ "type" : "function", "name" : "send", "constant" : false, "inputs" : [ { "name" : "amount", "type" : "uint256" } ] },
-	{ "type" : "function", "name" : "transfer", "constant" : false, "inputs" : [ { "name" : "from", "type" : "address" }, { "name" : "to", "type" : "address" }, { "name" : "value", "type" : "uint256" } ], "outputs" : [ { "name" : "success", "type" : "bool" } ]  }
+	{ "type" : "function", "name" : "transfer", "constant" : false, "inputs" : [ { "name" : "from", "type" : "address" }, { "name" : "to", "type" : "address" }, { "name" : "value", "type" : "uint256" } ], "outputs" : [ { "name" : "success", "type" : "bool" } ]  },
+	{ "type" : "function", "name" : "deposit", "stateMutability" : "payable", "inputs" : [ { "name" : "", "type" : "uint" }, { "name" : "memo", "type" : "string" } ], "outputs" : [ { "name" : "", "type" : "bool" }, { "name" : "id", "type" : "uint64" } ] },
+	{ "type" : "function", "name" : "hash", "stateMutability" : "pure", "inputs" : [ { "name" : "data", "type" : "tuple", "components" : [ { "name" : "a", "type" : "bytes32" }, { "name" : "b", "type" : "address[]" } ] } ], "outputs" : [ { "name" : "", "type" : "bytes32" } ] }
 ]`
 
 func TestMethodString(t *testing.T) {
@@ -35,15 +42,23 @@ func TestMethodString(t *testing.T) {
 	}{
 		{
 			method:      "balance",
-			expectation: "function balance() constant returns()",
+			expectation: "function balance() view",
 		},
 		{
 			method:      "send",
-			expectation: "function send(uint256 amount) returns()",
+			expectation: "function send(uint256 amount)",
 		},
 		{
 			method:      "transfer",
-			expectation: "function transfer(address from, address to, uint256 value) returns(bool success)",
+			expectation: "function transfer(address from, address to, uint256 value) returns (bool success)",
+		},
+		{
+			method:      "deposit",
+			expectation: "function deposit(uint256, string memo) payable returns (bool, uint64 id)",
+		},
+		{
+			method:      "hash",
+			expectation: "function hash((bytes32,address[]) data) pure returns (bytes32)",
 		},
 	}
 
@@ -59,3 +74,206 @@ func TestMethodString(t *testing.T) {
 		}
 	}
 }
+
+func TestMethodSelector(t *testing.T) {
+	abi, err := JSON(strings.NewReader(methoddata))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, method := range abi.Methods {
+		selector := method.Selector()
+		if !bytes.Equal(selector[:], method.Id()) {
+			t.Errorf("method %s: selector mismatch: have %x, want %x", name, selector, method.Id())
+		}
+	}
+	if selector := abi.Methods["transfer"].Selector(); selector != [4]byte{0xbe, 0xab, 0xac, 0xc8} {
+		t.Errorf("transfer: selector mismatch: have %x, want %x", selector, common.FromHex("0xbeabacc8"))
+	}
+}
+
+func TestMethodInputTuple(t *testing.T) {
+	abi, err := JSON(strings.NewReader(`[
+		{"type":"function","name":"mixed","inputs":[{"name":"to","type":"address"},{"name":"","type":"uint256"},{"name":"memo","type":"string"},{"name":"ids","type":"uint8[2]"}]}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	method := abi.Methods["mixed"]
+	tuple, err := method.InputTuple()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tuple.String() != "(address,uint256,string,uint8[2])" {
+		t.Errorf("tuple string mismatch: have %s, want (address,uint256,string,uint8[2])", tuple)
+	}
+	to, amount, memo, ids := common.Address{1}, big.NewInt(100), "hello", [2]uint8{1, 2}
+
+	want, err := method.Inputs.Pack(to, amount, memo, ids)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The dynamic string makes the tuple dynamic, so it is packed behind an offset
+	want = append(common.LeftPadBytes([]byte{0x20}, 32), want...)
+
+	args := Arguments{{Type: tuple}}
+	packed, err := args.Pack(struct {
+		To   common.Address
+		Arg1 *big.Int
+		Memo string
+		Ids  [2]uint8
+	}{to, amount, memo, ids})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(packed, want) {
+		t.Errorf("struct pack mismatch: have %x, want %x", packed, want)
+	}
+	packed, err = args.Pack(map[string]interface{}{"to": to, "arg1": amount, "memo": memo, "ids": ids})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(packed, want) {
+		t.Errorf("map pack mismatch: have %x, want %x", packed, want)
+	}
+	// Static inputs pack exactly as the tuple does
+	static := Method{Inputs: method.Inputs[:2]}
+	want, err = static.Inputs.Pack(to, amount)
+	if err != nil {
+		t.Fatal(err)
+	}
+	staticTuple, err := static.InputTuple()
+	if err != nil {
+		t.Fatal(err)
+	}
+	packed, err = Arguments{{Type: staticTuple}}.Pack(map[string]interface{}{"to": to, "arg1": amount})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(packed, want) {
+		t.Errorf("static pack mismatch: have %x, want %x", packed, want)
+	}
+}
+
+func TestMethodInputTupleNames(t *testing.T) {
+	uint256, _ := NewType("uint256", nil)
+	for _, test := range []struct {
+		inputs []string
+		fields []string
+	}{
+		{[]string{"arg1", ""}, []string{"Arg1", "Arg2"}},
+		{[]string{"", "arg0", "arg2", ""}, []string{"Arg0", "Arg1", "Arg2", "Arg3"}},
+		{[]string{"$x", "1st", "to-do", "_"}, []string{"Arg0", "Arg1", "Arg2", "Arg3"}},
+		{[]string{"a", "A", "b_c", "bC"}, []string{"A", "Arg1", "BC", "Arg3"}},
+	} {
+		var method Method
+		for _, name := range test.inputs {
+			method.Inputs = append(method.Inputs, Argument{Name: name, Type: uint256})
+		}
+		tuple, err := method.InputTuple()
+		if err != nil {
+			t.Fatalf("%q: %v", test.inputs, err)
+		}
+		for i, want := range test.fields {
+			if have := tuple.Type.Field(i).Name; have != want {
+				t.Errorf("%q: field %d mismatch: have %s, want %s", test.inputs, i, have, want)
+			}
+		}
+	}
+	if _, err := (Method{Inputs: Arguments{{Name: "x"}}}).InputTuple(); err == nil {
+		t.Error("expected error for argument without type")
+	}
+}
+
+func TestMethodStateMutability(t *testing.T) {
+	abi, err := JSON(strings.NewReader(`[
+		{"type":"function","name":"get","stateMutability":"view"},
+		{"type":"function","name":"hash","stateMutability":"pure"},
+		{"type":"function","name":"deposit","stateMutability":"payable"},
+		{"type":"function","name":"set","stateMutability":"nonpayable"},
+		{"type":"function","name":"legacyGet","constant":true},
+		{"type":"function","name":"legacyDeposit","constant":false,"payable":true},
+		{"type":"function","name":"legacySet","constant":false}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var table = []struct {
+		method     string
+		mutability StateMutability
+		constant   bool
+		payable    bool
+	}{
+		{"get", MutabilityView, true, false},
+		{"hash", MutabilityPure, true, false},
+		{"deposit", MutabilityPayable, false, true},
+		{"set", MutabilityNonPayable, false, false},
+		{"legacyGet", MutabilityView, true, false},
+		{"legacyDeposit", MutabilityPayable, false, true},
+		{"legacySet", MutabilityNonPayable, false, false},
+	}
+	for _, test := range table {
+		method := abi.Methods[test.method]
+		if method.StateMutability != test.mutability {
+			t.Errorf("%s: state mutability mismatch: have %q, want %q", test.method, method.StateMutability, test.mutability)
+		}
+		if method.IsConstant() != test.constant || method.Const != test.constant {
+			t.Errorf("%s: constant mismatch: have %v, want %v", test.method, method.IsConstant(), test.constant)
+		}
+		if method.IsPayable() != test.payable {
+			t.Errorf("%s: payable mismatch: have %v, want %v", test.method, method.IsPayable(), test.payable)
+		}
+	}
+}
+
+// BenchmarkMethodIdParallel computes selectors and topics from many goroutines
+// at once. Run it with -race to check that doing so is safe.
+func BenchmarkMethodIdParallel(b *testing.B) {
+	abi, err := JSON(strings.NewReader(methoddata))
+	if err != nil {
+		b.Fatal(err)
+	}
+	event := Event{Name: "Transfer", Inputs: abi.Methods["transfer"].Inputs}
+	method := abi.Methods["transfer"]
+
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			method.Id()
+			method.Selector()
+			event.ID()
+		}
+	})
+}
+
+func TestMethodPackOutput(t *testing.T) {
+	abi, err := JSON(strings.NewReader(`[
+		{"type":"function","name":"info","outputs":[{"name":"owner","type":"address"},{"name":"balance","type":"uint256"},{"name":"tags","type":"string[]"}]}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	method := abi.Methods["info"]
+	owner, balance, tags := common.Address{1}, big.NewInt(1000), []string{"a", "bc"}
+
+	output, err := method.PackOutput(owner, balance, tags)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(output)%32 != 0 {
+		t.Fatalf("output not word aligned, selector prepended? %x", output)
+	}
+	var decoded struct {
+		Owner   common.Address
+		Balance *big.Int
+		Tags    []string
+	}
+	if err := abi.Unpack(&decoded, "info", output); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Owner != owner || decoded.Balance.Cmp(balance) != 0 || !reflect.DeepEqual(decoded.Tags, tags) {
+		t.Errorf("output mismatch: have %+v", decoded)
+	}
+	if _, err := method.PackOutput(owner, balance); err == nil {
+		t.Error("expected error for missing output value")
+	}
+}
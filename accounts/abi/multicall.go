@@ -0,0 +1,39 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package abi
+
+import (
+	"github.com/enode/common"
+)
+
+// Call3 is a single call of a batch, matching the (address target, bytes callData)
+// tuple taken by Multicall's aggregate method. A slice of calls can be packed
+// directly as the calls argument of aggregate.
+type Call3 struct {
+	Target   common.Address
+	CallData []byte
+}
+
+// PackCall packs the invocation of method with the given arguments and pairs
+// it with the address of the contract it should be sent to.
+func PackCall(addr common.Address, abi ABI, method string, args ...interface{}) (Call3, error) {
+	data, err := abi.Pack(method, args...)
+	if err != nil {
+		return Call3{}, err
+	}
+	return Call3{Target: addr, CallData: data}, nil
+}
@@ -17,8 +17,10 @@
 package abi
 
 import (
+	"encoding/json"
 	"math/big"
 	"reflect"
+	"time"
 
 	"github.com/enode/common"
 	"github.com/enode/common/math"
@@ -27,6 +29,7 @@ import (
 var (
 	bigT      = reflect.TypeOf(&big.Int{})
 	derefbigT = reflect.TypeOf(big.Int{})
+	derefratT = reflect.TypeOf(big.Rat{})
 	uint8T    = reflect.TypeOf(uint8(0))
 	uint16T   = reflect.TypeOf(uint16(0))
 	uint32T   = reflect.TypeOf(uint32(0))
@@ -36,6 +39,12 @@ var (
 	int32T    = reflect.TypeOf(int32(0))
 	int64T    = reflect.TypeOf(int64(0))
 	addressT  = reflect.TypeOf(common.Address{})
+	hashT     = reflect.TypeOf(common.Hash{})
+	durationT = reflect.TypeOf(time.Duration(0))
+	timeT     = reflect.TypeOf(time.Time{})
+
+	mixedcaseAddressT = reflect.TypeOf(common.MixedcaseAddress{})
+	rawMessageT       = reflect.TypeOf(json.RawMessage(nil))
 )
 
 // U256 converts a big Int into a 256bit EVM number.
@@ -17,13 +17,120 @@
 package abi
 
 import (
+	"encoding/json"
+	"fmt"
+	gmath "math"
 	"math/big"
 	"reflect"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/enode/common"
 	"github.com/enode/common/math"
 )
 
+// PackOptions tweaks how Go values are converted into their ABI encoding. The
+// zero value corresponds to the strict behaviour of Pack.
+type PackOptions struct {
+	// NilBigIntAsZero packs nil *big.Int values as zero instead of rejecting them.
+	NilBigIntAsZero bool
+
+	// DurationsAsSeconds packs time.Duration values into integer types as their
+	// number of seconds instead of nanoseconds. Durations with a sub-second
+	// remainder are rejected unless TruncateDurations is also set.
+	DurationsAsSeconds bool
+
+	// TruncateDurations packs time.Duration values with a sub-second remainder
+	// as their whole seconds instead of rejecting them, if DurationsAsSeconds
+	// is set.
+	TruncateDurations bool
+
+	// WrapUnsigned reinterprets unsigned Go integers packed into signed types
+	// as two's complement values of the type's width, so uint64(math.MaxUint64)
+	// packs as int64(-1). By default such values are only accepted if they fit
+	// into the signed type.
+	WrapUnsigned bool
+
+	// MaxDynamicLength limits the number of bytes of each dynamic bytes or
+	// string value, longer values being rejected. Zero means unlimited.
+	MaxDynamicLength int
+
+	// StrictUTF8 rejects string values that aren't valid UTF-8. By default
+	// strings are packed as the arbitrary bytes they hold.
+	StrictUTF8 bool
+}
+
+// ValueProvider is implemented by arguments whose value is computed lazily. The
+// value is resolved when the argument is packed, errors aborting the packing.
+type ValueProvider interface {
+	ABIValue() (interface{}, error)
+}
+
+var valueProviderT = reflect.TypeOf((*ValueProvider)(nil)).Elem()
+
+// resolveValue returns the value of v to be packed, asking value providers for
+// theirs until a plain value is obtained.
+func resolveValue(v reflect.Value) (reflect.Value, error) {
+	for v.IsValid() && v.Type().Implements(valueProviderT) && v.CanInterface() {
+		if (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && v.IsNil() {
+			break
+		}
+		value, err := v.Interface().(ValueProvider).ABIValue()
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("abi: failed to resolve %v value: %v", v.Type(), err)
+		}
+		v = reflect.ValueOf(value)
+	}
+	return v, nil
+}
+
+var stringerT = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+
+// stringerValue returns the String() text of a fmt.Stringer given for a string
+// or bytes argument of type t. Strings, byte slices and byte arrays are packed
+// as they are, so that a common.Hash is still rejected as bytes rather than
+// silently packed as its hex text.
+func stringerValue(t Type, v reflect.Value) reflect.Value {
+	if (t.T != StringTy && t.T != BytesTy) || !v.IsValid() || !v.Type().Implements(stringerT) || !v.CanInterface() {
+		return v
+	}
+	if (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && v.IsNil() {
+		return v
+	}
+	switch elem := indirect(v); {
+	case elem.Kind() == reflect.String:
+		return v
+	case (elem.Kind() == reflect.Slice || elem.Kind() == reflect.Array) && elem.Type().Elem().Kind() == reflect.Uint8:
+		return v
+	}
+	text := v.Interface().(fmt.Stringer).String()
+	if t.T == BytesTy {
+		return reflect.ValueOf([]byte(text))
+	}
+	return reflect.ValueOf(text)
+}
+
+// RawEncoded is an already ABI encoded value. It is inserted verbatim wherever
+// it is packed as an argument or tuple component, the offsets of dynamic values
+// being set up as for any other value of the type. This is an escape hatch for
+// splicing in encodings produced elsewhere, their content is not validated.
+type RawEncoded []byte
+
+var rawEncodedT = reflect.TypeOf(RawEncoded(nil))
+
+// packRawEncoded checks that the raw encoding can stand for a value of type t
+// and returns it unchanged.
+func packRawEncoded(t Type, raw []byte) ([]byte, error) {
+	if len(raw)%32 != 0 {
+		return nil, fmt.Errorf("abi: raw encoding of %v has %d bytes, want a multiple of 32", t, len(raw))
+	}
+	if !isDynamicType(t) && len(raw) != getTypeSize(t) {
+		return nil, fmt.Errorf("abi: raw encoding of %v has %d bytes, want %d", t, len(raw), getTypeSize(t))
+	}
+	return raw, nil
+}
+
 // packBytesSlice packs the given bytes as [L, V] as the canonical representation
 // bytes slice
 func packBytesSlice(bytes []byte, l int) []byte {
@@ -35,7 +142,7 @@ func packBytesSlice(bytes []byte, l int) []byte {
 // t.
 func packElement(t Type, reflectValue reflect.Value) []byte {
 	switch t.T {
-	case IntTy, UintTy:
+	case IntTy, UintTy, FixedPointTy:
 		return packNum(reflectValue)
 	case StringTy:
 		return packBytesSlice([]byte(reflectValue.String()), reflectValue.Len())
@@ -79,3 +186,179 @@ func packNum(value reflect.Value) []byte {
 	}
 
 }
+
+// packNumString parses the given decimal or 0x prefixed hexadecimal string and
+// packs it as an integer of type t, making sure the value fits into its width.
+func packNumString(t Type, s string) ([]byte, error) {
+	num, err := parseNumString(s)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkIntRange(t, num); err != nil {
+		return nil, err
+	}
+	return U256(num), nil
+}
+
+// packNumJSON packs the JSON encoded number, given either as a plain number or as
+// a decimal or hexadecimal string, as an integer of type t.
+func packNumJSON(t Type, raw json.RawMessage) ([]byte, error) {
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		var num json.Number
+		if err := json.Unmarshal(raw, &num); err != nil {
+			return nil, fmt.Errorf("abi: invalid JSON number %s for %v arg", raw, t)
+		}
+		s = num.String()
+	}
+	return packNumString(t, s)
+}
+
+// packUnsignedAsSigned packs the unsigned Go integer u into the signed integer
+// type t. Values not fitting into t are rejected, unless wrap is set and they
+// fit into t's width when reinterpreted as two's complement.
+func packUnsignedAsSigned(t Type, u uint64, wrap bool) ([]byte, error) {
+	num := new(big.Int).SetUint64(u)
+	if wrap && num.BitLen() == t.Size {
+		num.Sub(num, new(big.Int).Lsh(common.Big1, uint(t.Size)))
+	}
+	if err := checkIntRange(t, num); err != nil {
+		return nil, err
+	}
+	return U256(num), nil
+}
+
+// packMixedcaseAddress packs the address after verifying its EIP-55 checksum.
+// Addresses given in all lower or upper case carry no checksum and are accepted
+// as they are.
+func packMixedcaseAddress(addr common.MixedcaseAddress) ([]byte, error) {
+	if err := checkAddressChecksum(addr); err != nil {
+		return nil, err
+	}
+	return common.LeftPadBytes(addr.Address().Bytes(), 32), nil
+}
+
+// checkAddressChecksum verifies the EIP-55 checksum of a mixed case address.
+func checkAddressChecksum(addr common.MixedcaseAddress) error {
+	hex := strings.TrimPrefix(strings.TrimPrefix(addr.Original(), "0x"), "0X")
+	if !addr.ValidChecksum() && hex != strings.ToLower(hex) && hex != strings.ToUpper(hex) {
+		return fmt.Errorf("abi: invalid checksum in address %s", addr.Original())
+	}
+	return nil
+}
+
+// parseAddressStrings converts a slice or array of hex strings into addresses,
+// verifying the checksums of mixed case ones like packMixedcaseAddress.
+func parseAddressStrings(v reflect.Value) ([]common.Address, error) {
+	addrs := make([]common.Address, v.Len())
+	for i := range addrs {
+		s := v.Index(i).String()
+		addr, err := common.NewMixedcaseAddressFromString(s)
+		if err != nil {
+			return nil, fmt.Errorf("abi: invalid address %q at index %d", s, i)
+		}
+		if err := checkAddressChecksum(*addr); err != nil {
+			return nil, fmt.Errorf("%v at index %d", err, i)
+		}
+		addrs[i] = addr.Address()
+	}
+	return addrs, nil
+}
+
+// packDuration packs the given duration into the integer type t, as its number
+// of nanoseconds or, with DurationsAsSeconds, of seconds.
+func packDuration(t Type, d time.Duration, opts PackOptions) ([]byte, error) {
+	num := big.NewInt(int64(d))
+	if opts.DurationsAsSeconds {
+		if d%time.Second != 0 && !opts.TruncateDurations {
+			return nil, fmt.Errorf("abi: duration %v is not a whole number of seconds", d)
+		}
+		num.SetInt64(int64(d / time.Second))
+	}
+	if err := checkIntRange(t, num); err != nil {
+		return nil, err
+	}
+	return U256(num), nil
+}
+
+// parseNumString converts a decimal or 0x prefixed hexadecimal string, with an
+// optional leading sign, into a big integer.
+func parseNumString(s string) (*big.Int, error) {
+	digits, neg := s, false
+	if strings.HasPrefix(digits, "-") {
+		digits, neg = digits[1:], true
+	} else if strings.HasPrefix(digits, "+") {
+		digits = digits[1:]
+	}
+	base := 10
+	if strings.HasPrefix(digits, "0x") || strings.HasPrefix(digits, "0X") {
+		digits, base = digits[2:], 16
+	}
+	// SetString would happily accept a second sign, reject it explicitly
+	if digits == "" || digits[0] == '-' || digits[0] == '+' {
+		return nil, fmt.Errorf("abi: invalid number string %q", s)
+	}
+	num, ok := new(big.Int).SetString(digits, base)
+	if !ok {
+		return nil, fmt.Errorf("abi: invalid number string %q", s)
+	}
+	if neg {
+		num.Neg(num)
+	}
+	return num, nil
+}
+
+// checkIntRange verifies that num can be represented by the integer or fixed
+// point type t.
+func checkIntRange(t Type, num *big.Int) error {
+	if t.unsigned() {
+		if num.Sign() < 0 || num.BitLen() > t.Size {
+			return errorf(ErrOverflow, "abi: %v overflows %v", num, t)
+		}
+		return nil
+	}
+	max := new(big.Int).Lsh(common.Big1, uint(t.Size-1))
+	min := new(big.Int).Neg(max)
+	if num.Cmp(min) < 0 || num.Cmp(max) >= 0 {
+		return errorf(ErrOverflow, "abi: %v overflows %v", num, t)
+	}
+	return nil
+}
+
+// packFixedPoint packs a big.Rat or floating point value as the fixed point type
+// t, scaling it by 10^t.Decimals. Values that cannot be represented exactly with
+// the type's decimals are rejected instead of being rounded.
+//
+// Floats are converted through their shortest decimal representation, so 0.1 is
+// packed as exactly 0.1. Keep in mind however that an IEEE-754 float64 is only
+// precise to about 15 significant decimal digits, so larger or more precise
+// values should be given as a *big.Rat (or the raw scaled *big.Int) instead.
+func packFixedPoint(t Type, v reflect.Value) ([]byte, error) {
+	var rat *big.Rat
+	switch v.Kind() {
+	case reflect.Float32, reflect.Float64:
+		f := v.Float()
+		if gmath.IsNaN(f) || gmath.IsInf(f, 0) {
+			return nil, fmt.Errorf("abi: cannot use %v as type %v", f, t)
+		}
+		rat, _ = new(big.Rat).SetString(strconv.FormatFloat(f, 'f', -1, v.Type().Bits()))
+	case reflect.Struct:
+		if v.Type() != derefratT {
+			return nil, typeErr(t, v.Type())
+		}
+		r := v.Interface().(big.Rat)
+		rat = &r
+	default:
+		return nil, typeErr(t, v.Type())
+	}
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(t.Decimals)), nil)
+	scaled := new(big.Rat).Mul(rat, new(big.Rat).SetInt(scale))
+	if !scaled.IsInt() {
+		return nil, fmt.Errorf("abi: %v cannot be represented exactly as %v", rat.RatString(), t)
+	}
+	num := scaled.Num()
+	if err := checkIntRange(t, num); err != nil {
+		return nil, err
+	}
+	return U256(num), nil
+}
@@ -18,601 +18,680 @@ package abi
 
 import (
 	"bytes"
+	"encoding/json"
+	"errors"
 	"math"
 	"math/big"
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/enode/common"
 )
 
-func TestPack(t *testing.T) {
-	for i, test := range []struct {
-		typ        string
-		components []ArgumentMarshaling
-		input      interface{}
-		output     []byte
-	}{
-		{
-			"uint8",
-			nil,
-			uint8(2),
-			common.Hex2Bytes("0000000000000000000000000000000000000000000000000000000000000002"),
-		},
-		{
-			"uint8[]",
-			nil,
-			[]uint8{1, 2},
-			common.Hex2Bytes("000000000000000000000000000000000000000000000000000000000000000200000000000000000000000000000000000000000000000000000000000000010000000000000000000000000000000000000000000000000000000000000002"),
-		},
-		{
-			"uint16",
-			nil,
-			uint16(2),
-			common.Hex2Bytes("0000000000000000000000000000000000000000000000000000000000000002"),
-		},
-		{
-			"uint16[]",
-			nil,
-			[]uint16{1, 2},
-			common.Hex2Bytes("000000000000000000000000000000000000000000000000000000000000000200000000000000000000000000000000000000000000000000000000000000010000000000000000000000000000000000000000000000000000000000000002"),
-		},
-		{
-			"uint32",
-			nil,
-			uint32(2),
-			common.Hex2Bytes("0000000000000000000000000000000000000000000000000000000000000002"),
-		},
-		{
-			"uint32[]",
-			nil,
-			[]uint32{1, 2},
-			common.Hex2Bytes("000000000000000000000000000000000000000000000000000000000000000200000000000000000000000000000000000000000000000000000000000000010000000000000000000000000000000000000000000000000000000000000002"),
-		},
-		{
-			"uint64",
-			nil,
-			uint64(2),
-			common.Hex2Bytes("0000000000000000000000000000000000000000000000000000000000000002"),
-		},
-		{
-			"uint64[]",
-			nil,
-			[]uint64{1, 2},
-			common.Hex2Bytes("000000000000000000000000000000000000000000000000000000000000000200000000000000000000000000000000000000000000000000000000000000010000000000000000000000000000000000000000000000000000000000000002"),
-		},
-		{
-			"uint256",
-			nil,
-			big.NewInt(2),
-			common.Hex2Bytes("0000000000000000000000000000000000000000000000000000000000000002"),
-		},
-		{
-			"uint256[]",
-			nil,
-			[]*big.Int{big.NewInt(1), big.NewInt(2)},
-			common.Hex2Bytes("000000000000000000000000000000000000000000000000000000000000000200000000000000000000000000000000000000000000000000000000000000010000000000000000000000000000000000000000000000000000000000000002"),
-		},
-		{
-			"int8",
-			nil,
-			int8(2),
-			common.Hex2Bytes("0000000000000000000000000000000000000000000000000000000000000002"),
-		},
-		{
-			"int8[]",
-			nil,
-			[]int8{1, 2},
-			common.Hex2Bytes("000000000000000000000000000000000000000000000000000000000000000200000000000000000000000000000000000000000000000000000000000000010000000000000000000000000000000000000000000000000000000000000002"),
-		},
-		{
-			"int16",
-			nil,
-			int16(2),
-			common.Hex2Bytes("0000000000000000000000000000000000000000000000000000000000000002"),
-		},
-		{
-			"int16[]",
-			nil,
-			[]int16{1, 2},
-			common.Hex2Bytes("000000000000000000000000000000000000000000000000000000000000000200000000000000000000000000000000000000000000000000000000000000010000000000000000000000000000000000000000000000000000000000000002"),
-		},
-		{
-			"int32",
-			nil,
-			int32(2),
-			common.Hex2Bytes("0000000000000000000000000000000000000000000000000000000000000002"),
-		},
-		{
-			"int32[]",
-			nil,
-			[]int32{1, 2},
-			common.Hex2Bytes("000000000000000000000000000000000000000000000000000000000000000200000000000000000000000000000000000000000000000000000000000000010000000000000000000000000000000000000000000000000000000000000002"),
-		},
-		{
-			"int64",
-			nil,
-			int64(2),
-			common.Hex2Bytes("0000000000000000000000000000000000000000000000000000000000000002"),
-		},
-		{
-			"int64[]",
-			nil,
-			[]int64{1, 2},
-			common.Hex2Bytes("000000000000000000000000000000000000000000000000000000000000000200000000000000000000000000000000000000000000000000000000000000010000000000000000000000000000000000000000000000000000000000000002"),
-		},
-		{
-			"int256",
-			nil,
-			big.NewInt(2),
-			common.Hex2Bytes("0000000000000000000000000000000000000000000000000000000000000002"),
-		},
-		{
-			"int256[]",
-			nil,
-			[]*big.Int{big.NewInt(1), big.NewInt(2)},
-			common.Hex2Bytes("000000000000000000000000000000000000000000000000000000000000000200000000000000000000000000000000000000000000000000000000000000010000000000000000000000000000000000000000000000000000000000000002"),
-		},
-		{
-			"bytes1",
-			nil,
-			[1]byte{1},
-			common.Hex2Bytes("0100000000000000000000000000000000000000000000000000000000000000"),
-		},
-		{
-			"bytes2",
-			nil,
-			[2]byte{1},
-			common.Hex2Bytes("0100000000000000000000000000000000000000000000000000000000000000"),
-		},
-		{
-			"bytes3",
-			nil,
-			[3]byte{1},
-			common.Hex2Bytes("0100000000000000000000000000000000000000000000000000000000000000"),
-		},
-		{
-			"bytes4",
-			nil,
-			[4]byte{1},
-			common.Hex2Bytes("0100000000000000000000000000000000000000000000000000000000000000"),
-		},
-		{
-			"bytes5",
-			nil,
-			[5]byte{1},
-			common.Hex2Bytes("0100000000000000000000000000000000000000000000000000000000000000"),
-		},
-		{
-			"bytes6",
-			nil,
-			[6]byte{1},
-			common.Hex2Bytes("0100000000000000000000000000000000000000000000000000000000000000"),
-		},
-		{
-			"bytes7",
-			nil,
-			[7]byte{1},
-			common.Hex2Bytes("0100000000000000000000000000000000000000000000000000000000000000"),
-		},
-		{
-			"bytes8",
-			nil,
-			[8]byte{1},
-			common.Hex2Bytes("0100000000000000000000000000000000000000000000000000000000000000"),
-		},
-		{
-			"bytes9",
-			nil,
-			[9]byte{1},
-			common.Hex2Bytes("0100000000000000000000000000000000000000000000000000000000000000"),
-		},
-		{
-			"bytes10",
-			nil,
-			[10]byte{1},
-			common.Hex2Bytes("0100000000000000000000000000000000000000000000000000000000000000"),
-		},
-		{
-			"bytes11",
-			nil,
-			[11]byte{1},
-			common.Hex2Bytes("0100000000000000000000000000000000000000000000000000000000000000"),
-		},
-		{
-			"bytes12",
-			nil,
-			[12]byte{1},
-			common.Hex2Bytes("0100000000000000000000000000000000000000000000000000000000000000"),
-		},
-		{
-			"bytes13",
-			nil,
-			[13]byte{1},
-			common.Hex2Bytes("0100000000000000000000000000000000000000000000000000000000000000"),
-		},
-		{
-			"bytes14",
-			nil,
-			[14]byte{1},
-			common.Hex2Bytes("0100000000000000000000000000000000000000000000000000000000000000"),
-		},
-		{
-			"bytes15",
-			nil,
-			[15]byte{1},
-			common.Hex2Bytes("0100000000000000000000000000000000000000000000000000000000000000"),
-		},
-		{
-			"bytes16",
-			nil,
-			[16]byte{1},
-			common.Hex2Bytes("0100000000000000000000000000000000000000000000000000000000000000"),
-		},
-		{
-			"bytes17",
-			nil,
-			[17]byte{1},
-			common.Hex2Bytes("0100000000000000000000000000000000000000000000000000000000000000"),
-		},
-		{
-			"bytes18",
-			nil,
-			[18]byte{1},
-			common.Hex2Bytes("0100000000000000000000000000000000000000000000000000000000000000"),
-		},
-		{
-			"bytes19",
-			nil,
-			[19]byte{1},
-			common.Hex2Bytes("0100000000000000000000000000000000000000000000000000000000000000"),
-		},
-		{
-			"bytes20",
-			nil,
-			[20]byte{1},
-			common.Hex2Bytes("0100000000000000000000000000000000000000000000000000000000000000"),
-		},
-		{
-			"bytes21",
-			nil,
-			[21]byte{1},
-			common.Hex2Bytes("0100000000000000000000000000000000000000000000000000000000000000"),
-		},
-		{
-			"bytes22",
-			nil,
-			[22]byte{1},
-			common.Hex2Bytes("0100000000000000000000000000000000000000000000000000000000000000"),
-		},
-		{
-			"bytes23",
-			nil,
-			[23]byte{1},
-			common.Hex2Bytes("0100000000000000000000000000000000000000000000000000000000000000"),
-		},
-		{
-			"bytes24",
-			nil,
-			[24]byte{1},
-			common.Hex2Bytes("0100000000000000000000000000000000000000000000000000000000000000"),
-		},
-		{
-			"bytes25",
-			nil,
-			[25]byte{1},
-			common.Hex2Bytes("0100000000000000000000000000000000000000000000000000000000000000"),
-		},
-		{
-			"bytes26",
-			nil,
-			[26]byte{1},
-			common.Hex2Bytes("0100000000000000000000000000000000000000000000000000000000000000"),
-		},
-		{
-			"bytes27",
-			nil,
-			[27]byte{1},
-			common.Hex2Bytes("0100000000000000000000000000000000000000000000000000000000000000"),
-		},
-		{
-			"bytes28",
-			nil,
-			[28]byte{1},
-			common.Hex2Bytes("0100000000000000000000000000000000000000000000000000000000000000"),
-		},
-		{
-			"bytes29",
-			nil,
-			[29]byte{1},
-			common.Hex2Bytes("0100000000000000000000000000000000000000000000000000000000000000"),
-		},
-		{
-			"bytes30",
-			nil,
-			[30]byte{1},
-			common.Hex2Bytes("0100000000000000000000000000000000000000000000000000000000000000"),
-		},
-		{
-			"bytes31",
-			nil,
-			[31]byte{1},
-			common.Hex2Bytes("0100000000000000000000000000000000000000000000000000000000000000"),
-		},
-		{
-			"bytes32",
-			nil,
-			[32]byte{1},
-			common.Hex2Bytes("0100000000000000000000000000000000000000000000000000000000000000"),
-		},
-		{
-			"uint32[2][3][4]",
-			nil,
-			[4][3][2]uint32{{{1, 2}, {3, 4}, {5, 6}}, {{7, 8}, {9, 10}, {11, 12}}, {{13, 14}, {15, 16}, {17, 18}}, {{19, 20}, {21, 22}, {23, 24}}},
-			common.Hex2Bytes("000000000000000000000000000000000000000000000000000000000000000100000000000000000000000000000000000000000000000000000000000000020000000000000000000000000000000000000000000000000000000000000003000000000000000000000000000000000000000000000000000000000000000400000000000000000000000000000000000000000000000000000000000000050000000000000000000000000000000000000000000000000000000000000006000000000000000000000000000000000000000000000000000000000000000700000000000000000000000000000000000000000000000000000000000000080000000000000000000000000000000000000000000000000000000000000009000000000000000000000000000000000000000000000000000000000000000a000000000000000000000000000000000000000000000000000000000000000b000000000000000000000000000000000000000000000000000000000000000c000000000000000000000000000000000000000000000000000000000000000d000000000000000000000000000000000000000000000000000000000000000e000000000000000000000000000000000000000000000000000000000000000f000000000000000000000000000000000000000000000000000000000000001000000000000000000000000000000000000000000000000000000000000000110000000000000000000000000000000000000000000000000000000000000012000000000000000000000000000000000000000000000000000000000000001300000000000000000000000000000000000000000000000000000000000000140000000000000000000000000000000000000000000000000000000000000015000000000000000000000000000000000000000000000000000000000000001600000000000000000000000000000000000000000000000000000000000000170000000000000000000000000000000000000000000000000000000000000018"),
-		},
-		{
-			"address[]",
-			nil,
-			[]common.Address{{1}, {2}},
-			common.Hex2Bytes("000000000000000000000000000000000000000000000000000000000000000200000000000000000000000001000000000000000000000000000000000000000000000000000000000000000200000000000000000000000000000000000000"),
-		},
-		{
-			"bytes32[]",
-			nil,
-			[]common.Hash{{1}, {2}},
-			common.Hex2Bytes("000000000000000000000000000000000000000000000000000000000000000201000000000000000000000000000000000000000000000000000000000000000200000000000000000000000000000000000000000000000000000000000000"),
-		},
-		{
-			"function",
-			nil,
-			[24]byte{1},
-			common.Hex2Bytes("0100000000000000000000000000000000000000000000000000000000000000"),
-		},
-		{
-			"string",
-			nil,
-			"foobar",
-			common.Hex2Bytes("0000000000000000000000000000000000000000000000000000000000000006666f6f6261720000000000000000000000000000000000000000000000000000"),
-		},
-		{
-			"string[]",
-			nil,
-			[]string{"hello", "foobar"},
-			common.Hex2Bytes("0000000000000000000000000000000000000000000000000000000000000002" + // len(array) = 2
-				"0000000000000000000000000000000000000000000000000000000000000040" + // offset 64 to i = 0
-				"0000000000000000000000000000000000000000000000000000000000000080" + // offset 128 to i = 1
-				"0000000000000000000000000000000000000000000000000000000000000005" + // len(str[0]) = 5
-				"68656c6c6f000000000000000000000000000000000000000000000000000000" + // str[0]
-				"0000000000000000000000000000000000000000000000000000000000000006" + // len(str[1]) = 6
-				"666f6f6261720000000000000000000000000000000000000000000000000000"), // str[1]
-		},
-		{
-			"string[2]",
-			nil,
-			[]string{"hello", "foobar"},
-			common.Hex2Bytes("0000000000000000000000000000000000000000000000000000000000000040" + // offset to i = 0
-				"0000000000000000000000000000000000000000000000000000000000000080" + // offset to i = 1
-				"0000000000000000000000000000000000000000000000000000000000000005" + // len(str[0]) = 5
-				"68656c6c6f000000000000000000000000000000000000000000000000000000" + // str[0]
-				"0000000000000000000000000000000000000000000000000000000000000006" + // len(str[1]) = 6
-				"666f6f6261720000000000000000000000000000000000000000000000000000"), // str[1]
-		},
-		{
-			"bytes32[][]",
-			nil,
-			[][]common.Hash{{{1}, {2}}, {{3}, {4}, {5}}},
-			common.Hex2Bytes("0000000000000000000000000000000000000000000000000000000000000002" + // len(array) = 2
-				"0000000000000000000000000000000000000000000000000000000000000040" + // offset 64 to i = 0
-				"00000000000000000000000000000000000000000000000000000000000000a0" + // offset 160 to i = 1
-				"0000000000000000000000000000000000000000000000000000000000000002" + // len(array[0]) = 2
-				"0100000000000000000000000000000000000000000000000000000000000000" + // array[0][0]
-				"0200000000000000000000000000000000000000000000000000000000000000" + // array[0][1]
-				"0000000000000000000000000000000000000000000000000000000000000003" + // len(array[1]) = 3
-				"0300000000000000000000000000000000000000000000000000000000000000" + // array[1][0]
-				"0400000000000000000000000000000000000000000000000000000000000000" + // array[1][1]
-				"0500000000000000000000000000000000000000000000000000000000000000"), // array[1][2]
-		},
+// packTests are the encoding test vectors shared by the packing and unpacking
+// tests.
+var packTests = []struct {
+	typ        string
+	components []ArgumentMarshaling
+	input      interface{}
+	output     []byte
+}{
+	{
+		"uint8",
+		nil,
+		uint8(2),
+		common.Hex2Bytes("0000000000000000000000000000000000000000000000000000000000000002"),
+	},
+	{
+		"uint8[]",
+		nil,
+		[]uint8{1, 2},
+		common.Hex2Bytes("000000000000000000000000000000000000000000000000000000000000000200000000000000000000000000000000000000000000000000000000000000010000000000000000000000000000000000000000000000000000000000000002"),
+	},
+	{
+		"uint16",
+		nil,
+		uint16(2),
+		common.Hex2Bytes("0000000000000000000000000000000000000000000000000000000000000002"),
+	},
+	{
+		"uint16[]",
+		nil,
+		[]uint16{1, 2},
+		common.Hex2Bytes("000000000000000000000000000000000000000000000000000000000000000200000000000000000000000000000000000000000000000000000000000000010000000000000000000000000000000000000000000000000000000000000002"),
+	},
+	{
+		"uint32",
+		nil,
+		uint32(2),
+		common.Hex2Bytes("0000000000000000000000000000000000000000000000000000000000000002"),
+	},
+	{
+		"uint32[]",
+		nil,
+		[]uint32{1, 2},
+		common.Hex2Bytes("000000000000000000000000000000000000000000000000000000000000000200000000000000000000000000000000000000000000000000000000000000010000000000000000000000000000000000000000000000000000000000000002"),
+	},
+	{
+		"uint64",
+		nil,
+		uint64(2),
+		common.Hex2Bytes("0000000000000000000000000000000000000000000000000000000000000002"),
+	},
+	{
+		"uint64[]",
+		nil,
+		[]uint64{1, 2},
+		common.Hex2Bytes("000000000000000000000000000000000000000000000000000000000000000200000000000000000000000000000000000000000000000000000000000000010000000000000000000000000000000000000000000000000000000000000002"),
+	},
+	{
+		"uint256",
+		nil,
+		big.NewInt(2),
+		common.Hex2Bytes("0000000000000000000000000000000000000000000000000000000000000002"),
+	},
+	{
+		"uint256[]",
+		nil,
+		[]*big.Int{big.NewInt(1), big.NewInt(2)},
+		common.Hex2Bytes("000000000000000000000000000000000000000000000000000000000000000200000000000000000000000000000000000000000000000000000000000000010000000000000000000000000000000000000000000000000000000000000002"),
+	},
+	{
+		"int8",
+		nil,
+		int8(2),
+		common.Hex2Bytes("0000000000000000000000000000000000000000000000000000000000000002"),
+	},
+	{
+		"int8[]",
+		nil,
+		[]int8{1, 2},
+		common.Hex2Bytes("000000000000000000000000000000000000000000000000000000000000000200000000000000000000000000000000000000000000000000000000000000010000000000000000000000000000000000000000000000000000000000000002"),
+	},
+	{
+		"int16",
+		nil,
+		int16(2),
+		common.Hex2Bytes("0000000000000000000000000000000000000000000000000000000000000002"),
+	},
+	{
+		"int16[]",
+		nil,
+		[]int16{1, 2},
+		common.Hex2Bytes("000000000000000000000000000000000000000000000000000000000000000200000000000000000000000000000000000000000000000000000000000000010000000000000000000000000000000000000000000000000000000000000002"),
+	},
+	{
+		"int32",
+		nil,
+		int32(2),
+		common.Hex2Bytes("0000000000000000000000000000000000000000000000000000000000000002"),
+	},
+	{
+		"int32[]",
+		nil,
+		[]int32{1, 2},
+		common.Hex2Bytes("000000000000000000000000000000000000000000000000000000000000000200000000000000000000000000000000000000000000000000000000000000010000000000000000000000000000000000000000000000000000000000000002"),
+	},
+	{
+		"int64",
+		nil,
+		int64(2),
+		common.Hex2Bytes("0000000000000000000000000000000000000000000000000000000000000002"),
+	},
+	{
+		"int64[]",
+		nil,
+		[]int64{1, 2},
+		common.Hex2Bytes("000000000000000000000000000000000000000000000000000000000000000200000000000000000000000000000000000000000000000000000000000000010000000000000000000000000000000000000000000000000000000000000002"),
+	},
+	{
+		"int256",
+		nil,
+		big.NewInt(2),
+		common.Hex2Bytes("0000000000000000000000000000000000000000000000000000000000000002"),
+	},
+	{
+		"int256[]",
+		nil,
+		[]*big.Int{big.NewInt(1), big.NewInt(2)},
+		common.Hex2Bytes("000000000000000000000000000000000000000000000000000000000000000200000000000000000000000000000000000000000000000000000000000000010000000000000000000000000000000000000000000000000000000000000002"),
+	},
+	{
+		"bytes1",
+		nil,
+		[1]byte{1},
+		common.Hex2Bytes("0100000000000000000000000000000000000000000000000000000000000000"),
+	},
+	{
+		"bytes2",
+		nil,
+		[2]byte{1},
+		common.Hex2Bytes("0100000000000000000000000000000000000000000000000000000000000000"),
+	},
+	{
+		"bytes3",
+		nil,
+		[3]byte{1},
+		common.Hex2Bytes("0100000000000000000000000000000000000000000000000000000000000000"),
+	},
+	{
+		"bytes4",
+		nil,
+		[4]byte{1},
+		common.Hex2Bytes("0100000000000000000000000000000000000000000000000000000000000000"),
+	},
+	{
+		"bytes5",
+		nil,
+		[5]byte{1},
+		common.Hex2Bytes("0100000000000000000000000000000000000000000000000000000000000000"),
+	},
+	{
+		"bytes6",
+		nil,
+		[6]byte{1},
+		common.Hex2Bytes("0100000000000000000000000000000000000000000000000000000000000000"),
+	},
+	{
+		"bytes7",
+		nil,
+		[7]byte{1},
+		common.Hex2Bytes("0100000000000000000000000000000000000000000000000000000000000000"),
+	},
+	{
+		"bytes8",
+		nil,
+		[8]byte{1},
+		common.Hex2Bytes("0100000000000000000000000000000000000000000000000000000000000000"),
+	},
+	{
+		"bytes9",
+		nil,
+		[9]byte{1},
+		common.Hex2Bytes("0100000000000000000000000000000000000000000000000000000000000000"),
+	},
+	{
+		"bytes10",
+		nil,
+		[10]byte{1},
+		common.Hex2Bytes("0100000000000000000000000000000000000000000000000000000000000000"),
+	},
+	{
+		"bytes11",
+		nil,
+		[11]byte{1},
+		common.Hex2Bytes("0100000000000000000000000000000000000000000000000000000000000000"),
+	},
+	{
+		"bytes12",
+		nil,
+		[12]byte{1},
+		common.Hex2Bytes("0100000000000000000000000000000000000000000000000000000000000000"),
+	},
+	{
+		"bytes13",
+		nil,
+		[13]byte{1},
+		common.Hex2Bytes("0100000000000000000000000000000000000000000000000000000000000000"),
+	},
+	{
+		"bytes14",
+		nil,
+		[14]byte{1},
+		common.Hex2Bytes("0100000000000000000000000000000000000000000000000000000000000000"),
+	},
+	{
+		"bytes15",
+		nil,
+		[15]byte{1},
+		common.Hex2Bytes("0100000000000000000000000000000000000000000000000000000000000000"),
+	},
+	{
+		"bytes16",
+		nil,
+		[16]byte{1},
+		common.Hex2Bytes("0100000000000000000000000000000000000000000000000000000000000000"),
+	},
+	{
+		"bytes17",
+		nil,
+		[17]byte{1},
+		common.Hex2Bytes("0100000000000000000000000000000000000000000000000000000000000000"),
+	},
+	{
+		"bytes18",
+		nil,
+		[18]byte{1},
+		common.Hex2Bytes("0100000000000000000000000000000000000000000000000000000000000000"),
+	},
+	{
+		"bytes19",
+		nil,
+		[19]byte{1},
+		common.Hex2Bytes("0100000000000000000000000000000000000000000000000000000000000000"),
+	},
+	{
+		"bytes20",
+		nil,
+		[20]byte{1},
+		common.Hex2Bytes("0100000000000000000000000000000000000000000000000000000000000000"),
+	},
+	{
+		"bytes21",
+		nil,
+		[21]byte{1},
+		common.Hex2Bytes("0100000000000000000000000000000000000000000000000000000000000000"),
+	},
+	{
+		"bytes22",
+		nil,
+		[22]byte{1},
+		common.Hex2Bytes("0100000000000000000000000000000000000000000000000000000000000000"),
+	},
+	{
+		"bytes23",
+		nil,
+		[23]byte{1},
+		common.Hex2Bytes("0100000000000000000000000000000000000000000000000000000000000000"),
+	},
+	{
+		"bytes24",
+		nil,
+		[24]byte{1},
+		common.Hex2Bytes("0100000000000000000000000000000000000000000000000000000000000000"),
+	},
+	{
+		"bytes25",
+		nil,
+		[25]byte{1},
+		common.Hex2Bytes("0100000000000000000000000000000000000000000000000000000000000000"),
+	},
+	{
+		"bytes26",
+		nil,
+		[26]byte{1},
+		common.Hex2Bytes("0100000000000000000000000000000000000000000000000000000000000000"),
+	},
+	{
+		"bytes27",
+		nil,
+		[27]byte{1},
+		common.Hex2Bytes("0100000000000000000000000000000000000000000000000000000000000000"),
+	},
+	{
+		"bytes28",
+		nil,
+		[28]byte{1},
+		common.Hex2Bytes("0100000000000000000000000000000000000000000000000000000000000000"),
+	},
+	{
+		"bytes29",
+		nil,
+		[29]byte{1},
+		common.Hex2Bytes("0100000000000000000000000000000000000000000000000000000000000000"),
+	},
+	{
+		"bytes30",
+		nil,
+		[30]byte{1},
+		common.Hex2Bytes("0100000000000000000000000000000000000000000000000000000000000000"),
+	},
+	{
+		"bytes31",
+		nil,
+		[31]byte{1},
+		common.Hex2Bytes("0100000000000000000000000000000000000000000000000000000000000000"),
+	},
+	{
+		"bytes32",
+		nil,
+		[32]byte{1},
+		common.Hex2Bytes("0100000000000000000000000000000000000000000000000000000000000000"),
+	},
+	{
+		"bool",
+		nil,
+		true,
+		common.Hex2Bytes("0000000000000000000000000000000000000000000000000000000000000001"),
+	},
+	{
+		"bool[2]",
+		nil,
+		[2]bool{false, true},
+		common.Hex2Bytes("0000000000000000000000000000000000000000000000000000000000000000" +
+			"0000000000000000000000000000000000000000000000000000000000000001"),
+	},
+	{
+		"bool[]",
+		nil,
+		[]bool{true, false, true},
+		common.Hex2Bytes("0000000000000000000000000000000000000000000000000000000000000003" + // length
+			"0000000000000000000000000000000000000000000000000000000000000001" +
+			"0000000000000000000000000000000000000000000000000000000000000000" +
+			"0000000000000000000000000000000000000000000000000000000000000001"),
+	},
+	{
+		"bool[]",
+		nil,
+		[]bool{},
+		common.Hex2Bytes("0000000000000000000000000000000000000000000000000000000000000000"),
+	},
+	{
+		"bool[3]",
+		nil,
+		[3]bool{true, true, false},
+		common.Hex2Bytes("0000000000000000000000000000000000000000000000000000000000000001" +
+			"0000000000000000000000000000000000000000000000000000000000000001" +
+			"0000000000000000000000000000000000000000000000000000000000000000"),
+	},
+	{
+		"bool[2][]",
+		nil,
+		[][2]bool{{true, false}, {false, true}},
+		common.Hex2Bytes("0000000000000000000000000000000000000000000000000000000000000002" + // length
+			"0000000000000000000000000000000000000000000000000000000000000001" + // [0][0]
+			"0000000000000000000000000000000000000000000000000000000000000000" + // [0][1]
+			"0000000000000000000000000000000000000000000000000000000000000000" + // [1][0]
+			"0000000000000000000000000000000000000000000000000000000000000001"), // [1][1]
+	},
+	{
+		"bytes",
+		nil,
+		[]byte{1, 2},
+		common.Hex2Bytes("0000000000000000000000000000000000000000000000000000000000000002" + // length
+			"0102000000000000000000000000000000000000000000000000000000000000"),
+	},
+	{
+		"string[][]",
+		nil,
+		[][]string{{"a"}, {"b", "c"}},
+		common.Hex2Bytes("0000000000000000000000000000000000000000000000000000000000000002" + // outer length
+			"0000000000000000000000000000000000000000000000000000000000000040" + // offset of [0]
+			"00000000000000000000000000000000000000000000000000000000000000c0" + // offset of [1]
+			"0000000000000000000000000000000000000000000000000000000000000001" + // [0] length
+			"0000000000000000000000000000000000000000000000000000000000000020" + // offset of [0][0]
+			"0000000000000000000000000000000000000000000000000000000000000001" + // [0][0] length
+			"6100000000000000000000000000000000000000000000000000000000000000" + // [0][0] "a"
+			"0000000000000000000000000000000000000000000000000000000000000002" + // [1] length
+			"0000000000000000000000000000000000000000000000000000000000000040" + // offset of [1][0]
+			"0000000000000000000000000000000000000000000000000000000000000080" + // offset of [1][1]
+			"0000000000000000000000000000000000000000000000000000000000000001" + // [1][0] length
+			"6200000000000000000000000000000000000000000000000000000000000000" + // [1][0] "b"
+			"0000000000000000000000000000000000000000000000000000000000000001" + // [1][1] length
+			"6300000000000000000000000000000000000000000000000000000000000000"), // [1][1] "c"
+	},
+	{
+		"uint32[2][3][4]",
+		nil,
+		[4][3][2]uint32{{{1, 2}, {3, 4}, {5, 6}}, {{7, 8}, {9, 10}, {11, 12}}, {{13, 14}, {15, 16}, {17, 18}}, {{19, 20}, {21, 22}, {23, 24}}},
+		common.Hex2Bytes("000000000000000000000000000000000000000000000000000000000000000100000000000000000000000000000000000000000000000000000000000000020000000000000000000000000000000000000000000000000000000000000003000000000000000000000000000000000000000000000000000000000000000400000000000000000000000000000000000000000000000000000000000000050000000000000000000000000000000000000000000000000000000000000006000000000000000000000000000000000000000000000000000000000000000700000000000000000000000000000000000000000000000000000000000000080000000000000000000000000000000000000000000000000000000000000009000000000000000000000000000000000000000000000000000000000000000a000000000000000000000000000000000000000000000000000000000000000b000000000000000000000000000000000000000000000000000000000000000c000000000000000000000000000000000000000000000000000000000000000d000000000000000000000000000000000000000000000000000000000000000e000000000000000000000000000000000000000000000000000000000000000f000000000000000000000000000000000000000000000000000000000000001000000000000000000000000000000000000000000000000000000000000000110000000000000000000000000000000000000000000000000000000000000012000000000000000000000000000000000000000000000000000000000000001300000000000000000000000000000000000000000000000000000000000000140000000000000000000000000000000000000000000000000000000000000015000000000000000000000000000000000000000000000000000000000000001600000000000000000000000000000000000000000000000000000000000000170000000000000000000000000000000000000000000000000000000000000018"),
+	},
+	{
+		"address[]",
+		nil,
+		[]common.Address{{1}, {2}},
+		common.Hex2Bytes("000000000000000000000000000000000000000000000000000000000000000200000000000000000000000001000000000000000000000000000000000000000000000000000000000000000200000000000000000000000000000000000000"),
+	},
+	{
+		"bytes32[]",
+		nil,
+		[]common.Hash{{1}, {2}},
+		common.Hex2Bytes("000000000000000000000000000000000000000000000000000000000000000201000000000000000000000000000000000000000000000000000000000000000200000000000000000000000000000000000000000000000000000000000000"),
+	},
+	{
+		"function",
+		nil,
+		[24]byte{1},
+		common.Hex2Bytes("0100000000000000000000000000000000000000000000000000000000000000"),
+	},
+	{
+		"string",
+		nil,
+		"foobar",
+		common.Hex2Bytes("0000000000000000000000000000000000000000000000000000000000000006666f6f6261720000000000000000000000000000000000000000000000000000"),
+	},
+	{
+		"string[]",
+		nil,
+		[]string{"hello", "foobar"},
+		common.Hex2Bytes("0000000000000000000000000000000000000000000000000000000000000002" + // len(array) = 2
+			"0000000000000000000000000000000000000000000000000000000000000040" + // offset 64 to i = 0
+			"0000000000000000000000000000000000000000000000000000000000000080" + // offset 128 to i = 1
+			"0000000000000000000000000000000000000000000000000000000000000005" + // len(str[0]) = 5
+			"68656c6c6f000000000000000000000000000000000000000000000000000000" + // str[0]
+			"0000000000000000000000000000000000000000000000000000000000000006" + // len(str[1]) = 6
+			"666f6f6261720000000000000000000000000000000000000000000000000000"), // str[1]
+	},
+	{
+		"string[2]",
+		nil,
+		[]string{"hello", "foobar"},
+		common.Hex2Bytes("0000000000000000000000000000000000000000000000000000000000000040" + // offset to i = 0
+			"0000000000000000000000000000000000000000000000000000000000000080" + // offset to i = 1
+			"0000000000000000000000000000000000000000000000000000000000000005" + // len(str[0]) = 5
+			"68656c6c6f000000000000000000000000000000000000000000000000000000" + // str[0]
+			"0000000000000000000000000000000000000000000000000000000000000006" + // len(str[1]) = 6
+			"666f6f6261720000000000000000000000000000000000000000000000000000"), // str[1]
+	},
+	{
+		"bytes32[][]",
+		nil,
+		[][]common.Hash{{{1}, {2}}, {{3}, {4}, {5}}},
+		common.Hex2Bytes("0000000000000000000000000000000000000000000000000000000000000002" + // len(array) = 2
+			"0000000000000000000000000000000000000000000000000000000000000040" + // offset 64 to i = 0
+			"00000000000000000000000000000000000000000000000000000000000000a0" + // offset 160 to i = 1
+			"0000000000000000000000000000000000000000000000000000000000000002" + // len(array[0]) = 2
+			"0100000000000000000000000000000000000000000000000000000000000000" + // array[0][0]
+			"0200000000000000000000000000000000000000000000000000000000000000" + // array[0][1]
+			"0000000000000000000000000000000000000000000000000000000000000003" + // len(array[1]) = 3
+			"0300000000000000000000000000000000000000000000000000000000000000" + // array[1][0]
+			"0400000000000000000000000000000000000000000000000000000000000000" + // array[1][1]
+			"0500000000000000000000000000000000000000000000000000000000000000"), // array[1][2]
+	},
 
-		{
-			"bytes32[][2]",
-			nil,
-			[][]common.Hash{{{1}, {2}}, {{3}, {4}, {5}}},
-			common.Hex2Bytes("0000000000000000000000000000000000000000000000000000000000000040" + // offset 64 to i = 0
-				"00000000000000000000000000000000000000000000000000000000000000a0" + // offset 160 to i = 1
-				"0000000000000000000000000000000000000000000000000000000000000002" + // len(array[0]) = 2
-				"0100000000000000000000000000000000000000000000000000000000000000" + // array[0][0]
-				"0200000000000000000000000000000000000000000000000000000000000000" + // array[0][1]
-				"0000000000000000000000000000000000000000000000000000000000000003" + // len(array[1]) = 3
-				"0300000000000000000000000000000000000000000000000000000000000000" + // array[1][0]
-				"0400000000000000000000000000000000000000000000000000000000000000" + // array[1][1]
-				"0500000000000000000000000000000000000000000000000000000000000000"), // array[1][2]
-		},
+	{
+		"bytes32[][2]",
+		nil,
+		[][]common.Hash{{{1}, {2}}, {{3}, {4}, {5}}},
+		common.Hex2Bytes("0000000000000000000000000000000000000000000000000000000000000040" + // offset 64 to i = 0
+			"00000000000000000000000000000000000000000000000000000000000000a0" + // offset 160 to i = 1
+			"0000000000000000000000000000000000000000000000000000000000000002" + // len(array[0]) = 2
+			"0100000000000000000000000000000000000000000000000000000000000000" + // array[0][0]
+			"0200000000000000000000000000000000000000000000000000000000000000" + // array[0][1]
+			"0000000000000000000000000000000000000000000000000000000000000003" + // len(array[1]) = 3
+			"0300000000000000000000000000000000000000000000000000000000000000" + // array[1][0]
+			"0400000000000000000000000000000000000000000000000000000000000000" + // array[1][1]
+			"0500000000000000000000000000000000000000000000000000000000000000"), // array[1][2]
+	},
 
-		{
-			"bytes32[3][2]",
-			nil,
-			[][]common.Hash{{{1}, {2}, {3}}, {{3}, {4}, {5}}},
-			common.Hex2Bytes("0100000000000000000000000000000000000000000000000000000000000000" + // array[0][0]
-				"0200000000000000000000000000000000000000000000000000000000000000" + // array[0][1]
-				"0300000000000000000000000000000000000000000000000000000000000000" + // array[0][2]
-				"0300000000000000000000000000000000000000000000000000000000000000" + // array[1][0]
-				"0400000000000000000000000000000000000000000000000000000000000000" + // array[1][1]
-				"0500000000000000000000000000000000000000000000000000000000000000"), // array[1][2]
-		},
-		{
-			// static tuple
-			"tuple",
-			[]ArgumentMarshaling{
-				{Name: "a", Type: "int64"},
-				{Name: "b", Type: "int256"},
-				{Name: "c", Type: "int256"},
-				{Name: "d", Type: "bool"},
-				{Name: "e", Type: "bytes32[3][2]"},
-			},
-			struct {
-				A int64
-				B *big.Int
-				C *big.Int
-				D bool
-				E [][]common.Hash
-			}{1, big.NewInt(1), big.NewInt(-1), true, [][]common.Hash{{{1}, {2}, {3}}, {{3}, {4}, {5}}}},
-			common.Hex2Bytes("0000000000000000000000000000000000000000000000000000000000000001" + // struct[a]
-				"0000000000000000000000000000000000000000000000000000000000000001" + // struct[b]
-				"ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff" + // struct[c]
-				"0000000000000000000000000000000000000000000000000000000000000001" + // struct[d]
-				"0100000000000000000000000000000000000000000000000000000000000000" + // struct[e] array[0][0]
-				"0200000000000000000000000000000000000000000000000000000000000000" + // struct[e] array[0][1]
-				"0300000000000000000000000000000000000000000000000000000000000000" + // struct[e] array[0][2]
-				"0300000000000000000000000000000000000000000000000000000000000000" + // struct[e] array[1][0]
-				"0400000000000000000000000000000000000000000000000000000000000000" + // struct[e] array[1][1]
-				"0500000000000000000000000000000000000000000000000000000000000000"), // struct[e] array[1][2]
-		},
-		{
-			// dynamic tuple
-			"tuple",
-			[]ArgumentMarshaling{
-				{Name: "a", Type: "string"},
-				{Name: "b", Type: "int64"},
-				{Name: "c", Type: "bytes"},
-				{Name: "d", Type: "string[]"},
-				{Name: "e", Type: "int256[]"},
-				{Name: "f", Type: "address[]"},
-			},
-			struct {
-				FieldA string `abi:"a"` // Test whether abi tag works
-				FieldB int64  `abi:"b"`
-				C      []byte
-				D      []string
-				E      []*big.Int
-				F      []common.Address
-			}{"foobar", 1, []byte{1}, []string{"foo", "bar"}, []*big.Int{big.NewInt(1), big.NewInt(-1)}, []common.Address{{1}, {2}}},
-			common.Hex2Bytes("00000000000000000000000000000000000000000000000000000000000000c0" + // struct[a] offset
-				"0000000000000000000000000000000000000000000000000000000000000001" + // struct[b]
-				"0000000000000000000000000000000000000000000000000000000000000100" + // struct[c] offset
-				"0000000000000000000000000000000000000000000000000000000000000140" + // struct[d] offset
-				"0000000000000000000000000000000000000000000000000000000000000220" + // struct[e] offset
-				"0000000000000000000000000000000000000000000000000000000000000280" + // struct[f] offset
-				"0000000000000000000000000000000000000000000000000000000000000006" + // struct[a] length
-				"666f6f6261720000000000000000000000000000000000000000000000000000" + // struct[a] "foobar"
-				"0000000000000000000000000000000000000000000000000000000000000001" + // struct[c] length
-				"0100000000000000000000000000000000000000000000000000000000000000" + // []byte{1}
-				"0000000000000000000000000000000000000000000000000000000000000002" + // struct[d] length
-				"0000000000000000000000000000000000000000000000000000000000000040" + // foo offset
-				"0000000000000000000000000000000000000000000000000000000000000080" + // bar offset
-				"0000000000000000000000000000000000000000000000000000000000000003" + // foo length
-				"666f6f0000000000000000000000000000000000000000000000000000000000" + // foo
-				"0000000000000000000000000000000000000000000000000000000000000003" + // bar offset
-				"6261720000000000000000000000000000000000000000000000000000000000" + // bar
-				"0000000000000000000000000000000000000000000000000000000000000002" + // struct[e] length
-				"0000000000000000000000000000000000000000000000000000000000000001" + // 1
-				"ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff" + // -1
-				"0000000000000000000000000000000000000000000000000000000000000002" + // struct[f] length
-				"0000000000000000000000000100000000000000000000000000000000000000" + // common.Address{1}
-				"0000000000000000000000000200000000000000000000000000000000000000"), // common.Address{2}
-		},
-		{
-			// nested tuple
-			"tuple",
-			[]ArgumentMarshaling{
-				{Name: "a", Type: "tuple", Components: []ArgumentMarshaling{{Name: "a", Type: "uint256"}, {Name: "b", Type: "uint256[]"}}},
-				{Name: "b", Type: "int256[]"},
-			},
-			struct {
-				A struct {
-					FieldA *big.Int `abi:"a"`
-					B      []*big.Int
-				}
-				B []*big.Int
-			}{
-				A: struct {
-					FieldA *big.Int `abi:"a"` // Test whether abi tag works for nested tuple
-					B      []*big.Int
-				}{big.NewInt(1), []*big.Int{big.NewInt(1), big.NewInt(0)}},
-				B: []*big.Int{big.NewInt(1), big.NewInt(0)}},
-			common.Hex2Bytes("0000000000000000000000000000000000000000000000000000000000000040" + // a offset
-				"00000000000000000000000000000000000000000000000000000000000000e0" + // b offset
-				"0000000000000000000000000000000000000000000000000000000000000001" + // a.a value
-				"0000000000000000000000000000000000000000000000000000000000000040" + // a.b offset
-				"0000000000000000000000000000000000000000000000000000000000000002" + // a.b length
-				"0000000000000000000000000000000000000000000000000000000000000001" + // a.b[0] value
-				"0000000000000000000000000000000000000000000000000000000000000000" + // a.b[1] value
-				"0000000000000000000000000000000000000000000000000000000000000002" + // b length
-				"0000000000000000000000000000000000000000000000000000000000000001" + // b[0] value
-				"0000000000000000000000000000000000000000000000000000000000000000"), // b[1] value
-		},
-		{
-			// tuple slice
-			"tuple[]",
-			[]ArgumentMarshaling{
-				{Name: "a", Type: "int256"},
-				{Name: "b", Type: "int256[]"},
-			},
-			[]struct {
-				A *big.Int
-				B []*big.Int
-			}{
-				{big.NewInt(-1), []*big.Int{big.NewInt(1), big.NewInt(0)}},
-				{big.NewInt(1), []*big.Int{big.NewInt(2), big.NewInt(-1)}},
-			},
-			common.Hex2Bytes("0000000000000000000000000000000000000000000000000000000000000002" + // tuple length
-				"0000000000000000000000000000000000000000000000000000000000000040" + // tuple[0] offset
-				"00000000000000000000000000000000000000000000000000000000000000e0" + // tuple[1] offset
-				"ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff" + // tuple[0].A
-				"0000000000000000000000000000000000000000000000000000000000000040" + // tuple[0].B offset
-				"0000000000000000000000000000000000000000000000000000000000000002" + // tuple[0].B length
-				"0000000000000000000000000000000000000000000000000000000000000001" + // tuple[0].B[0] value
-				"0000000000000000000000000000000000000000000000000000000000000000" + // tuple[0].B[1] value
-				"0000000000000000000000000000000000000000000000000000000000000001" + // tuple[1].A
-				"0000000000000000000000000000000000000000000000000000000000000040" + // tuple[1].B offset
-				"0000000000000000000000000000000000000000000000000000000000000002" + // tuple[1].B length
-				"0000000000000000000000000000000000000000000000000000000000000002" + // tuple[1].B[0] value
-				"ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"), // tuple[1].B[1] value
-		},
-		{
-			// static tuple array
-			"tuple[2]",
-			[]ArgumentMarshaling{
-				{Name: "a", Type: "int256"},
-				{Name: "b", Type: "int256"},
-			},
-			[2]struct {
-				A *big.Int
-				B *big.Int
-			}{
-				{big.NewInt(-1), big.NewInt(1)},
-				{big.NewInt(1), big.NewInt(-1)},
-			},
-			common.Hex2Bytes("ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff" + // tuple[0].a
-				"0000000000000000000000000000000000000000000000000000000000000001" + // tuple[0].b
-				"0000000000000000000000000000000000000000000000000000000000000001" + // tuple[1].a
-				"ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"), // tuple[1].b
-		},
-		{
-			// dynamic tuple array
-			"tuple[2]",
-			[]ArgumentMarshaling{
-				{Name: "a", Type: "int256[]"},
-			},
-			[2]struct {
-				A []*big.Int
-			}{
-				{[]*big.Int{big.NewInt(-1), big.NewInt(1)}},
-				{[]*big.Int{big.NewInt(1), big.NewInt(-1)}},
-			},
-			common.Hex2Bytes("0000000000000000000000000000000000000000000000000000000000000040" + // tuple[0] offset
-				"00000000000000000000000000000000000000000000000000000000000000c0" + // tuple[1] offset
-				"0000000000000000000000000000000000000000000000000000000000000020" + // tuple[0].A offset
-				"0000000000000000000000000000000000000000000000000000000000000002" + // tuple[0].A length
-				"ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff" + // tuple[0].A[0]
-				"0000000000000000000000000000000000000000000000000000000000000001" + // tuple[0].A[1]
-				"0000000000000000000000000000000000000000000000000000000000000020" + // tuple[1].A offset
-				"0000000000000000000000000000000000000000000000000000000000000002" + // tuple[1].A length
-				"0000000000000000000000000000000000000000000000000000000000000001" + // tuple[1].A[0]
-				"ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"), // tuple[1].A[1]
-		},
-	} {
+	{
+		"bytes32[3][2]",
+		nil,
+		[][]common.Hash{{{1}, {2}, {3}}, {{3}, {4}, {5}}},
+		common.Hex2Bytes("0100000000000000000000000000000000000000000000000000000000000000" + // array[0][0]
+			"0200000000000000000000000000000000000000000000000000000000000000" + // array[0][1]
+			"0300000000000000000000000000000000000000000000000000000000000000" + // array[0][2]
+			"0300000000000000000000000000000000000000000000000000000000000000" + // array[1][0]
+			"0400000000000000000000000000000000000000000000000000000000000000" + // array[1][1]
+			"0500000000000000000000000000000000000000000000000000000000000000"), // array[1][2]
+	},
+	{
+		// static tuple
+		"tuple",
+		[]ArgumentMarshaling{
+			{Name: "a", Type: "int64"},
+			{Name: "b", Type: "int256"},
+			{Name: "c", Type: "int256"},
+			{Name: "d", Type: "bool"},
+			{Name: "e", Type: "bytes32[3][2]"},
+		},
+		struct {
+			A int64
+			B *big.Int
+			C *big.Int
+			D bool
+			E [][]common.Hash
+		}{1, big.NewInt(1), big.NewInt(-1), true, [][]common.Hash{{{1}, {2}, {3}}, {{3}, {4}, {5}}}},
+		common.Hex2Bytes("0000000000000000000000000000000000000000000000000000000000000001" + // struct[a]
+			"0000000000000000000000000000000000000000000000000000000000000001" + // struct[b]
+			"ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff" + // struct[c]
+			"0000000000000000000000000000000000000000000000000000000000000001" + // struct[d]
+			"0100000000000000000000000000000000000000000000000000000000000000" + // struct[e] array[0][0]
+			"0200000000000000000000000000000000000000000000000000000000000000" + // struct[e] array[0][1]
+			"0300000000000000000000000000000000000000000000000000000000000000" + // struct[e] array[0][2]
+			"0300000000000000000000000000000000000000000000000000000000000000" + // struct[e] array[1][0]
+			"0400000000000000000000000000000000000000000000000000000000000000" + // struct[e] array[1][1]
+			"0500000000000000000000000000000000000000000000000000000000000000"), // struct[e] array[1][2]
+	},
+	{
+		// dynamic tuple
+		"tuple",
+		[]ArgumentMarshaling{
+			{Name: "a", Type: "string"},
+			{Name: "b", Type: "int64"},
+			{Name: "c", Type: "bytes"},
+			{Name: "d", Type: "string[]"},
+			{Name: "e", Type: "int256[]"},
+			{Name: "f", Type: "address[]"},
+		},
+		struct {
+			FieldA string `abi:"a"` // Test whether abi tag works
+			FieldB int64  `abi:"b"`
+			C      []byte
+			D      []string
+			E      []*big.Int
+			F      []common.Address
+		}{"foobar", 1, []byte{1}, []string{"foo", "bar"}, []*big.Int{big.NewInt(1), big.NewInt(-1)}, []common.Address{{1}, {2}}},
+		common.Hex2Bytes("00000000000000000000000000000000000000000000000000000000000000c0" + // struct[a] offset
+			"0000000000000000000000000000000000000000000000000000000000000001" + // struct[b]
+			"0000000000000000000000000000000000000000000000000000000000000100" + // struct[c] offset
+			"0000000000000000000000000000000000000000000000000000000000000140" + // struct[d] offset
+			"0000000000000000000000000000000000000000000000000000000000000220" + // struct[e] offset
+			"0000000000000000000000000000000000000000000000000000000000000280" + // struct[f] offset
+			"0000000000000000000000000000000000000000000000000000000000000006" + // struct[a] length
+			"666f6f6261720000000000000000000000000000000000000000000000000000" + // struct[a] "foobar"
+			"0000000000000000000000000000000000000000000000000000000000000001" + // struct[c] length
+			"0100000000000000000000000000000000000000000000000000000000000000" + // []byte{1}
+			"0000000000000000000000000000000000000000000000000000000000000002" + // struct[d] length
+			"0000000000000000000000000000000000000000000000000000000000000040" + // foo offset
+			"0000000000000000000000000000000000000000000000000000000000000080" + // bar offset
+			"0000000000000000000000000000000000000000000000000000000000000003" + // foo length
+			"666f6f0000000000000000000000000000000000000000000000000000000000" + // foo
+			"0000000000000000000000000000000000000000000000000000000000000003" + // bar offset
+			"6261720000000000000000000000000000000000000000000000000000000000" + // bar
+			"0000000000000000000000000000000000000000000000000000000000000002" + // struct[e] length
+			"0000000000000000000000000000000000000000000000000000000000000001" + // 1
+			"ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff" + // -1
+			"0000000000000000000000000000000000000000000000000000000000000002" + // struct[f] length
+			"0000000000000000000000000100000000000000000000000000000000000000" + // common.Address{1}
+			"0000000000000000000000000200000000000000000000000000000000000000"), // common.Address{2}
+	},
+	{
+		// nested tuple
+		"tuple",
+		[]ArgumentMarshaling{
+			{Name: "a", Type: "tuple", Components: []ArgumentMarshaling{{Name: "a", Type: "uint256"}, {Name: "b", Type: "uint256[]"}}},
+			{Name: "b", Type: "int256[]"},
+		},
+		struct {
+			A struct {
+				FieldA *big.Int `abi:"a"`
+				B      []*big.Int
+			}
+			B []*big.Int
+		}{
+			A: struct {
+				FieldA *big.Int `abi:"a"` // Test whether abi tag works for nested tuple
+				B      []*big.Int
+			}{big.NewInt(1), []*big.Int{big.NewInt(1), big.NewInt(0)}},
+			B: []*big.Int{big.NewInt(1), big.NewInt(0)}},
+		common.Hex2Bytes("0000000000000000000000000000000000000000000000000000000000000040" + // a offset
+			"00000000000000000000000000000000000000000000000000000000000000e0" + // b offset
+			"0000000000000000000000000000000000000000000000000000000000000001" + // a.a value
+			"0000000000000000000000000000000000000000000000000000000000000040" + // a.b offset
+			"0000000000000000000000000000000000000000000000000000000000000002" + // a.b length
+			"0000000000000000000000000000000000000000000000000000000000000001" + // a.b[0] value
+			"0000000000000000000000000000000000000000000000000000000000000000" + // a.b[1] value
+			"0000000000000000000000000000000000000000000000000000000000000002" + // b length
+			"0000000000000000000000000000000000000000000000000000000000000001" + // b[0] value
+			"0000000000000000000000000000000000000000000000000000000000000000"), // b[1] value
+	},
+	{
+		// tuple slice
+		"tuple[]",
+		[]ArgumentMarshaling{
+			{Name: "a", Type: "int256"},
+			{Name: "b", Type: "int256[]"},
+		},
+		[]struct {
+			A *big.Int
+			B []*big.Int
+		}{
+			{big.NewInt(-1), []*big.Int{big.NewInt(1), big.NewInt(0)}},
+			{big.NewInt(1), []*big.Int{big.NewInt(2), big.NewInt(-1)}},
+		},
+		common.Hex2Bytes("0000000000000000000000000000000000000000000000000000000000000002" + // tuple length
+			"0000000000000000000000000000000000000000000000000000000000000040" + // tuple[0] offset
+			"00000000000000000000000000000000000000000000000000000000000000e0" + // tuple[1] offset
+			"ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff" + // tuple[0].A
+			"0000000000000000000000000000000000000000000000000000000000000040" + // tuple[0].B offset
+			"0000000000000000000000000000000000000000000000000000000000000002" + // tuple[0].B length
+			"0000000000000000000000000000000000000000000000000000000000000001" + // tuple[0].B[0] value
+			"0000000000000000000000000000000000000000000000000000000000000000" + // tuple[0].B[1] value
+			"0000000000000000000000000000000000000000000000000000000000000001" + // tuple[1].A
+			"0000000000000000000000000000000000000000000000000000000000000040" + // tuple[1].B offset
+			"0000000000000000000000000000000000000000000000000000000000000002" + // tuple[1].B length
+			"0000000000000000000000000000000000000000000000000000000000000002" + // tuple[1].B[0] value
+			"ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"), // tuple[1].B[1] value
+	},
+	{
+		// static tuple array
+		"tuple[2]",
+		[]ArgumentMarshaling{
+			{Name: "a", Type: "int256"},
+			{Name: "b", Type: "int256"},
+		},
+		[2]struct {
+			A *big.Int
+			B *big.Int
+		}{
+			{big.NewInt(-1), big.NewInt(1)},
+			{big.NewInt(1), big.NewInt(-1)},
+		},
+		common.Hex2Bytes("ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff" + // tuple[0].a
+			"0000000000000000000000000000000000000000000000000000000000000001" + // tuple[0].b
+			"0000000000000000000000000000000000000000000000000000000000000001" + // tuple[1].a
+			"ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"), // tuple[1].b
+	},
+	{
+		// dynamic tuple array
+		"tuple[2]",
+		[]ArgumentMarshaling{
+			{Name: "a", Type: "int256[]"},
+		},
+		[2]struct {
+			A []*big.Int
+		}{
+			{[]*big.Int{big.NewInt(-1), big.NewInt(1)}},
+			{[]*big.Int{big.NewInt(1), big.NewInt(-1)}},
+		},
+		common.Hex2Bytes("0000000000000000000000000000000000000000000000000000000000000040" + // tuple[0] offset
+			"00000000000000000000000000000000000000000000000000000000000000c0" + // tuple[1] offset
+			"0000000000000000000000000000000000000000000000000000000000000020" + // tuple[0].A offset
+			"0000000000000000000000000000000000000000000000000000000000000002" + // tuple[0].A length
+			"ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff" + // tuple[0].A[0]
+			"0000000000000000000000000000000000000000000000000000000000000001" + // tuple[0].A[1]
+			"0000000000000000000000000000000000000000000000000000000000000020" + // tuple[1].A offset
+			"0000000000000000000000000000000000000000000000000000000000000002" + // tuple[1].A length
+			"0000000000000000000000000000000000000000000000000000000000000001" + // tuple[1].A[0]
+			"ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"), // tuple[1].A[1]
+	},
+}
+
+func TestPack(t *testing.T) {
+	for i, test := range packTests {
 		typ, err := NewType(test.typ, test.components)
 		if err != nil {
 			t.Fatalf("%v failed. Unexpected parse error: %v", i, err)
@@ -781,3 +860,1347 @@ func TestPackNumber(t *testing.T) {
 		}
 	}
 }
+
+func TestPackNumberString(t *testing.T) {
+	uint256, _ := NewType("uint256", nil)
+	uint8, _ := NewType("uint8", nil)
+	int8, _ := NewType("int8", nil)
+
+	tests := []struct {
+		typ    Type
+		input  string
+		packed []byte
+		err    bool
+	}{
+		{uint256, "1000000000000000000", common.Hex2Bytes("0000000000000000000000000000000000000000000000000de0b6b3a7640000"), false},
+		{uint256, "0xde0b6b3a7640000", common.Hex2Bytes("0000000000000000000000000000000000000000000000000de0b6b3a7640000"), false},
+		{uint256, "0XDE0B6B3A7640000", common.Hex2Bytes("0000000000000000000000000000000000000000000000000de0b6b3a7640000"), false},
+		{uint256, "0", common.Hex2Bytes("0000000000000000000000000000000000000000000000000000000000000000"), false},
+		{uint256, "0x" + strings.Repeat("ff", 32), common.Hex2Bytes("ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"), false},
+		{uint8, "255", common.Hex2Bytes("00000000000000000000000000000000000000000000000000000000000000ff"), false},
+		{int8, "-128", common.Hex2Bytes("ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff80"), false},
+		{int8, "-0x1", common.Hex2Bytes("ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"), false},
+
+		// Malformed and out of range inputs
+		{uint256, "", nil, true},
+		{uint256, "0x", nil, true},
+		{uint256, "12abc", nil, true},
+		{uint256, "0xzz", nil, true},
+		{uint256, "1.5", nil, true},
+		{uint256, "--1", nil, true},
+		{uint256, "-1", nil, true},
+		{uint256, "0x1" + strings.Repeat("00", 32), nil, true},
+		{uint8, "256", nil, true},
+		{int8, "128", nil, true},
+		{int8, "-129", nil, true},
+	}
+	for i, tt := range tests {
+		packed, err := tt.typ.pack(reflect.ValueOf(tt.input))
+		if tt.err {
+			if err == nil {
+				t.Errorf("test %d: expected error for %q, got %x", i, tt.input, packed)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test %d: unexpected error for %q: %v", i, tt.input, err)
+			continue
+		}
+		if !bytes.Equal(packed, tt.packed) {
+			t.Errorf("test %d: pack mismatch: have %x, want %x", i, packed, tt.packed)
+		}
+	}
+}
+
+func TestPackTupleMap(t *testing.T) {
+	typ, err := NewType("tuple", []ArgumentMarshaling{
+		{Name: "a", Type: "uint256"},
+		{Name: "b", Type: "string"},
+		{Name: "c", Type: "address"},
+		{Name: "d", Type: "bytes"},
+		{Name: "e", Type: "bool"},
+		{Name: "f", Type: "int8[]"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := map[string]interface{}{
+		"f": []int8{-1, 1},
+		"e": true,
+		"d": []byte{0xde, 0xad},
+		"c": common.Address{1},
+		"b": "hello",
+		"a": big.NewInt(7),
+	}
+	want, err := typ.pack(reflect.ValueOf(struct {
+		A *big.Int
+		B string
+		C common.Address
+		D []byte
+		E bool
+		F []int8
+	}{big.NewInt(7), "hello", common.Address{1}, []byte{0xde, 0xad}, true, []int8{-1, 1}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100; i++ {
+		packed, err := typ.pack(reflect.ValueOf(input))
+		if err != nil {
+			t.Fatalf("pack %d: unexpected error: %v", i, err)
+		}
+		if !bytes.Equal(packed, want) {
+			t.Fatalf("pack %d: mismatch: have %x, want %x", i, packed, want)
+		}
+	}
+	// Missing, superfluous and badly typed keys must be rejected
+	delete(input, "f")
+	if _, err := typ.pack(reflect.ValueOf(input)); err == nil {
+		t.Error("expected error for missing component")
+	}
+	input["g"] = []int8{}
+	if _, err := typ.pack(reflect.ValueOf(input)); err == nil {
+		t.Error("expected error for unknown component")
+	}
+	if _, err := typ.pack(reflect.ValueOf(map[int]interface{}{})); err == nil {
+		t.Error("expected error for non-string keys")
+	}
+}
+
+func TestPackFixedPoint(t *testing.T) {
+	ufixed, _ := NewType("ufixed128x18", nil)
+	fixed, _ := NewType("fixed8x1", nil)
+
+	tests := []struct {
+		typ    Type
+		input  interface{}
+		packed []byte
+		err    bool
+	}{
+		{ufixed, 1.5, common.Hex2Bytes("00000000000000000000000000000000000000000000000014d1120d7b160000"), false},
+		{ufixed, 0.1, common.Hex2Bytes("000000000000000000000000000000000000000000000000016345785d8a0000"), false},
+		{ufixed, float32(0.25), common.Hex2Bytes("00000000000000000000000000000000000000000000000003782dace9d90000"), false},
+		{ufixed, big.NewRat(3, 2), common.Hex2Bytes("00000000000000000000000000000000000000000000000014d1120d7b160000"), false},
+		{ufixed, big.NewInt(1), common.Hex2Bytes("0000000000000000000000000000000000000000000000000000000000000001"), false},
+		{fixed, -12.7, common.Hex2Bytes("ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff81"), false},
+
+		// Values that cannot be represented exactly or do not fit
+		{ufixed, 1e-19, nil, true},
+		{ufixed, big.NewRat(1, 3), nil, true},
+		{ufixed, -1.5, nil, true},
+		{ufixed, math.NaN(), nil, true},
+		{ufixed, math.Inf(1), nil, true},
+		{fixed, 1.25, nil, true},
+		{fixed, 12.8, nil, true},
+		{ufixed, "1.5", nil, true},
+	}
+	for i, tt := range tests {
+		packed, err := tt.typ.pack(reflect.ValueOf(tt.input))
+		if tt.err {
+			if err == nil {
+				t.Errorf("test %d: expected error for %v, got %x", i, tt.input, packed)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test %d: unexpected error for %v: %v", i, tt.input, err)
+			continue
+		}
+		if !bytes.Equal(packed, tt.packed) {
+			t.Errorf("test %d: pack mismatch: have %x, want %x", i, packed, tt.packed)
+		}
+	}
+}
+
+func TestPackFixedBytesArray(t *testing.T) {
+	typ, _ := NewType("bytes4[]", nil)
+
+	want := common.Hex2Bytes("0000000000000000000000000000000000000000000000000000000000000002" +
+		"0102030400000000000000000000000000000000000000000000000000000000" +
+		"0506070800000000000000000000000000000000000000000000000000000000")
+	for i, input := range []interface{}{
+		[][4]byte{{1, 2, 3, 4}, {5, 6, 7, 8}},
+		[][]byte{{1, 2, 3, 4}, {5, 6, 7, 8}},
+	} {
+		packed, err := typ.pack(reflect.ValueOf(input))
+		if err != nil {
+			t.Fatalf("test %d: unexpected error: %v", i, err)
+		}
+		if !bytes.Equal(packed, want) {
+			t.Errorf("test %d: pack mismatch: have %x, want %x", i, packed, want)
+		}
+	}
+	for i, input := range []interface{}{
+		[][]byte{{1, 2, 3, 4}, {5, 6, 7}},
+		[][]byte{{1, 2, 3, 4}, {5, 6, 7, 8, 9}},
+		[][3]byte{{1, 2, 3}, {5, 6, 7}},
+	} {
+		_, err := typ.pack(reflect.ValueOf(input))
+		if err == nil {
+			t.Errorf("test %d: expected error for wrong-sized element", i)
+			continue
+		}
+		if !strings.Contains(err.Error(), "element ") {
+			t.Errorf("test %d: error does not name the offending element: %v", i, err)
+		}
+	}
+}
+
+func TestPackSliceIntoArray(t *testing.T) {
+	typ, _ := NewType("uint256[3]", nil)
+	nested, _ := NewType("uint256[3][]", nil)
+
+	one, two, three, four := big.NewInt(1), big.NewInt(2), big.NewInt(3), big.NewInt(4)
+	want := common.Hex2Bytes("0000000000000000000000000000000000000000000000000000000000000001" +
+		"0000000000000000000000000000000000000000000000000000000000000002" +
+		"0000000000000000000000000000000000000000000000000000000000000003")
+
+	for i, test := range []struct {
+		typ    Type
+		input  interface{}
+		packed []byte
+		err    string
+	}{
+		{typ, []*big.Int{one, two, three}, want, ""},
+		{typ, [3]*big.Int{one, two, three}, want, ""},
+		{typ, []*big.Int{one, two}, nil, "abi: cannot use [2]ptr as type [3]ptr as argument"},
+		{typ, []*big.Int{one, two, three, four}, nil, "abi: cannot use [4]ptr as type [3]ptr as argument"},
+		{nested, [][]*big.Int{}, common.Hex2Bytes("0000000000000000000000000000000000000000000000000000000000000000"), ""},
+		{nested, [][]*big.Int{{one, two, three}, {one, two}}, nil, "abi: cannot use [2]ptr as type [3]ptr as argument"},
+	} {
+		packed, err := test.typ.pack(reflect.ValueOf(test.input))
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Errorf("test %d: error mismatch: have %v, want %s", i, err, test.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test %d: unexpected error: %v", i, err)
+			continue
+		}
+		if !bytes.Equal(packed, test.packed) {
+			t.Errorf("test %d: pack mismatch: have %x, want %x", i, packed, test.packed)
+		}
+	}
+}
+
+func TestPackNilBigInt(t *testing.T) {
+	abi, err := JSON(strings.NewReader(`[
+		{"type":"function","name":"single","inputs":[{"name":"a","type":"uint256"}]},
+		{"type":"function","name":"slice","inputs":[{"name":"a","type":"int256[]"}]},
+		{"type":"function","name":"uints","inputs":[{"name":"a","type":"uint256[]"}]},
+		{"type":"function","name":"array","inputs":[{"name":"a","type":"uint256[2]"}]},
+		{"type":"function","name":"tuple","inputs":[{"name":"a","type":"tuple","components":[{"name":"x","type":"uint256"},{"name":"y","type":"uint256"}]}]}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var nilInt *big.Int
+	type pair struct {
+		X *big.Int
+		Y *big.Int
+	}
+	zero := common.LeftPadBytes(nil, 32)
+	one := common.LeftPadBytes([]byte{1}, 32)
+
+	for i, test := range []struct {
+		method string
+		input  interface{}
+		want   []byte
+		err    string
+	}{
+		{"single", nilInt, zero, "abi: nil *big.Int for uint256 arg"},
+		{"single", &nilInt, zero, "abi: nil *big.Int for uint256 arg"},
+		{"slice", []*big.Int{big.NewInt(1), nil}, append(append(append(common.LeftPadBytes([]byte{0x20}, 32), common.LeftPadBytes([]byte{2}, 32)...), one...), zero...), "abi: nil *big.Int at index 1 of int256[] arg"},
+		{"uints", []*big.Int{nil, big.NewInt(1), nil}, append(append(append(append(common.LeftPadBytes([]byte{0x20}, 32), common.LeftPadBytes([]byte{3}, 32)...), zero...), one...), zero...), "abi: nil *big.Int at index 0 of uint256[] arg"},
+		{"array", [2]*big.Int{big.NewInt(1), nil}, append(append([]byte{}, one...), zero...), "abi: nil *big.Int at index 1 of uint256[2] arg"},
+		{"tuple", pair{nil, big.NewInt(1)}, append(append([]byte{}, zero...), one...), "abi: nil *big.Int for uint256 arg"},
+	} {
+		// Strict packing must reject the nil value with a clear error
+		_, err := abi.Pack(test.method, test.input)
+		if err == nil || err.Error() != test.err {
+			t.Errorf("test %d: error mismatch: have %v, want %s", i, err, test.err)
+		}
+		// Opting in must pack it as zero instead
+		packed, err := abi.PackWithOptions(PackOptions{NilBigIntAsZero: true}, test.method, test.input)
+		if err != nil {
+			t.Errorf("test %d: unexpected error: %v", i, err)
+			continue
+		}
+		want := append(abi.Methods[test.method].Id(), test.want...)
+		if !bytes.Equal(packed, want) {
+			t.Errorf("test %d: pack mismatch: have %x, want %x", i, packed, want)
+		}
+	}
+	if _, err := abi.Pack("single", nilInt); err == nil || err.Error() != "abi: nil *big.Int for uint256 arg" {
+		t.Errorf("error mismatch: have %v, want abi: nil *big.Int for uint256 arg", err)
+	}
+}
+
+func TestPackTupleList(t *testing.T) {
+	typ, err := NewType("tuple", []ArgumentMarshaling{
+		{Name: "a", Type: "uint256"},
+		{Name: "b", Type: "string"},
+		{Name: "c", Type: "tuple", Components: []ArgumentMarshaling{{Name: "x", Type: "int8"}, {Name: "y", Type: "bool[]"}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	type inner struct {
+		X int8
+		Y []bool
+	}
+	want, err := typ.pack(reflect.ValueOf(struct {
+		A *big.Int
+		B string
+		C inner
+	}{big.NewInt(7), "hello", inner{-1, []bool{true, false}}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, input := range []interface{}{
+		[]interface{}{big.NewInt(7), "hello", inner{-1, []bool{true, false}}},
+		[]interface{}{"7", "hello", []interface{}{int8(-1), []bool{true, false}}},
+		[3]interface{}{big.NewInt(7), "hello", map[string]interface{}{"x": int8(-1), "y": []bool{true, false}}},
+	} {
+		packed, err := typ.pack(reflect.ValueOf(input))
+		if err != nil {
+			t.Errorf("test %d: unexpected error: %v", i, err)
+			continue
+		}
+		if !bytes.Equal(packed, want) {
+			t.Errorf("test %d: pack mismatch: have %x, want %x", i, packed, want)
+		}
+	}
+	for i, input := range []interface{}{
+		[]interface{}{big.NewInt(7), "hello"},
+		[]interface{}{big.NewInt(7), "hello", inner{}, true},
+		[]interface{}{"hello", big.NewInt(7), inner{}},
+	} {
+		if _, err := typ.pack(reflect.ValueOf(input)); err == nil {
+			t.Errorf("test %d: expected error", i)
+		}
+	}
+}
+
+func TestPackAddressByteArray(t *testing.T) {
+	typ, err := NewType("tuple", []ArgumentMarshaling{
+		{Name: "to", Type: "address"},
+		{Name: "list", Type: "address[]"},
+		{Name: "inner", Type: "tuple", Components: []ArgumentMarshaling{{Name: "a", Type: "address"}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	type addrInner struct{ A common.Address }
+	want, err := typ.pack(reflect.ValueOf(struct {
+		To    common.Address
+		List  []common.Address
+		Inner addrInner
+	}{common.Address{1}, []common.Address{{2}}, addrInner{common.Address{3}}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	type byteInner struct{ A [20]byte }
+	packed, err := typ.pack(reflect.ValueOf(struct {
+		To    [20]byte
+		List  [][20]byte
+		Inner byteInner
+	}{[20]byte{1}, [][20]byte{{2}}, byteInner{[20]byte{3}}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(packed, want) {
+		t.Errorf("pack mismatch: have %x, want %x", packed, want)
+	}
+	// Byte arrays of other lengths must not be accepted as addresses
+	if _, err := typ.pack(reflect.ValueOf(struct {
+		To    [32]byte
+		List  [][20]byte
+		Inner byteInner
+	}{})); err == nil {
+		t.Error("expected error for 32 byte address")
+	}
+	if _, err := typ.pack(reflect.ValueOf(struct {
+		To    [20]byte
+		List  [][20]byte
+		Inner struct{ A [19]byte }
+	}{List: [][20]byte{{}}})); err == nil {
+		t.Error("expected error for 19 byte nested address")
+	}
+}
+
+var staticPackArgs = []interface{}{
+	common.Address{1}, big.NewInt(1000), uint8(7), true, [32]byte{0xff},
+}
+
+func staticPackArguments(tb testing.TB) Arguments {
+	abi, err := JSON(strings.NewReader(`[{"type":"function","name":"f","inputs":[
+		{"name":"a","type":"address"},{"name":"b","type":"uint256"},{"name":"c","type":"uint8"},{"name":"d","type":"bool"},{"name":"e","type":"bytes32"}
+	]}]`))
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return abi.Methods["f"].Inputs
+}
+
+func TestPackStatic(t *testing.T) {
+	args := staticPackArguments(t)
+	if !args.isStatic() {
+		t.Fatal("arguments not detected as static")
+	}
+	static, err := args.packStatic(PackOptions{}, staticPackArgs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dynamic, err := args.packDynamic(PackOptions{}, staticPackArgs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(static, dynamic) {
+		t.Errorf("pack mismatch: have %x, want %x", static, dynamic)
+	}
+	str, _ := NewType("string", nil)
+	if (Arguments{{Type: str}}).isStatic() {
+		t.Error("string argument detected as static")
+	}
+}
+
+func BenchmarkPackStatic(b *testing.B) {
+	args := staticPackArguments(b)
+	b.Run("static", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			args.packStatic(PackOptions{}, staticPackArgs)
+		}
+	})
+	b.Run("general", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			args.packDynamic(PackOptions{}, staticPackArgs)
+		}
+	})
+}
+
+func TestPackTuplePositional(t *testing.T) {
+	typ, err := NewType("tuple", []ArgumentMarshaling{
+		{Name: "recipient", Type: "address"},
+		{Name: "amount", Type: "uint256"},
+		{Name: "memo", Type: "string"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := typ.pack(reflect.ValueOf(struct {
+		Recipient common.Address
+		Amount    *big.Int
+		Memo      string
+	}{common.Address{1}, big.NewInt(5), "hi"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Field names differ from the component names, so they are matched by order
+	packed, err := typ.pack(reflect.ValueOf(struct {
+		To    common.Address
+		Value *big.Int
+		Note  string
+	}{common.Address{1}, big.NewInt(5), "hi"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(packed, want) {
+		t.Errorf("pack mismatch: have %x, want %x", packed, want)
+	}
+	// Names and tags take priority over the field order
+	packed, err = typ.pack(reflect.ValueOf(struct {
+		Memo   string
+		Value  *big.Int       `abi:"amount"`
+		Target common.Address `abi:"recipient"`
+	}{"hi", big.NewInt(5), common.Address{1}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(packed, want) {
+		t.Errorf("tagged pack mismatch: have %x, want %x", packed, want)
+	}
+	// A partial name match or a differing field count doesn't fall back
+	if _, err := typ.pack(reflect.ValueOf(struct {
+		Recipient common.Address
+		Value     *big.Int
+		Note      string
+	}{common.Address{1}, big.NewInt(5), "hi"})); err == nil {
+		t.Error("expected error for partially matching field names")
+	}
+	if _, err := typ.pack(reflect.ValueOf(struct {
+		To    common.Address
+		Value *big.Int
+	}{common.Address{1}, big.NewInt(5)})); err == nil {
+		t.Error("expected error for differing field count")
+	}
+}
+
+func TestPackRawEncoded(t *testing.T) {
+	components := []ArgumentMarshaling{{Name: "name", Type: "string"}, {Name: "ids", Type: "uint256[]"}}
+	inner, err := NewType("tuple", components)
+	if err != nil {
+		t.Fatal(err)
+	}
+	typ, err := NewType("tuple", []ArgumentMarshaling{
+		{Name: "a", Type: "uint256"},
+		{Name: "b", Type: "tuple", Components: components},
+		{Name: "c", Type: "bytes32"},
+		{Name: "d", Type: "string"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	type innerT struct {
+		Name string
+		Ids  []*big.Int
+	}
+	value := innerT{"hello", []*big.Int{big.NewInt(1), big.NewInt(2)}}
+	want, err := typ.pack(reflect.ValueOf(struct {
+		A *big.Int
+		B innerT
+		C [32]byte
+		D string
+	}{big.NewInt(7), value, [32]byte{0xaa}, "tail"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := inner.pack(reflect.ValueOf(value))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Dynamic and static components can both be spliced in from raw encodings
+	packed, err := typ.pack(reflect.ValueOf(struct {
+		A *big.Int
+		B RawEncoded
+		C RawEncoded
+		D string
+	}{big.NewInt(7), raw, common.RightPadBytes([]byte{0xaa}, 32), "tail"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(packed, want) {
+		t.Errorf("pack mismatch: have %x, want %x", packed, want)
+	}
+	// Top level arguments accept raw encodings too
+	str, _ := NewType("string", nil)
+	args := Arguments{{Name: "b", Type: inner}, {Name: "d", Type: str}}
+	want, err = args.Pack(value, "tail")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if packed, err = args.Pack(RawEncoded(raw), "tail"); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(packed, want) {
+		t.Errorf("argument pack mismatch: have %x, want %x", packed, want)
+	}
+	// Raw encodings of the wrong size are rejected
+	if _, err := args.Pack(RawEncoded(raw[:31]), "tail"); err == nil {
+		t.Error("expected error for unaligned raw encoding")
+	}
+	if _, err := typ.pack(reflect.ValueOf([]interface{}{big.NewInt(7), RawEncoded(raw), RawEncoded(make([]byte, 64)), "tail"})); err == nil {
+		t.Error("expected error for oversized static raw encoding")
+	}
+}
+
+func TestEncodedSize(t *testing.T) {
+	str, _ := NewType("string", nil)
+	for i, test := range packTests {
+		typ, err := NewType(test.typ, test.components)
+		if err != nil {
+			t.Fatalf("%v failed. Unexpected parse error: %v", i, err)
+		}
+		// Pair every value with a dynamic argument to cover the offsets too
+		args := Arguments{{Name: "a", Type: typ}, {Name: "b", Type: str}}
+		packed, err := args.Pack(test.input, "trailing")
+		if err != nil {
+			t.Fatalf("%v failed. Unexpected pack error: %v", i, err)
+		}
+		size, err := args.EncodedSize(test.input, "trailing")
+		if err != nil {
+			t.Fatalf("%v failed. Unexpected size error: %v", i, err)
+		}
+		if size != len(packed) {
+			t.Errorf("input %d for typ: %v: size mismatch: have %d, want %d", i, typ, size, len(packed))
+		}
+		// The offset of the trailing argument must account for the first one
+		values, err := args.UnpackValues(packed)
+		if err != nil {
+			t.Fatalf("%v failed. Unexpected unpack error: %v", i, err)
+		}
+		if values[1] != "trailing" {
+			t.Errorf("input %d for typ: %v: trailing argument mismatch: have %q", i, typ, values[1])
+		}
+	}
+	abi, err := JSON(strings.NewReader(`[{"type":"function","name":"f","inputs":[
+		{"name":"a","type":"bytes[]"},{"name":"b","type":"uint8"},{"name":"c","type":"tuple[]","components":[{"name":"x","type":"string"},{"name":"y","type":"uint256[2]"}]},{"name":"d","type":"string[2]"}
+	]}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	type tuple struct {
+		X string
+		Y [2]*big.Int
+	}
+	args := []interface{}{
+		[][]byte{{1}, make([]byte, 33), {}},
+		uint8(1),
+		[]tuple{{"a", [2]*big.Int{big.NewInt(1), big.NewInt(2)}}, {strings.Repeat("b", 70), [2]*big.Int{big.NewInt(3), big.NewInt(4)}}},
+		[2]string{"", "c"},
+	}
+	packed, err := abi.Methods["f"].Inputs.Pack(args...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	size, err := abi.Methods["f"].Inputs.EncodedSize(args...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size != len(packed) {
+		t.Errorf("size mismatch: have %d, want %d", size, len(packed))
+	}
+	if _, err := abi.Methods["f"].Inputs.EncodedSize(args[:2]...); err == nil {
+		t.Error("expected error for argument count mismatch")
+	}
+}
+
+func TestPackDuration(t *testing.T) {
+	u256, _ := NewType("uint256", nil)
+	u64, _ := NewType("uint64", nil)
+	u8, _ := NewType("uint8", nil)
+	i32, _ := NewType("int32", nil)
+	i64, _ := NewType("int64", nil)
+
+	var table = []struct {
+		typ      Type
+		input    time.Duration
+		seconds  bool
+		truncate bool
+		want     *big.Int // nil if packing must fail
+	}{
+		// By default durations pack as nanoseconds into every integer type
+		{i64, 2 * time.Second, false, false, big.NewInt(2e9)},
+		{u64, 2 * time.Second, false, false, big.NewInt(2e9)},
+		{u256, 2 * time.Second, false, false, big.NewInt(2e9)},
+		{u256, 1500 * time.Millisecond, false, false, big.NewInt(15e8)},
+		{i32, -time.Minute, false, false, nil},
+		{u256, -time.Second, false, false, nil},
+		// or as seconds if requested, again for every integer type
+		{i64, 2 * time.Second, true, false, big.NewInt(2)},
+		{u64, 2 * time.Second, true, false, big.NewInt(2)},
+		{u256, 0, true, false, big.NewInt(0)},
+		{u256, time.Hour, true, false, big.NewInt(3600)},
+		{u256, 90 * time.Second, true, true, big.NewInt(90)},
+		{u256, 1500 * time.Millisecond, true, false, nil},
+		{u256, 1500 * time.Millisecond, true, true, big.NewInt(1)},
+		{u256, time.Nanosecond, true, true, big.NewInt(0)},
+		{u256, -time.Second, true, false, nil},
+		{u8, 255 * time.Second, true, false, big.NewInt(255)},
+		{u8, 256 * time.Second, true, false, nil},
+		{i32, -time.Minute, true, false, big.NewInt(-60)},
+		{i64, -time.Minute, true, true, big.NewInt(-60)},
+	}
+	for i, test := range table {
+		opts := PackOptions{DurationsAsSeconds: test.seconds, TruncateDurations: test.truncate}
+		packed, err := test.typ.packWithOptions(reflect.ValueOf(test.input), opts)
+		if test.want == nil {
+			if err == nil {
+				t.Errorf("test %d: expected error packing %v as %v", i, test.input, test.typ)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test %d: unexpected error: %v", i, err)
+			continue
+		}
+		if want := U256(new(big.Int).Set(test.want)); !bytes.Equal(packed, want) {
+			t.Errorf("test %d: pack mismatch: have %x, want %x", i, packed, want)
+		}
+	}
+}
+
+func TestPackBoolIntegerMismatch(t *testing.T) {
+	for _, test := range []struct {
+		typ   string
+		input interface{}
+		err   string
+	}{
+		{"uint256", true, "abi: cannot pack bool into uint256 arg"},
+		{"int8", false, "abi: cannot pack bool into int8 arg"},
+		{"bool", 1, "abi: cannot pack int into bool arg"},
+		{"bool", uint8(0), "abi: cannot pack uint8 into bool arg"},
+		{"bool", big.NewInt(1), "abi: cannot pack *big.Int into bool arg"},
+		{"uint64[]", []bool{true}, "abi: cannot pack []bool into uint64[] arg"},
+		{"bool[2]", [2]int{0, 1}, "abi: cannot pack [2]int into bool[2] arg"},
+	} {
+		typ, err := NewType(test.typ, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = typ.pack(reflect.ValueOf(test.input))
+		if err == nil || err.Error() != test.err {
+			t.Errorf("%s from %T: error mismatch: have %v, want %s", test.typ, test.input, err, test.err)
+		}
+		if !errors.Is(err, ErrTypeMismatch) {
+			t.Errorf("%s from %T: error kind mismatch: have %v", test.typ, test.input, err)
+		}
+	}
+}
+
+func TestPackUnsignedIntoSigned(t *testing.T) {
+	i8, _ := NewType("int8", nil)
+	i64, _ := NewType("int64", nil)
+	i256, _ := NewType("int256", nil)
+
+	var table = []struct {
+		typ   Type
+		input interface{}
+		wrap  bool
+		want  *big.Int // nil if packing must fail
+	}{
+		{i64, uint64(42), false, big.NewInt(42)},
+		{i64, uint64(math.MaxInt64), false, big.NewInt(math.MaxInt64)},
+		{i64, uint64(math.MaxUint64), false, nil},
+		{i64, uint64(math.MaxUint64), true, big.NewInt(-1)},
+		{i64, uint64(1 << 63), true, big.NewInt(math.MinInt64)},
+		{i256, uint64(math.MaxUint64), false, new(big.Int).SetUint64(math.MaxUint64)},
+		{i256, uint64(math.MaxUint64), true, new(big.Int).SetUint64(math.MaxUint64)},
+		{i8, uint8(200), false, nil},
+		{i8, uint8(200), true, big.NewInt(-56)},
+		{i8, uint16(300), true, nil},
+		{i8, uint(127), false, big.NewInt(127)},
+	}
+	for i, test := range table {
+		packed, err := test.typ.packWithOptions(reflect.ValueOf(test.input), PackOptions{WrapUnsigned: test.wrap})
+		if test.want == nil {
+			if err == nil {
+				t.Errorf("test %d: expected error packing %v as %v", i, test.input, test.typ)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test %d: unexpected error: %v", i, err)
+			continue
+		}
+		if want := U256(new(big.Int).Set(test.want)); !bytes.Equal(packed, want) {
+			t.Errorf("test %d: pack mismatch: have %x, want %x", i, packed, want)
+		}
+	}
+}
+
+func TestPackEmbeddedStruct(t *testing.T) {
+	typ, err := NewType("tuple", []ArgumentMarshaling{
+		{Name: "owner", Type: "address"},
+		{Name: "amount", Type: "uint256"},
+		{Name: "memo", Type: "string"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	type plain struct {
+		Owner  common.Address
+		Amount *big.Int
+		Memo   string
+	}
+	want, err := typ.pack(reflect.ValueOf(plain{common.Address{1}, big.NewInt(2), "memo"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Promoted fields are matched by name and by abi tag
+	type base struct {
+		Owner common.Address
+		Value *big.Int `abi:"amount"`
+	}
+	type embedded struct {
+		base
+		Memo string
+	}
+	value := embedded{base{common.Address{1}, big.NewInt(2)}, "memo"}
+	packed, err := typ.pack(reflect.ValueOf(value))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(packed, want) {
+		t.Errorf("pack mismatch: have %x, want %x", packed, want)
+	}
+	// Shallower fields shadow promoted ones of the same name
+	type shadowed struct {
+		base
+		Owner common.Address
+		Memo  string
+	}
+	packed, err = typ.pack(reflect.ValueOf(shadowed{base{common.Address{9}, big.NewInt(2)}, common.Address{1}, "memo"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(packed, want) {
+		t.Errorf("shadowed pack mismatch: have %x, want %x", packed, want)
+	}
+	// Unpacking fills the promoted fields as well
+	args := Arguments{{Name: "entry", Type: typ}}
+	var decoded struct {
+		Entry embedded
+	}
+	if err := args.Unpack(&decoded, append(common.LeftPadBytes([]byte{0x20}, 32), want...)); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded.Entry, value) {
+		t.Errorf("unpack mismatch: have %+v, want %+v", decoded.Entry, value)
+	}
+}
+
+func TestPackMixedcaseAddress(t *testing.T) {
+	typ, _ := NewType("address", nil)
+	want := common.LeftPadBytes(common.HexToAddress("0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed").Bytes(), 32)
+
+	for i, test := range []struct {
+		address string
+		valid   bool
+	}{
+		{"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed", true},
+		{"0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed", true},
+		{"0x5AAEB6053F3E94C9B9A09F33669435E7EF1BEAED", true},
+		{"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAeD", false},
+		{"0x5AAeb6053F3E94C9b9A09f33669435E7Ef1BeAed", false},
+	} {
+		addr, err := common.NewMixedcaseAddressFromString(test.address)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, input := range []interface{}{*addr, addr} {
+			packed, err := typ.pack(reflect.ValueOf(input))
+			if !test.valid {
+				if err == nil {
+					t.Errorf("test %d: expected checksum error for %s", i, test.address)
+				}
+				continue
+			}
+			if err != nil {
+				t.Errorf("test %d: unexpected error: %v", i, err)
+				continue
+			}
+			if !bytes.Equal(packed, want) {
+				t.Errorf("test %d: pack mismatch: have %x, want %x", i, packed, want)
+			}
+		}
+	}
+}
+
+func TestPackAddressStrings(t *testing.T) {
+	abi, err := JSON(strings.NewReader(`[{"type":"function","name":"f","inputs":[{"name":"a","type":"address[]"},{"name":"b","type":"address[2]"}]}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var (
+		first  = "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed"
+		second = "0xfb6916095ca1df60bb79ce92ce3ea74c37c5d359"
+		addrs  = []common.Address{common.HexToAddress(first), common.HexToAddress(second)}
+	)
+	have, err := abi.Pack("f", []string{first, second}, [2]string{second, first})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := abi.MustPack("f", addrs, [2]common.Address{addrs[1], addrs[0]}); !bytes.Equal(have, want) {
+		t.Errorf("pack mismatch:\nhave %x\nwant %x", have, want)
+	}
+	for _, test := range []struct {
+		addrs []string
+		err   string
+	}{
+		{[]string{first, "0x1234"}, `abi: invalid address "0x1234" at index 1`},
+		{[]string{"0x5AAeb6053F3E94C9b9A09f33669435E7Ef1BeAed"}, "abi: invalid checksum in address 0x5AAeb6053F3E94C9b9A09f33669435E7Ef1BeAed at index 0"},
+	} {
+		if _, err := abi.Pack("f", test.addrs, [2]string{first, second}); err == nil || err.Error() != test.err {
+			t.Errorf("error mismatch: have %v, want %s", err, test.err)
+		}
+	}
+}
+
+func TestPackMaxDynamicLength(t *testing.T) {
+	abi, err := JSON(strings.NewReader(`[
+		{"type":"function","name":"post","inputs":[{"name":"data","type":"bytes"},{"name":"tags","type":"string[]"}]}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := PackOptions{MaxDynamicLength: 4}
+	if _, err := abi.PackWithOptions(opts, "post", []byte{1, 2, 3, 4}, []string{"ok", "fine"}); err != nil {
+		t.Fatalf("values within limit rejected: %v", err)
+	}
+	if _, err := abi.PackWithOptions(opts, "post", []byte{1, 2, 3, 4, 5}, []string{}); err == nil {
+		t.Error("expected error for bytes exceeding limit")
+	}
+	if _, err := abi.PackWithOptions(opts, "post", []byte{}, []string{"ok", "too long"}); err == nil {
+		t.Error("expected error for nested string exceeding limit")
+	}
+	// No limit applies by default
+	if _, err := abi.Pack("post", make([]byte, 1024), []string{}); err != nil {
+		t.Errorf("default pack rejected long bytes: %v", err)
+	}
+}
+
+type lazyValue struct {
+	value interface{}
+	err   error
+}
+
+func (l lazyValue) ABIValue() (interface{}, error) { return l.value, l.err }
+
+func TestPackValueProvider(t *testing.T) {
+	abi, err := JSON(strings.NewReader(`[
+		{"type":"function","name":"set","inputs":[{"name":"id","type":"uint256"},{"name":"name","type":"string"}]}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := abi.Pack("set", big.NewInt(42), "answer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	packed, err := abi.Pack("set", lazyValue{value: big.NewInt(42)}, &lazyValue{value: "answer"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(packed, want) {
+		t.Errorf("pack mismatch: have %x, want %x", packed, want)
+	}
+	_, err = abi.Pack("set", lazyValue{err: errors.New("expensive computation failed")}, "answer")
+	if err == nil || !strings.Contains(err.Error(), "expensive computation failed") {
+		t.Errorf("provider error not propagated: %v", err)
+	}
+}
+
+// ticker is a domain type packed through its fmt.Stringer implementation.
+type ticker struct{ base, quote string }
+
+func (t ticker) String() string { return t.base + "/" + t.quote }
+
+func TestPackStringer(t *testing.T) {
+	abi, err := JSON(strings.NewReader(`[{"type":"function","name":"f","inputs":[{"name":"s","type":"string"},{"name":"b","type":"bytes"}]}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := abi.MustPack("f", "ETH/DAI", []byte("ETH/DAI"))
+	pair := ticker{"ETH", "DAI"}
+	for _, args := range [][]interface{}{{pair, pair}, {&pair, &pair}} {
+		have, err := abi.Pack("f", args...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(have, want) {
+			t.Errorf("pack mismatch for %T:\nhave %x\nwant %x", args[0], have, want)
+		}
+	}
+	// Byte arrays are not packed as their hex text
+	if _, err := abi.Pack("f", pair, common.Hash{1}); err == nil {
+		t.Error("expected error for hash as bytes")
+	}
+	if _, err := abi.Pack("f", (*ticker)(nil), pair); err == nil {
+		t.Error("expected error for nil stringer")
+	}
+}
+
+func TestPackJSONRawMessage(t *testing.T) {
+	abi, err := JSON(strings.NewReader(`[
+		{"type":"function","name":"set","inputs":[{"name":"amount","type":"uint256"},{"name":"delta","type":"int8"}]}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := abi.Pack("set", big.NewInt(1000), int8(-5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, args := range [][]interface{}{
+		{json.RawMessage(`1000`), json.RawMessage(`-5`)},
+		{json.RawMessage(`"1000"`), json.RawMessage(`"-5"`)},
+		{json.RawMessage(` "0x3e8" `), json.RawMessage(`-5`)},
+	} {
+		packed, err := abi.Pack("set", args...)
+		if err != nil {
+			t.Errorf("test %d: pack failed: %v", i, err)
+			continue
+		}
+		if !bytes.Equal(packed, want) {
+			t.Errorf("test %d: pack mismatch: have %x, want %x", i, packed, want)
+		}
+	}
+	for i, args := range [][]interface{}{
+		{json.RawMessage(`1000`), json.RawMessage(`128`)},
+		{json.RawMessage(`-1`), json.RawMessage(`0`)},
+		{json.RawMessage(`1.5`), json.RawMessage(`0`)},
+		{json.RawMessage(`true`), json.RawMessage(`0`)},
+	} {
+		if _, err := abi.Pack("set", args...); err == nil {
+			t.Errorf("test %d: expected error", i)
+		}
+	}
+}
+
+func TestPackTupleSliceFromMaps(t *testing.T) {
+	abi, err := JSON(strings.NewReader(`[
+		{"type":"function","name":"batch","inputs":[{"name":"orders","type":"tuple[]","components":[{"name":"maker","type":"address"},{"name":"amount","type":"uint256"},{"name":"memo","type":"string"}]}]}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	type order struct {
+		Maker  common.Address
+		Amount *big.Int
+		Memo   string
+	}
+	want, err := abi.Pack("batch", []order{{common.Address{1}, big.NewInt(10), "a"}, {common.Address{2}, big.NewInt(20), "bc"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	orders := []map[string]interface{}{
+		{"memo": "a", "amount": big.NewInt(10), "maker": common.Address{1}},
+		{"maker": common.Address{2}, "memo": "bc", "amount": big.NewInt(20)},
+	}
+	packed, err := abi.Pack("batch", orders)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(packed, want) {
+		t.Errorf("pack mismatch: have %x, want %x", packed, want)
+	}
+	// The shape of decoded JSON arrays of objects works as well
+	generic := []interface{}{
+		map[string]interface{}{"maker": common.Address{1}, "amount": big.NewInt(10), "memo": "a"},
+		map[string]interface{}{"maker": common.Address{2}, "amount": big.NewInt(20), "memo": "bc"},
+	}
+	if packed, err = abi.Pack("batch", generic); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(packed, want) {
+		t.Errorf("generic pack mismatch: have %x, want %x", packed, want)
+	}
+	orders[1] = map[string]interface{}{"maker": common.Address{2}, "memo": "bc"}
+	if _, err := abi.Pack("batch", orders); err == nil {
+		t.Error("expected error for missing component")
+	}
+}
+
+func TestPackIntegerAliasArrays(t *testing.T) {
+	values := []*big.Int{big.NewInt(-1), big.NewInt(2), new(big.Int).Lsh(common.Big1, 200)}
+	for _, alias := range [][2]string{{"int[]", "int256[]"}, {"uint[2]", "uint256[2]"}, {"int[][]", "int256[][]"}} {
+		short, err := NewType(alias[0], nil)
+		if err != nil {
+			t.Fatalf("%s: %v", alias[0], err)
+		}
+		long, err := NewType(alias[1], nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if short.String() != long.String() {
+			t.Errorf("%s: canonical type mismatch: have %s, want %s", alias[0], short, long)
+		}
+		var input interface{}
+		switch alias[0] {
+		case "int[]":
+			input = values
+		case "uint[2]":
+			input = [2]*big.Int{values[1], values[2]}
+		default:
+			input = [][]*big.Int{values, values[:1]}
+		}
+		have, err := short.pack(reflect.ValueOf(input))
+		if err != nil {
+			t.Fatalf("%s: %v", alias[0], err)
+		}
+		want, err := long.pack(reflect.ValueOf(input))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(have, want) {
+			t.Errorf("%s: pack mismatch: have %x, want %x", alias[0], have, want)
+		}
+	}
+	// The aliases resolve to the canonical types in selectors as well
+	abi, err := JSON(strings.NewReader(`[{"type":"function","name":"f","inputs":[{"name":"a","type":"uint[]"}]}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sig := abi.Methods["f"].Sig(); sig != "f(uint256[])" {
+		t.Errorf("signature mismatch: have %s, want f(uint256[])", sig)
+	}
+}
+
+func TestPackIntegerAliasTuple(t *testing.T) {
+	abi, err := JSON(strings.NewReader(`[
+		{"type":"function","name":"f","inputs":[{"name":"p","type":"tuple","components":[{"name":"x","type":"uint"},{"name":"y","type":"int"}]}]},
+		{"type":"function","name":"g","inputs":[{"name":"p","type":"tuple","components":[{"name":"x","type":"uint256"},{"name":"y","type":"int256"}]}]}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, g := abi.Methods["f"], abi.Methods["g"]
+	if f.Sig() != "f((uint256,int256))" {
+		t.Errorf("signature mismatch: have %s, want f((uint256,int256))", f.Sig())
+	}
+	point := struct {
+		X *big.Int
+		Y *big.Int
+	}{big.NewInt(1), big.NewInt(-2)}
+	have, err := f.Inputs.Pack(point)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := g.Inputs.MustPack(point); !bytes.Equal(have, want) {
+		t.Errorf("pack mismatch: have %x, want %x", have, want)
+	}
+	// The parenthesized form normalizes the aliases the same way
+	typ, err := NewType("(uint,int)", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if typ.String() != "(uint256,int256)" {
+		t.Errorf("type mismatch: have %s, want (uint256,int256)", typ)
+	}
+}
+
+// packedEnum is a named uint8 type, like the Go representation of a Solidity enum.
+type packedEnum uint8
+
+func TestPackNamedIntegerArrays(t *testing.T) {
+	abi, err := JSON(strings.NewReader(`[{"type":"function","name":"f","inputs":[{"name":"a","type":"uint8[]"},{"name":"b","type":"uint8[2]"}]}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := abi.Pack("f", []uint8{0, 2, 1}, [2]uint8{3, 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	have, err := abi.Pack("f", []packedEnum{0, 2, 1}, [2]packedEnum{3, 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(have, want) {
+		t.Errorf("pack mismatch:\nhave %x\nwant %x", have, want)
+	}
+	if _, err := abi.Pack("f", []packedEnum{1}, [2]int8{3, 4}); err == nil {
+		t.Error("expected error for element kind mismatch")
+	}
+}
+
+func TestPackTupleValueProviderField(t *testing.T) {
+	typ, err := NewType("tuple", []ArgumentMarshaling{
+		{Name: "owner", Type: "address"},
+		{Name: "amount", Type: "uint256"},
+		{Name: "memo", Type: "string"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := typ.pack(reflect.ValueOf(struct {
+		Owner  common.Address
+		Amount *big.Int
+		Memo   string
+	}{common.Address{1}, big.NewInt(5), "hi"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	packed, err := typ.pack(reflect.ValueOf(struct {
+		Owner  common.Address
+		Amount lazyValue
+		Memo   *lazyValue
+	}{common.Address{1}, lazyValue{value: big.NewInt(5)}, &lazyValue{value: "hi"}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(packed, want) {
+		t.Errorf("pack mismatch: have %x, want %x", packed, want)
+	}
+	// Components of tuple arrays are resolved as well
+	arrayTyp, err := NewType("tuple[]", []ArgumentMarshaling{{Name: "amount", Type: "uint256"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ = arrayTyp.pack(reflect.ValueOf([]struct{ Amount *big.Int }{{big.NewInt(1)}, {big.NewInt(2)}}))
+	packed, err = arrayTyp.pack(reflect.ValueOf([]struct{ Amount lazyValue }{{lazyValue{value: big.NewInt(1)}}, {lazyValue{value: big.NewInt(2)}}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(packed, want) {
+		t.Errorf("array pack mismatch: have %x, want %x", packed, want)
+	}
+	_, err = typ.pack(reflect.ValueOf(struct {
+		Owner  common.Address
+		Amount lazyValue
+		Memo   string
+	}{common.Address{1}, lazyValue{err: errors.New("rate unavailable")}, "hi"}))
+	if err == nil || !strings.Contains(err.Error(), "rate unavailable") {
+		t.Errorf("component error not propagated: %v", err)
+	}
+}
+
+func TestPackStrictUTF8(t *testing.T) {
+	abi, err := JSON(strings.NewReader(`[
+		{"type":"function","name":"post","inputs":[{"name":"text","type":"string"},{"name":"tags","type":"string[]"}]}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid, invalid := "héllo wörld ✓", "bad \xff\xfe bytes"
+	strict := PackOptions{StrictUTF8: true}
+
+	for _, opts := range []PackOptions{{}, strict} {
+		if _, err := abi.PackWithOptions(opts, "post", valid, []string{valid}); err != nil {
+			t.Errorf("strict %v: valid UTF-8 rejected: %v", opts.StrictUTF8, err)
+		}
+	}
+	// Strings are packed byte for byte by default
+	packed, err := abi.Pack("post", invalid, []string{})
+	if err != nil {
+		t.Fatalf("invalid UTF-8 rejected by default: %v", err)
+	}
+	if !bytes.Contains(packed, []byte(invalid)) {
+		t.Errorf("string bytes not preserved: %x", packed)
+	}
+	if _, err := abi.PackWithOptions(strict, "post", invalid, []string{}); err == nil {
+		t.Error("expected error for invalid UTF-8 in strict mode")
+	}
+	if _, err := abi.PackWithOptions(strict, "post", valid, []string{valid, invalid}); err == nil {
+		t.Error("expected error for invalid UTF-8 array element in strict mode")
+	}
+}
+
+func TestPackStaticTupleArray(t *testing.T) {
+	abi, err := JSON(strings.NewReader(`[
+		{"type":"function","name":"f","inputs":[
+			{"name":"pairs","type":"tuple[2]","components":[{"name":"x","type":"uint256"},{"name":"y","type":"uint256"}]},
+			{"name":"memo","type":"string"}
+		]}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	type pair struct {
+		X *big.Int
+		Y *big.Int
+	}
+	args := abi.Methods["f"].Inputs
+	pairs := [2]pair{{big.NewInt(1), big.NewInt(2)}, {big.NewInt(3), big.NewInt(4)}}
+	packed, err := args.Pack(pairs, "x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The array is encoded in place as its four words, so the string starts
+	// right after the five head words
+	if offset := new(big.Int).SetBytes(packed[128:160]); offset.Int64() != 160 {
+		t.Errorf("string offset mismatch: have %v, want 160", offset)
+	}
+	var decoded struct {
+		Pairs [2]pair
+		Memo  string
+	}
+	if err := args.Unpack(&decoded, packed); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded.Pairs, pairs) || decoded.Memo != "x" {
+		t.Errorf("round trip mismatch: have %+v", decoded)
+	}
+}
+
+func TestPackNestedStaticTuple(t *testing.T) {
+	abi, err := JSON(strings.NewReader(`[
+		{"type":"function","name":"f","inputs":[
+			{"name":"outer","type":"tuple","components":[
+				{"name":"a","type":"uint8"},
+				{"name":"mid","type":"tuple","components":[
+					{"name":"b","type":"address"},
+					{"name":"inner","type":"tuple","components":[{"name":"c","type":"bool"},{"name":"d","type":"bytes2"}]}
+				]},
+				{"name":"e","type":"int16"}
+			]},
+			{"name":"memo","type":"string"}
+		]}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	type inner struct {
+		C bool
+		D [2]byte
+	}
+	type mid struct {
+		B     common.Address
+		Inner inner
+	}
+	type outer struct {
+		A   uint8
+		Mid mid
+		E   int16
+	}
+	args := abi.Methods["f"].Inputs
+	if args[0].Type.IsDynamic() {
+		t.Fatal("nested static tuple reported as dynamic")
+	}
+	value := outer{7, mid{common.Address{1}, inner{true, [2]byte{0xab, 0xcd}}}, -2}
+	packed, err := args.Pack(value, "x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The tuple is encoded in place as its five words, followed by the offset
+	// of the string, which starts right after the six head words
+	want := common.Hex2Bytes(
+		"0000000000000000000000000000000000000000000000000000000000000007" +
+			"0000000000000000000000000100000000000000000000000000000000000000" +
+			"0000000000000000000000000000000000000000000000000000000000000001" +
+			"abcd000000000000000000000000000000000000000000000000000000000000" +
+			"fffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffe" +
+			"00000000000000000000000000000000000000000000000000000000000000c0" +
+			"0000000000000000000000000000000000000000000000000000000000000001" +
+			"7800000000000000000000000000000000000000000000000000000000000000")
+	if !bytes.Equal(packed, want) {
+		t.Errorf("encoding mismatch:\nhave %x\nwant %x", packed, want)
+	}
+	var decoded struct {
+		Outer outer
+		Memo  string
+	}
+	if err := args.Unpack(&decoded, packed); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Outer != value || decoded.Memo != "x" {
+		t.Errorf("round trip mismatch: have %+v", decoded)
+	}
+}
+
+func TestPackHead(t *testing.T) {
+	abi, err := JSON(strings.NewReader(`[{"type":"function","name":"execute","inputs":[
+		{"name":"from","type":"address"},{"name":"nonce","type":"uint256"},{"name":"to","type":"address"},{"name":"data","type":"bytes"}
+	]}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var (
+		inputs = abi.Methods["execute"].Inputs
+		from   = common.HexToAddress("0x1111111111111111111111111111111111111111")
+		to     = common.HexToAddress("0x2222222222222222222222222222222222222222")
+	)
+	head, err := inputs.PackHead(2, from, big.NewInt(7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(head) != 64 {
+		t.Fatalf("head length mismatch: have %d, want 64", len(head))
+	}
+	full := inputs.MustPack(from, big.NewInt(7), to, []byte{0xca, 0xfe})
+	if !bytes.Equal(head, full[:len(head)]) {
+		t.Errorf("head is not a prefix of the full encoding:\nhave %x\nwant %x", head, full[:len(head)])
+	}
+	if _, err := inputs.PackHead(2, from); err == nil {
+		t.Error("expected error for argument count mismatch")
+	}
+	if _, err := inputs.PackHead(4, from, big.NewInt(7), to, []byte{}); err == nil {
+		t.Error("expected error for dynamic argument in head")
+	}
+	if _, err := inputs.PackHead(5); err == nil {
+		t.Error("expected error for head longer than the arguments")
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package abi
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// PackPacked packs the arguments using Solidity's non-standard packed mode, as
+// done by abi.encodePacked. Values are concatenated using the natural width of
+// their types: integers take their size in bytes, addresses 20 bytes and bools
+// a single byte. Strings and bytes are inserted without padding or length. The
+// elements of arrays are padded to 32 bytes each, without length either.
+//
+// The encoding is ambiguous and not meant to be decoded. Tuples and arrays of
+// dynamic types or of other arrays are not supported, as Solidity doesn't
+// support them in packed mode either.
+func (arguments Arguments) PackPacked(args ...interface{}) ([]byte, error) {
+	if len(args) != len(arguments) {
+		return nil, errorf(ErrArity, "argument count mismatch: %d for %d", len(args), len(arguments))
+	}
+	var ret []byte
+	for i, arg := range arguments {
+		packed, err := arg.Type.packPacked(reflect.ValueOf(args[i]))
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, packed...)
+	}
+	return ret, nil
+}
+
+// packPacked packs the value as type t in Solidity's non-standard packed mode.
+func (t Type) packPacked(v reflect.Value) ([]byte, error) {
+	v, err := resolveValue(v)
+	if err != nil {
+		return nil, err
+	}
+	switch t.T {
+	case StringTy, BytesTy:
+		v = indirect(v)
+		if err := typeCheck(t, v); err != nil {
+			return nil, err
+		}
+		if v.Kind() == reflect.String {
+			return []byte(v.String()), nil
+		}
+		if v.Kind() == reflect.Array {
+			v = mustArrayToByteSlice(v)
+		}
+		return v.Bytes(), nil
+
+	case SliceTy, ArrayTy:
+		if isDynamicType(*t.Elem) || t.Elem.T == ArrayTy || t.Elem.T == TupleTy {
+			return nil, fmt.Errorf("abi: %v is not supported in packed mode", t)
+		}
+		v = indirect(v)
+		if err := typeCheck(t, v); err != nil {
+			return nil, err
+		}
+		var ret []byte
+		for i := 0; i < v.Len(); i++ {
+			packed, err := t.Elem.pack(v.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			ret = append(ret, packed...)
+		}
+		return ret, nil
+
+	case TupleTy:
+		return nil, fmt.Errorf("abi: %v is not supported in packed mode", t)
+	}
+	// Static values are cut out of their regular 32 byte encoding
+	packed, err := t.pack(v)
+	if err != nil {
+		return nil, err
+	}
+	switch t.T {
+	case IntTy, UintTy, FixedPointTy:
+		return packed[32-t.Size/8:], nil
+	case BoolTy:
+		return packed[31:], nil
+	case AddressTy:
+		return packed[12:], nil
+	case FixedBytesTy:
+		return packed[:t.Size], nil
+	case FunctionTy:
+		return packed[:24], nil
+	}
+	return nil, fmt.Errorf("abi: %v is not supported in packed mode", t)
+}
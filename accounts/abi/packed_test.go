@@ -0,0 +1,91 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package abi
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/enode/common"
+)
+
+func packedArguments(t *testing.T, types ...string) Arguments {
+	args := make(Arguments, len(types))
+	for i, typ := range types {
+		parsed, err := NewType(typ, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		args[i] = Argument{Type: parsed}
+	}
+	return args
+}
+
+func TestPackPacked(t *testing.T) {
+	var table = []struct {
+		types  []string
+		values []interface{}
+		want   string
+	}{
+		// Example from the Solidity documentation
+		{
+			[]string{"int16", "bytes1", "uint16", "string"},
+			[]interface{}{int16(-1), [1]byte{0x42}, uint16(3), "Hello, world!"},
+			"ffff42000348656c6c6f2c20776f726c6421",
+		},
+		{
+			[]string{"address", "uint8", "bool", "uint256[]"},
+			[]interface{}{common.HexToAddress("0x00000000000000000000000000000000000000ff"), uint8(5), true, []*big.Int{big.NewInt(1), big.NewInt(2)}},
+			"00000000000000000000000000000000000000ff" + "05" + "01" +
+				"0000000000000000000000000000000000000000000000000000000000000001" +
+				"0000000000000000000000000000000000000000000000000000000000000002",
+		},
+		{
+			[]string{"bytes", "int256", "uint24", "bool[2]"},
+			[]interface{}{[]byte{0xde, 0xad}, big.NewInt(-2), "0x010203", [2]bool{false, true}},
+			"dead" + "fffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffe" + "010203" +
+				"0000000000000000000000000000000000000000000000000000000000000000" +
+				"0000000000000000000000000000000000000000000000000000000000000001",
+		},
+	}
+	for i, test := range table {
+		packed, err := packedArguments(t, test.types...).PackPacked(test.values...)
+		if err != nil {
+			t.Errorf("test %d: unexpected error: %v", i, err)
+			continue
+		}
+		if want := common.Hex2Bytes(test.want); !bytes.Equal(packed, want) {
+			t.Errorf("test %d: pack mismatch: have %x, want %x", i, packed, want)
+		}
+	}
+	// Nested dynamic types are not supported, neither are invalid values
+	for i, test := range []struct {
+		typ   string
+		value interface{}
+	}{
+		{"string[]", []string{"a"}},
+		{"uint8[2][]", [][2]uint8{{1, 2}}},
+		{"(uint8,bool)", []interface{}{uint8(1), true}},
+		{"uint8", uint16(1)},
+		{"uint8", "256"},
+	} {
+		if _, err := packedArguments(t, test.typ).PackPacked(test.value); err == nil {
+			t.Errorf("test %d: expected error packing %v as %s", i, test.value, test.typ)
+		}
+	}
+}
@@ -17,15 +17,22 @@
 package abi
 
 import (
+	"bytes"
 	"fmt"
+	"io"
+	gmath "math"
+	"math/big"
 	"reflect"
 	"strings"
+	"time"
+
+	"github.com/enode/common"
 )
 
-// indirect recursively dereferences the value until it either gets the value
-// or finds a big.Int
+// indirect recursively dereferences the value until it either gets the value,
+// finds a big.Int or hits a nil pointer
 func indirect(v reflect.Value) reflect.Value {
-	if v.Kind() == reflect.Ptr && v.Elem().Type() != derefbigT {
+	if v.Kind() == reflect.Ptr && !v.IsNil() && v.Elem().Type() != derefbigT {
 		return indirect(v.Elem())
 	}
 	return v
@@ -83,11 +90,203 @@ func set(dst, src reflect.Value) error {
 	case dstType.Kind() == reflect.Slice && srcType.Kind() == reflect.Slice:
 		return setSlice(dst, src)
 	default:
-		return fmt.Errorf("abi: cannot unmarshal %v in to %v", src.Type(), dst.Type())
+		return errorf(ErrTypeMismatch, "abi: cannot unmarshal %v in to %v", src.Type(), dst.Type())
+	}
+	return nil
+}
+
+// requiresConversion returns whether decoded values of type t can't be assigned
+// as they are, but have to be converted element by element.
+func requiresConversion(t *Type) bool {
+	switch t.T {
+	case TupleTy, FixedPointTy:
+		return true
+	case SliceTy, ArrayTy:
+		return requiresConversion(t.Elem)
+	}
+	return false
+}
+
+// setFixedPoint assigns the decoded fixed point value src, the raw integer, to
+// dst. Rational destinations receive the value scaled down by the type's
+// decimals, string destinations its decimal representation.
+func setFixedPoint(t *Type, dst, src reflect.Value) error {
+	rat := func() *big.Rat {
+		return new(big.Rat).SetFrac(src.Interface().(*big.Int), new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(t.Decimals)), nil))
+	}
+	switch {
+	case dst.Type() == reflect.PtrTo(derefratT):
+		dst.Set(reflect.ValueOf(rat()))
+	case dst.Type() == derefratT:
+		dst.Set(reflect.ValueOf(rat()).Elem())
+	case dst.Kind() == reflect.String:
+		dst.SetString(rat().FloatString(t.Decimals))
+	default:
+		return set(dst, src)
+	}
+	return nil
+}
+
+var readerFromT = reflect.TypeOf((*io.ReaderFrom)(nil)).Elem()
+
+// readerFrom returns the io.ReaderFrom that dst is or points to, allocating nil
+// pointers, or nil if dst does not implement it. Destinations implementing it
+// receive decoded bytes by reading from them instead of being assigned a slice.
+func readerFrom(dst reflect.Value) io.ReaderFrom {
+	switch {
+	case dst.Kind() == reflect.Interface && dst.Type().Implements(readerFromT) && !dst.IsNil():
+		return dst.Interface().(io.ReaderFrom)
+	case dst.Kind() == reflect.Ptr && dst.Type().Implements(readerFromT):
+		if dst.IsNil() {
+			dst.Set(reflect.New(dst.Type().Elem()))
+		}
+		return dst.Interface().(io.ReaderFrom)
+	case dst.Kind() != reflect.Interface && dst.CanAddr() && dst.Addr().Type().Implements(readerFromT):
+		return dst.Addr().Interface().(io.ReaderFrom)
+	}
+	return nil
+}
+
+// setFixedBytes assigns the decoded fixed size byte array src to dst. Big
+// integer destinations receive the bytes interpreted as a big endian number.
+func setFixedBytes(dst, src reflect.Value) error {
+	switch dst.Type() {
+	case bigT:
+		dst.Set(reflect.ValueOf(new(big.Int).SetBytes(mustArrayToByteSlice(src).Bytes())))
+	case derefbigT:
+		dst.Set(reflect.ValueOf(new(big.Int).SetBytes(mustArrayToByteSlice(src).Bytes())).Elem())
+	default:
+		return set(dst, src)
+	}
+	return nil
+}
+
+// abiTag splits the abi:"" tag of the struct field into the argument name and
+// the comma separated options following it, e.g. abi:"deadline,unixtime".
+func abiTag(field reflect.StructField) (name string, options []string, ok bool) {
+	tag, ok := field.Tag.Lookup("abi")
+	if !ok {
+		return "", nil, false
+	}
+	parts := strings.Split(tag, ",")
+	return parts[0], parts[1:], true
+}
+
+// jsonTagName returns the name given by the json:"" tag of the struct field, if
+// any. Fields excluded from JSON with json:"-" have no name.
+func jsonTagName(field reflect.StructField) (string, bool) {
+	tag, ok := field.Tag.Lookup("json")
+	if !ok {
+		return "", false
+	}
+	name := strings.Split(tag, ",")[0]
+	if name == "" || name == "-" {
+		return "", false
+	}
+	return name, true
+}
+
+// hasTagOption reports whether the abi:"" tag of the struct field carries the
+// given option.
+func hasTagOption(field reflect.StructField, option string) bool {
+	_, options, _ := abiTag(field)
+	for _, opt := range options {
+		if opt == option {
+			return true
+		}
+	}
+	return false
+}
+
+// maxUnixTime is the latest Unix timestamp time.Time can represent, its internal
+// clock counting seconds from year 1 rather than from 1970.
+const maxUnixTime = gmath.MaxInt64 - 62135596800
+
+// setUnixTime assigns the integer src, interpreted as seconds since the Unix
+// epoch, to the time.Time dst.
+func setUnixTime(dst, src reflect.Value) error {
+	if dst.Type() != timeT {
+		return errorf(ErrTypeMismatch, "abi: unixtime field must be time.Time, got %v", dst.Type())
+	}
+	var secs *big.Int
+	switch {
+	case src.Type() == bigT:
+		secs = src.Interface().(*big.Int)
+	case isUnsignedKind(src.Kind()):
+		secs = new(big.Int).SetUint64(src.Uint())
+	case isSignedKind(src.Kind()):
+		secs = big.NewInt(src.Int())
+	default:
+		return errorf(ErrTypeMismatch, "abi: cannot use %v as unix timestamp", src.Type())
+	}
+	if !secs.IsInt64() || secs.Int64() > maxUnixTime {
+		return errorf(ErrOverflow, "abi: unix timestamp %v overflows time.Time", secs)
 	}
+	dst.Set(reflect.ValueOf(time.Unix(secs.Int64(), 0).UTC()))
 	return nil
 }
 
+// setSignedInteger assigns the decoded signed integer src to dst. Values wider
+// than a fixed width signed destination are narrowed to it if they fit, the
+// remaining cases being left to set.
+func setSignedInteger(dst, src reflect.Value) error {
+	if src.Type().AssignableTo(dst.Type()) || !isSignedKind(dst.Kind()) {
+		return set(dst, src)
+	}
+	var num *big.Int
+	switch {
+	case src.Type() == bigT:
+		num = src.Interface().(*big.Int)
+	case isSignedKind(src.Kind()):
+		num = big.NewInt(src.Int())
+	default:
+		return set(dst, src)
+	}
+	bits := uint(dst.Type().Bits())
+	max := new(big.Int).Lsh(common.Big1, bits-1)
+	if num.Cmp(new(big.Int).Neg(max)) < 0 || num.Cmp(max) >= 0 {
+		return errorf(ErrOverflow, "abi: %v overflows %v", num, dst.Type())
+	}
+	dst.SetInt(num.Int64())
+	return nil
+}
+
+// requiresFactory returns whether the decoded integer src has to be converted by
+// the number factory to be assigned to dst.
+func requiresFactory(dst, src reflect.Value) bool {
+	switch {
+	case src.Kind() == reflect.String:
+		// already decoded as a decimal string
+		return false
+	case src.Type().AssignableTo(dst.Type()):
+		return false
+	case isSignedKind(dst.Kind()), isUnsignedKind(dst.Kind()):
+		return false
+	case dst.Type() == bigT, dst.Type() == derefbigT:
+		return false
+	}
+	return true
+}
+
+// setFromFactory assigns the decoded integer src to dst after converting it with
+// the number factory.
+func setFromFactory(factory func(*big.Int, Type) (interface{}, error), t *Type, dst, src reflect.Value) error {
+	var num *big.Int
+	switch {
+	case src.Type() == bigT:
+		num = new(big.Int).Set(src.Interface().(*big.Int))
+	case isUnsignedKind(src.Kind()):
+		num = new(big.Int).SetUint64(src.Uint())
+	default:
+		num = big.NewInt(src.Int())
+	}
+	value, err := factory(num, *t)
+	if err != nil {
+		return err
+	}
+	return set(dst, reflect.ValueOf(value))
+}
+
 // setSlice attempts to assign src to dst when slices are not assignable by default
 // e.g. src: [][]byte -> dst: [][15]byte
 func setSlice(dst, src reflect.Value) error {
@@ -104,7 +303,7 @@ func setSlice(dst, src reflect.Value) error {
 // requireAssignable assures that `dest` is a pointer and it's not an interface.
 func requireAssignable(dst, src reflect.Value) error {
 	if dst.Kind() != reflect.Ptr && dst.Kind() != reflect.Interface {
-		return fmt.Errorf("abi: cannot unmarshal %v into %v", src.Type(), dst.Type())
+		return errorf(ErrTypeMismatch, "abi: cannot unmarshal %v into %v", src.Type(), dst.Type())
 	}
 	return nil
 }
@@ -117,18 +316,112 @@ func requireUnpackKind(v reflect.Value, t reflect.Type, k reflect.Kind,
 	case reflect.Struct:
 	case reflect.Slice, reflect.Array:
 		if minLen := args.LengthNonIndexed(); v.Len() < minLen {
-			return fmt.Errorf("abi: insufficient number of elements in the list/array for unpack, want %d, got %d",
+			return errorf(ErrArity, "abi: insufficient number of elements in the list/array for unpack, want %d, got %d",
 				minLen, v.Len())
 		}
 	default:
-		return fmt.Errorf("abi: cannot unmarshal tuple into %v", t)
+		return errorf(ErrTypeMismatch, "abi: cannot unmarshal tuple into %v", t)
 	}
 	return nil
 }
 
+// isUnsignedKind returns whether k is the kind of an unsigned Go integer.
+func isUnsignedKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	}
+	return false
+}
+
+// isSignedKind returns whether k is the kind of a signed Go integer.
+func isSignedKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return true
+	}
+	return false
+}
+
+// tupleFields returns the values of the components of tuple t from v, which may
+// be a map keyed by component name, a list of the components in order or a
+// struct whose fields are matched by name, falling back to the field order if
+// no name matches.
+func tupleFields(t Type, v reflect.Value) ([]reflect.Value, error) {
+	switch v.Kind() {
+	case reflect.Map:
+		return tupleMapFields(t, v)
+	case reflect.Slice, reflect.Array:
+		return tupleListFields(t, v)
+	case reflect.Struct:
+	default:
+		return nil, typeErr(t.Kind, v.Kind())
+	}
+	fieldmap, err := mapArgNamesToStructFields(t.TupleRawNames, v)
+	if err != nil {
+		return nil, err
+	}
+	if len(fieldmap) == 0 {
+		if fields := positionalFields(v, len(t.TupleElems)); fields != nil {
+			return fields, nil
+		}
+	}
+	fields := make([]reflect.Value, len(t.TupleElems))
+	for i := range t.TupleElems {
+		field := v.FieldByName(fieldmap[t.TupleRawNames[i]])
+		if !field.IsValid() {
+			return nil, fmt.Errorf("field %s for tuple not found in the given struct", t.TupleRawNames[i])
+		}
+		fields[i] = field
+	}
+	return fields, nil
+}
+
+// positionalFields returns the fields of the struct value in declaration order,
+// or nil if the struct doesn't have exactly n fields, all of them exported.
+func positionalFields(value reflect.Value, n int) []reflect.Value {
+	typ := value.Type()
+	if typ.NumField() != n {
+		return nil
+	}
+	fields := make([]reflect.Value, n)
+	for i := 0; i < n; i++ {
+		if typ.Field(i).PkgPath != "" {
+			return nil
+		}
+		fields[i] = value.Field(i)
+	}
+	return fields
+}
+
+// promotedFields returns the fields of the struct type, including the ones
+// promoted from embedded structs that can be accessed by their name. Fields
+// shadowed by shallower ones of the same name are left out.
+func promotedFields(typ reflect.Type) []reflect.StructField {
+	var fields []reflect.StructField
+
+	var walk func(t reflect.Type, index []int)
+	walk = func(t reflect.Type, index []int) {
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			field.Index = append(append([]int{}, index...), i)
+			if field.Anonymous && field.Type.Kind() == reflect.Struct {
+				walk(field.Type, field.Index)
+			}
+			if resolved, ok := typ.FieldByName(field.Name); ok && reflect.DeepEqual(resolved.Index, field.Index) {
+				fields = append(fields, field)
+			}
+		}
+	}
+	walk(typ, nil)
+	return fields
+}
+
 // mapArgNamesToStructFields maps a slice of argument names to struct fields.
 // first round: for each Exportable field that contains a `abi:""` tag
 //   and this field name exists in the given argument name list, pair them together.
+// json round: for each Exportable field without an `abi:""` tag whose `json:""`
+//   tag names an argument that has not been linked yet, pair them.
 // second round: for each argument name that has not been already linked,
 //   find what variable is expected to be mapped into, if it exists and has not been
 //   used, pair them.
@@ -140,17 +433,16 @@ func mapArgNamesToStructFields(argNames []string, value reflect.Value) (map[stri
 	struct2abi := make(map[string]string)
 
 	// first round ~~~
-	for i := 0; i < typ.NumField(); i++ {
-		structFieldName := typ.Field(i).Name
+	for _, field := range promotedFields(typ) {
+		structFieldName := field.Name
 
 		// skip private struct fields.
 		if structFieldName[:1] != strings.ToUpper(structFieldName[:1]) {
 			continue
 		}
 		// skip fields that have no abi:"" tag.
-		var ok bool
-		var tagName string
-		if tagName, ok = typ.Field(i).Tag.Lookup("abi"); !ok {
+		tagName, _, ok := abiTag(field)
+		if !ok {
 			continue
 		}
 		// check if tag is empty.
@@ -176,6 +468,30 @@ func mapArgNamesToStructFields(argNames []string, value reflect.Value) (map[stri
 		}
 	}
 
+	// json round ~~~
+	for _, field := range promotedFields(typ) {
+		structFieldName := field.Name
+		if structFieldName[:1] != strings.ToUpper(structFieldName[:1]) {
+			continue
+		}
+		if _, _, ok := abiTag(field); ok {
+			continue
+		}
+		// json tags name fields for other encodings too, so unlike abi tags
+		// they are not required to match an argument.
+		tagName, ok := jsonTagName(field)
+		if !ok || struct2abi[structFieldName] != "" {
+			continue
+		}
+		for _, arg := range argNames {
+			if arg == tagName && abi2struct[arg] == "" {
+				abi2struct[arg] = structFieldName
+				struct2abi[structFieldName] = arg
+				break
+			}
+		}
+	}
+
 	// second round ~~~
 	for _, argName := range argNames {
 
@@ -216,3 +532,153 @@ func mapArgNamesToStructFields(argNames []string, value reflect.Value) (map[stri
 	}
 	return abi2struct, nil
 }
+
+// tupleMapFields looks up the components of the tuple t in the string keyed
+// map value. The values are returned in the order the tuple declares its
+// components, never in the map's iteration order, so that packing a map is
+// deterministic.
+func tupleMapFields(t Type, value reflect.Value) ([]reflect.Value, error) {
+	keyType := value.Type().Key()
+	if keyType.Kind() != reflect.String {
+		return nil, fmt.Errorf("abi: cannot use map with %v keys as tuple", keyType)
+	}
+	if value.Len() != len(t.TupleRawNames) {
+		return nil, errorf(ErrArity, "abi: tuple has %d components, map has %d entries", len(t.TupleRawNames), value.Len())
+	}
+	fields := make([]reflect.Value, len(t.TupleRawNames))
+	for i, name := range t.TupleRawNames {
+		field := value.MapIndex(reflect.ValueOf(name).Convert(keyType))
+		if !field.IsValid() {
+			return nil, fmt.Errorf("abi: field %s for tuple not found in the given map", name)
+		}
+		if field.Kind() == reflect.Interface {
+			field = field.Elem()
+		}
+		fields[i] = field
+	}
+	return fields, nil
+}
+
+// tupleListFields returns the components of the tuple t from a slice or array
+// value holding them positionally.
+func tupleListFields(t Type, value reflect.Value) ([]reflect.Value, error) {
+	if value.Len() != len(t.TupleElems) {
+		return nil, errorf(ErrArity, "abi: tuple has %d components, list has %d elements", len(t.TupleElems), value.Len())
+	}
+	fields := make([]reflect.Value, value.Len())
+	for i := range fields {
+		field := value.Index(i)
+		if field.Kind() == reflect.Interface {
+			field = field.Elem()
+		}
+		fields[i] = field
+	}
+	return fields, nil
+}
+
+// EqualValues reports whether two decoded ABI values are semantically equal,
+// regardless of the exact Go types used to represent them. Integers of any kind
+// (including *big.Int) are compared by value, byte slices and byte arrays (such
+// as common.Address or common.Hash) by content, lists, structs and maps element
+// by element. This is mainly meant for comparing call results in tests.
+func EqualValues(a, b interface{}) bool {
+	return equalValues(reflect.ValueOf(a), reflect.ValueOf(b))
+}
+
+// equalValues is the reflection based implementation of EqualValues.
+func equalValues(a, b reflect.Value) bool {
+	a, b = derefValue(a), derefValue(b)
+	if !a.IsValid() || !b.IsValid() {
+		return a.IsValid() == b.IsValid()
+	}
+	if (a.Kind() == reflect.Ptr && a.IsNil()) || (b.Kind() == reflect.Ptr && b.IsNil()) {
+		return a.Kind() == b.Kind() && a.IsNil() && b.IsNil()
+	}
+	if x, ok := valueToBig(a); ok {
+		y, ok := valueToBig(b)
+		return ok && x.Cmp(y) == 0
+	}
+	if x, ok := valueToBytes(a); ok {
+		y, ok := valueToBytes(b)
+		return ok && bytes.Equal(x, y)
+	}
+	switch a.Kind() {
+	case reflect.Slice, reflect.Array:
+		if (b.Kind() != reflect.Slice && b.Kind() != reflect.Array) || a.Len() != b.Len() {
+			return false
+		}
+		for i := 0; i < a.Len(); i++ {
+			if !equalValues(a.Index(i), b.Index(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Struct:
+		if b.Kind() != reflect.Struct || a.NumField() != b.NumField() {
+			return false
+		}
+		for i := 0; i < a.NumField(); i++ {
+			if !equalValues(a.Field(i), b.Field(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Map:
+		if b.Kind() != reflect.Map || a.Len() != b.Len() || a.Type().Key() != b.Type().Key() {
+			return false
+		}
+		for _, key := range a.MapKeys() {
+			if !equalValues(a.MapIndex(key), b.MapIndex(key)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Bool:
+		return b.Kind() == reflect.Bool && a.Bool() == b.Bool()
+	case reflect.String:
+		return b.Kind() == reflect.String && a.String() == b.String()
+	}
+	return false
+}
+
+// derefValue unwraps interfaces and dereferences pointers, except pointers to
+// big integers and nil pointers.
+func derefValue(v reflect.Value) reflect.Value {
+	for v.IsValid() {
+		switch {
+		case v.Kind() == reflect.Interface:
+			v = v.Elem()
+		case v.Kind() == reflect.Ptr && !v.IsNil() && v.Type() != bigT:
+			v = v.Elem()
+		default:
+			return v
+		}
+	}
+	return v
+}
+
+// valueToBig converts an integer value of any kind into a big integer.
+func valueToBig(v reflect.Value) (*big.Int, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return big.NewInt(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return new(big.Int).SetUint64(v.Uint()), true
+	}
+	if v.Type() == bigT && v.CanInterface() {
+		return v.Interface().(*big.Int), true
+	}
+	return nil, false
+}
+
+// valueToBytes returns the contents of byte slices and byte arrays.
+func valueToBytes(v reflect.Value) ([]byte, bool) {
+	if (v.Kind() != reflect.Slice && v.Kind() != reflect.Array) || v.Type().Elem().Kind() != reflect.Uint8 {
+		return nil, false
+	}
+	blob := make([]byte, v.Len())
+	for i := range blob {
+		blob[i] = byte(v.Index(i).Uint())
+	}
+	return blob, true
+}
@@ -17,8 +17,11 @@
 package abi
 
 import (
+	"math/big"
 	"reflect"
 	"testing"
+
+	"github.com/enode/common"
 )
 
 type reflectTest struct {
@@ -166,6 +169,30 @@ var reflectTests = []reflectTest{
 		}{},
 		err: "struct: abi tag in 'FieldB' already mapped",
 	},
+	{
+		name: "JSONTagFallback",
+		args: []string{"to_addr", "amount"},
+		struc: struct {
+			Recipient int `json:"to_addr"`
+			Value     int `json:"amount,omitempty"`
+			Other     int `json:"other"`
+		}{},
+		want: map[string]string{
+			"to_addr": "Recipient",
+			"amount":  "Value",
+		},
+	},
+	{
+		name: "AbiTagOverJSONTag",
+		args: []string{"value"},
+		struc: struct {
+			FieldA int `json:"value"`
+			FieldB int `abi:"value"`
+		}{},
+		want: map[string]string{
+			"value": "FieldB",
+		},
+	},
 }
 
 func TestReflectNameToStruct(t *testing.T) {
@@ -189,3 +216,49 @@ func TestReflectNameToStruct(t *testing.T) {
 		})
 	}
 }
+
+func TestEqualValues(t *testing.T) {
+	type pair struct {
+		A *big.Int
+		B common.Address
+	}
+	tests := []struct {
+		a, b  interface{}
+		equal bool
+	}{
+		{big.NewInt(1), uint64(1), true},
+		{big.NewInt(1), 1, true},
+		{big.NewInt(-1), int8(-1), true},
+		{uint8(255), int64(255), true},
+		{big.NewInt(1), uint64(2), false},
+		{big.NewInt(-1), uint64(1<<63 + 1<<62), false},
+		{[]byte{1, 2}, [2]byte{1, 2}, true},
+		{common.Address{1}, [20]byte{1}, true},
+		{common.Hash{1}, common.Address{1}, false},
+		{[]*big.Int{big.NewInt(1), big.NewInt(2)}, []interface{}{uint8(1), 2}, true},
+		{[2]uint64{1, 2}, []int{1, 2}, true},
+		{[]uint64{1, 2}, []int{1, 2, 3}, false},
+		{pair{big.NewInt(5), common.Address{9}}, struct {
+			X uint16
+			Y [20]byte
+		}{5, [20]byte{9}}, true},
+		{pair{big.NewInt(5), common.Address{9}}, pair{big.NewInt(6), common.Address{9}}, false},
+		{map[string]interface{}{"a": big.NewInt(1), "b": "x"}, map[string]interface{}{"a": uint32(1), "b": "x"}, true},
+		{map[string]interface{}{"a": big.NewInt(1)}, map[string]interface{}{"b": big.NewInt(1)}, false},
+		{true, true, true},
+		{true, 1, false},
+		{"a", []byte("a"), false},
+		{(*big.Int)(nil), (*big.Int)(nil), true},
+		{(*big.Int)(nil), big.NewInt(0), false},
+		{nil, nil, true},
+		{nil, 0, false},
+	}
+	for i, test := range tests {
+		if have := EqualValues(test.a, test.b); have != test.equal {
+			t.Errorf("test %d: equality mismatch for %v and %v: have %v, want %v", i, test.a, test.b, have, test.equal)
+		}
+		if have := EqualValues(test.b, test.a); have != test.equal {
+			t.Errorf("test %d: reverse equality mismatch for %v and %v: have %v, want %v", i, test.b, test.a, have, test.equal)
+		}
+	}
+}
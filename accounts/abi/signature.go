@@ -0,0 +1,59 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package abi
+
+import (
+	"fmt"
+)
+
+// signatureLength is the length of a [R || S || V] secp256k1 signature.
+const signatureLength = 65
+
+// CompactSignature converts a 65 byte [R || S || V] signature into the 64 byte
+// EIP-2098 representation (r, vs), where the parity of V is stored in the top
+// bit of S. V may be given as 0/1 or as 27/28. The result is ready to be packed
+// as two bytes32 arguments.
+func CompactSignature(sig []byte) (r, vs [32]byte, err error) {
+	if len(sig) != signatureLength {
+		return r, vs, fmt.Errorf("abi: invalid signature length %d, want %d", len(sig), signatureLength)
+	}
+	v := sig[64]
+	if v >= 27 {
+		v -= 27
+	}
+	if v > 1 {
+		return r, vs, fmt.Errorf("abi: invalid signature recovery id %d", sig[64])
+	}
+	if sig[32]&0x80 != 0 {
+		return r, vs, fmt.Errorf("abi: signature s value is not in the lower half order")
+	}
+	copy(r[:], sig[:32])
+	copy(vs[:], sig[32:64])
+	vs[0] |= v << 7
+	return r, vs, nil
+}
+
+// ExpandSignature converts an EIP-2098 compact signature (r, vs) back into the
+// 65 byte [R || S || V] representation, with V being 0 or 1.
+func ExpandSignature(r, vs [32]byte) []byte {
+	sig := make([]byte, signatureLength)
+	copy(sig[:32], r[:])
+	copy(sig[32:64], vs[:])
+	sig[32] &= 0x7f
+	sig[64] = vs[0] >> 7
+	return sig
+}
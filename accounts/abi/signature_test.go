@@ -0,0 +1,104 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package abi
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/enode/common"
+	"github.com/enode/crypto"
+)
+
+func TestCompactSignature(t *testing.T) {
+	// Test vectors from EIP-2098
+	tests := []struct {
+		r, s string
+		v    byte
+		vs   string
+	}{
+		{
+			r:  "0x68a020a209d3d56c46f38cc50a33f704f4a9a10a59377f8dd762ac66910e9b90",
+			s:  "0x7e865ad05c4035ab5792787d4a0297a43617ae897930a6fe4d822b8faea52064",
+			v:  27,
+			vs: "0x7e865ad05c4035ab5792787d4a0297a43617ae897930a6fe4d822b8faea52064",
+		},
+		{
+			r:  "0x9328da16089fcba9bececa81663203989f2df5fe1faa6291a45381c81bd17f76",
+			s:  "0x139c6d6b623b42da56557e5e734a43dc83345ddfadec52cbe24d0cc64f550793",
+			v:  28,
+			vs: "0x939c6d6b623b42da56557e5e734a43dc83345ddfadec52cbe24d0cc64f550793",
+		},
+	}
+	for i, test := range tests {
+		sig := append(append(common.FromHex(test.r), common.FromHex(test.s)...), test.v)
+
+		r, vs, err := CompactSignature(sig)
+		if err != nil {
+			t.Fatalf("test %d: failed to compact signature: %v", i, err)
+		}
+		if r != common.HexToHash(test.r) {
+			t.Errorf("test %d: r mismatch: have %x, want %s", i, r, test.r)
+		}
+		if vs != common.HexToHash(test.vs) {
+			t.Errorf("test %d: vs mismatch: have %x, want %s", i, vs, test.vs)
+		}
+		want := append(sig[:64:64], test.v-27)
+		if expanded := ExpandSignature(r, vs); !bytes.Equal(expanded, want) {
+			t.Errorf("test %d: expanded signature mismatch: have %x, want %x", i, expanded, want)
+		}
+	}
+}
+
+func TestCompactSignatureRoundTrip(t *testing.T) {
+	key, _ := crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	for i := 0; i < 16; i++ {
+		hash := crypto.Keccak256([]byte{byte(i)})
+		sig, err := crypto.Sign(hash, key)
+		if err != nil {
+			t.Fatalf("failed to sign: %v", err)
+		}
+		r, vs, err := CompactSignature(sig)
+		if err != nil {
+			t.Fatalf("message %d: failed to compact signature: %v", i, err)
+		}
+		expanded := ExpandSignature(r, vs)
+		if !bytes.Equal(expanded, sig) {
+			t.Fatalf("message %d: round trip mismatch: have %x, want %x", i, expanded, sig)
+		}
+		pub, err := crypto.Ecrecover(hash, expanded)
+		if err != nil {
+			t.Fatalf("message %d: failed to recover key: %v", i, err)
+		}
+		if !bytes.Equal(pub, crypto.FromECDSAPub(&key.PublicKey)) {
+			t.Errorf("message %d: recovered key mismatch", i)
+		}
+	}
+	// Malformed signatures must be rejected
+	sig := make([]byte, 65)
+	if _, _, err := CompactSignature(sig[:64]); err == nil {
+		t.Error("expected error for short signature")
+	}
+	sig[64] = 2
+	if _, _, err := CompactSignature(sig); err == nil {
+		t.Error("expected error for invalid recovery id")
+	}
+	sig[64], sig[32] = 0, 0x80
+	if _, _, err := CompactSignature(sig); err == nil {
+		t.Error("expected error for high s value")
+	}
+}
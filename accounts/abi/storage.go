@@ -0,0 +1,63 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package abi
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+
+	"github.com/enode/common"
+	"github.com/enode/crypto"
+)
+
+// MappingKey is a key into a Solidity mapping along with its ABI type.
+type MappingKey struct {
+	Value interface{}
+	Type  Type
+}
+
+// MappingSlot returns the storage slot holding the value of the mapping stored
+// at baseSlot for the given key, as read by eth_getStorageAt. Values of nested
+// mappings are reached by passing the keys of the inner mappings in order, so
+// m[key][inner[0]][inner[1]] lives at MappingSlot(key, keyType, slot, inner...).
+//
+// The slot is keccak256(k . p) with k the key padded to 32 bytes and p the slot
+// of the mapping. String and bytes keys are used unpadded, as Solidity does.
+// The base slot must be a 256 bit unsigned integer.
+func MappingSlot(key interface{}, keyType Type, baseSlot *big.Int, inner ...MappingKey) (common.Hash, error) {
+	if baseSlot == nil || baseSlot.Sign() < 0 || baseSlot.BitLen() > 256 {
+		return common.Hash{}, fmt.Errorf("abi: invalid mapping slot %v", baseSlot)
+	}
+	slot := common.BigToHash(baseSlot)
+	for _, k := range append([]MappingKey{{key, keyType}}, inner...) {
+		var (
+			encoded []byte
+			err     error
+		)
+		if isDynamicType(k.Type) {
+			encoded, err = k.Type.packPacked(reflect.ValueOf(k.Value))
+		} else {
+			encoded, err = k.Type.pack(reflect.ValueOf(k.Value))
+		}
+		if err != nil {
+			return common.Hash{}, err
+		}
+		slot = crypto.Keccak256Hash(encoded, slot.Bytes())
+	}
+	return slot, nil
+}
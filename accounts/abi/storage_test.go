@@ -0,0 +1,77 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package abi
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/enode/common"
+	"github.com/enode/crypto"
+)
+
+func TestMappingSlot(t *testing.T) {
+	var (
+		uint256, _ = NewType("uint256", nil)
+		address, _ = NewType("address", nil)
+		str, _     = NewType("string", nil)
+		owner      = common.HexToAddress("0xCcCCccccCCCCcCCCCCCcCcCccCcCCCcCcccccccC")
+	)
+	// mapping(uint256 => ...) at slot 0, key 0: keccak256 of 64 zero bytes
+	slot, err := MappingSlot(big.NewInt(0), uint256, big.NewInt(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := common.HexToHash("0xad3228b676f7d3cd4284a5443f17f1962b36e491b30a40b2405849e597ba5fb5"); slot != want {
+		t.Errorf("uint key slot mismatch: have %x, want %x", slot, want)
+	}
+	// mapping(address => uint256) balances at slot 3
+	slot, err = MappingSlot(owner, address, big.NewInt(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	balance := crypto.Keccak256Hash(common.LeftPadBytes(owner.Bytes(), 32), common.LeftPadBytes([]byte{3}, 32))
+	if slot != balance {
+		t.Errorf("address key slot mismatch: have %x, want %x", slot, balance)
+	}
+	// mapping(address => mapping(address => uint256)) allowances at slot 4
+	spender := common.Address{1}
+	slot, err = MappingSlot(owner, address, big.NewInt(4), MappingKey{spender, address})
+	if err != nil {
+		t.Fatal(err)
+	}
+	outer := crypto.Keccak256Hash(common.LeftPadBytes(owner.Bytes(), 32), common.LeftPadBytes([]byte{4}, 32))
+	if want := crypto.Keccak256Hash(common.LeftPadBytes(spender.Bytes(), 32), outer.Bytes()); slot != want {
+		t.Errorf("nested slot mismatch: have %x, want %x", slot, want)
+	}
+	// mapping(string => ...) keys are hashed unpadded
+	slot, err = MappingSlot("key", str, big.NewInt(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := crypto.Keccak256Hash([]byte("key"), common.LeftPadBytes([]byte{1}, 32)); slot != want {
+		t.Errorf("string key slot mismatch: have %x, want %x", slot, want)
+	}
+	if _, err := MappingSlot("key", address, big.NewInt(1)); err == nil {
+		t.Error("expected error for mistyped key")
+	}
+	for _, base := range []*big.Int{nil, big.NewInt(-1), new(big.Int).Lsh(common.Big1, 256)} {
+		if _, err := MappingSlot(owner, address, base); err == nil {
+			t.Errorf("expected error for mapping slot %v", base)
+		}
+	}
+}
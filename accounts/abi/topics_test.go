@@ -0,0 +1,81 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package abi
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/enode/common"
+	"github.com/enode/crypto"
+)
+
+func TestParseTopicsIndexedArray(t *testing.T) {
+	parsed, err := JSON(strings.NewReader(`[{"type":"event","name":"Stored","inputs":[
+		{"name":"owner","type":"address","indexed":true},
+		{"name":"values","type":"uint256[3]","indexed":true},
+		{"name":"note","type":"string","indexed":true}
+	]}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields := parsed.Events["Stored"].Inputs
+
+	owner := common.HexToAddress("0x00Ce0d46d924CC8437c806721496599FC3FFA268")
+	valuesHash := crypto.Keccak256Hash(common.LeftPadBytes([]byte{1}, 32), common.LeftPadBytes([]byte{2}, 32), common.LeftPadBytes([]byte{3}, 32))
+	noteHash := crypto.Keccak256Hash([]byte("hello"))
+	topics := []common.Hash{common.BytesToHash(owner[:]), valuesHash, noteHash}
+
+	var out struct {
+		Owner  common.Address
+		Values common.Hash
+		Note   common.Hash
+	}
+	if err := parseTopics(&out, fields, topics); err != nil {
+		t.Fatalf("failed to parse topics: %v", err)
+	}
+	if out.Owner != owner {
+		t.Errorf("owner mismatch: have %x, want %x", out.Owner, owner)
+	}
+	if out.Values != valuesHash {
+		t.Errorf("values mismatch: have %x, want %x", out.Values, valuesHash)
+	}
+	if out.Note != noteHash {
+		t.Errorf("note mismatch: have %x, want %x", out.Note, noteHash)
+	}
+	// The array cannot be reconstructed from its hash, so reject non-hash fields
+	var bad struct {
+		Owner  common.Address
+		Values [3]*big.Int
+		Note   common.Hash
+	}
+	if err := parseTopics(&bad, fields, topics); err == nil {
+		t.Error("expected error for non-hash array field")
+	}
+	// Every indexed argument needs a field to be stored in
+	var missing struct {
+		Owner common.Address
+		Note  common.Hash
+	}
+	if err := parseTopics(&missing, fields, topics); err == nil || !strings.Contains(err.Error(), "field Values") {
+		t.Errorf("error mismatch: have %v, want missing field Values", err)
+	}
+	if !fields[1].Type.HashedTopic() {
+		t.Errorf("indexed %v not stored as a hash", fields[1].Type)
+	}
+}
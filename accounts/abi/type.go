@@ -17,12 +17,19 @@
 package abi
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"math/big"
 	"reflect"
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/enode/common"
 )
 
 // Type enumerator
@@ -50,6 +57,8 @@ type Type struct {
 	Size int
 	T    byte // Our own type checking
 
+	Decimals int // Number of decimals of fixed point types
+
 	stringKind string // holds the unparsed string for deriving signatures
 
 	// Tuple relative fields
@@ -60,10 +69,90 @@ type Type struct {
 var (
 	// typeRegex parses the abi sub types
 	typeRegex = regexp.MustCompile("([a-zA-Z]+)(([0-9]+)(x([0-9]+))?)?")
+	// arrayRegex matches a single array or slice suffix, e.g. [] or [2]
+	arrayRegex = regexp.MustCompile(`^\[[0-9]*\]$`)
+	// addressPayableRegex matches the address payable type, which is encoded
+	// like an address
+	addressPayableRegex = regexp.MustCompile(`address\s+payable`)
 )
 
+// maxCachedTypes bounds the number of parsed types kept by NewType, so that
+// decoding many distinct ABIs cannot grow the cache without limit.
+const maxCachedTypes = 4096
+
+// typeCache holds the types parsed by NewType, keyed by the type string and
+// the JSON encoding of its components.
+var typeCache = struct {
+	sync.RWMutex
+	types map[string]Type
+}{types: make(map[string]Type)}
+
 // NewType creates a new reflection type of abi type given in t.
-func NewType(t string, components []ArgumentMarshaling) (typ Type, err error) {
+//
+// Tuples may also be given in their parenthesized form, e.g. "(uint256,bool)[]",
+// in which case the components are parsed from t and named arg0, arg1, etc.
+// The "address payable" spelling of some ABIs is accepted as address.
+//
+// Parsed types are cached, every call returning its own deep copy, so callers
+// may modify the result without affecting other ones.
+func NewType(t string, components []ArgumentMarshaling) (Type, error) {
+	key := t
+	if len(components) > 0 {
+		blob, err := json.Marshal(components)
+		if err != nil {
+			return newType(t, components)
+		}
+		key += string(blob)
+	}
+	typeCache.RLock()
+	typ, ok := typeCache.types[key]
+	typeCache.RUnlock()
+	if ok {
+		return typ.copy(), nil
+	}
+	typ, err := newType(t, components)
+	if err != nil {
+		return Type{}, err
+	}
+	typeCache.Lock()
+	if len(typeCache.types) < maxCachedTypes {
+		typeCache.types[key] = typ.copy()
+	}
+	typeCache.Unlock()
+	return typ, nil
+}
+
+// copy returns a deep copy of the type, sharing no element or tuple component
+// types with the original.
+func (t Type) copy() Type {
+	if t.Elem != nil {
+		elem := t.Elem.copy()
+		t.Elem = &elem
+	}
+	if t.TupleElems != nil {
+		elems := make([]*Type, len(t.TupleElems))
+		for i, e := range t.TupleElems {
+			elem := e.copy()
+			elems[i] = &elem
+		}
+		t.TupleElems = elems
+	}
+	if t.TupleRawNames != nil {
+		t.TupleRawNames = append([]string(nil), t.TupleRawNames...)
+	}
+	return t
+}
+
+// newType parses the abi type given in t, see NewType.
+func newType(t string, components []ArgumentMarshaling) (typ Type, err error) {
+	t = addressPayableRegex.ReplaceAllString(t, "address")
+	if strings.HasPrefix(strings.TrimSpace(t), "(") {
+		components, suffix, err := parseTupleComponents(strings.Join(strings.Fields(t), ""))
+		if err != nil {
+			return Type{}, err
+		}
+		return NewType("tuple"+suffix, components)
+	}
 	// check that array brackets are equal if they exist
 	if strings.Count(t, "[") != strings.Count(t, "]") {
 		return Type{}, fmt.Errorf("invalid arg type in abi")
@@ -82,6 +171,9 @@ func NewType(t string, components []ArgumentMarshaling) (typ Type, err error) {
 		}
 		// grab the last cell and create a type from there
 		sliced := t[i:]
+		if !arrayRegex.MatchString(sliced) {
+			return Type{}, fmt.Errorf("invalid formatting of array type")
+		}
 		// grab the slice size with regexp
 		re := regexp.MustCompile("[0-9]+")
 		intz := re.FindAllString(sliced, -1)
@@ -92,9 +184,7 @@ func NewType(t string, components []ArgumentMarshaling) (typ Type, err error) {
 			typ.Kind = reflect.Slice
 			typ.Elem = &embeddedType
 			typ.Type = reflect.SliceOf(embeddedType.Type)
-			if embeddedType.T == TupleTy {
-				typ.stringKind = embeddedType.stringKind + sliced
-			}
+			typ.stringKind = embeddedType.stringKind + "[]"
 		} else if len(intz) == 1 {
 			// is a array
 			typ.T = ArrayTy
@@ -105,9 +195,7 @@ func NewType(t string, components []ArgumentMarshaling) (typ Type, err error) {
 				return Type{}, fmt.Errorf("abi: error parsing variable size: %v", err)
 			}
 			typ.Type = reflect.ArrayOf(typ.Size, embeddedType.Type)
-			if embeddedType.T == TupleTy {
-				typ.stringKind = embeddedType.stringKind + sliced
-			}
+			typ.stringKind = fmt.Sprintf("%s[%d]", embeddedType.stringKind, typ.Size)
 		} else {
 			return Type{}, fmt.Errorf("invalid formatting of array type")
 		}
@@ -119,32 +207,49 @@ func NewType(t string, components []ArgumentMarshaling) (typ Type, err error) {
 		return Type{}, fmt.Errorf("invalid type '%v'", t)
 	}
 	parsedType := matches[0]
+	if parsedType[0] != t {
+		return Type{}, fmt.Errorf("invalid type '%v'", t)
+	}
 
 	// varSize is the size of the variable
 	var varSize int
 	if len(parsedType[3]) > 0 {
 		var err error
-		varSize, err = strconv.Atoi(parsedType[2])
+		varSize, err = strconv.Atoi(parsedType[3])
 		if err != nil {
 			return Type{}, fmt.Errorf("abi: error parsing variable size: %v", err)
 		}
-	} else {
-		if parsedType[0] == "uint" || parsedType[0] == "int" {
-			// this should fail because it means that there's something wrong with
-			// the abi type (the compiler should always format it to the size...always)
-			return Type{}, fmt.Errorf("unsupported arg type: %s", t)
-		}
+	} else if parsedType[0] == "uint" || parsedType[0] == "int" {
+		// int and uint are aliases of int256 and uint256, which are used in
+		// their canonical form for selectors and packing
+		varSize = 256
 	}
 	// varType is the parsed abi type
-	switch varType := parsedType[1]; varType {
+	varType := parsedType[1]
+	isFixed := varType == "fixed" || varType == "ufixed"
+	if len(parsedType[2]) > 0 && varType != "int" && varType != "uint" && varType != "bytes" && !isFixed {
+		return Type{}, fmt.Errorf("unsupported arg type: %s", t)
+	}
+	if len(parsedType[4]) > 0 && !isFixed {
+		return Type{}, fmt.Errorf("unsupported arg type: %s", t)
+	}
+	if (varType == "int" || varType == "uint") && (varSize == 0 || varSize > 256 || varSize%8 != 0) {
+		return Type{}, fmt.Errorf("unsupported arg type: %s", t)
+	}
+	if varType == "bytes" && len(parsedType[2]) > 0 && (varSize == 0 || varSize > 32) {
+		return Type{}, fmt.Errorf("unsupported arg type: %s, fixed size bytes hold 1 to 32 bytes, use bytes for longer values", t)
+	}
+	switch varType {
 	case "int":
 		typ.Kind, typ.Type = reflectIntKindAndType(false, varSize)
 		typ.Size = varSize
 		typ.T = IntTy
+		typ.stringKind = fmt.Sprintf("int%d", varSize)
 	case "uint":
 		typ.Kind, typ.Type = reflectIntKindAndType(true, varSize)
 		typ.Size = varSize
 		typ.T = UintTy
+		typ.stringKind = fmt.Sprintf("uint%d", varSize)
 	case "bool":
 		typ.Kind = reflect.Bool
 		typ.T = BoolTy
@@ -168,6 +273,7 @@ func NewType(t string, components []ArgumentMarshaling) (typ Type, err error) {
 			typ.Kind = reflect.Array
 			typ.Size = varSize
 			typ.Type = reflect.ArrayOf(varSize, reflect.TypeOf(byte(0)))
+			typ.stringKind = fmt.Sprintf("bytes%d", varSize)
 		}
 	case "tuple":
 		var (
@@ -180,11 +286,21 @@ func NewType(t string, components []ArgumentMarshaling) (typ Type, err error) {
 		for idx, c := range components {
 			cType, err := NewType(c.Type, c.Components)
 			if err != nil {
-				return Type{}, err
+				return Type{}, fmt.Errorf("abi: tuple component %q: %v", c.Name, err)
 			}
 			if ToCamelCase(c.Name) == "" {
 				return Type{}, errors.New("abi: purely anonymous or underscored field is not supported")
 			}
+			// components are mapped to struct fields by name, which must
+			// therefore be unique
+			for _, name := range names {
+				if ToCamelCase(name) == ToCamelCase(c.Name) {
+					if name == c.Name {
+						return Type{}, fmt.Errorf("abi: duplicate tuple component %q", c.Name)
+					}
+					return Type{}, fmt.Errorf("abi: tuple components %q and %q map to the same field %s", name, c.Name, ToCamelCase(c.Name))
+				}
+			}
 			fields = append(fields, reflect.StructField{
 				Name: ToCamelCase(c.Name), // reflect.StructOf will panic for any exported field.
 				Type: cType.Type,
@@ -203,6 +319,25 @@ func NewType(t string, components []ArgumentMarshaling) (typ Type, err error) {
 		typ.TupleRawNames = names
 		typ.T = TupleTy
 		typ.stringKind = expression
+	case "fixed", "ufixed":
+		// fixed and ufixed are aliases for fixed128x18 and ufixed128x18
+		decimals := 18
+		if len(parsedType[2]) == 0 {
+			varSize = 128
+		} else if len(parsedType[5]) == 0 {
+			return Type{}, fmt.Errorf("unsupported arg type: %s", t)
+		} else if decimals, err = strconv.Atoi(parsedType[5]); err != nil {
+			return Type{}, fmt.Errorf("abi: error parsing variable decimals: %v", err)
+		}
+		if varSize%8 != 0 || varSize < 8 || varSize > 256 || decimals < 1 || decimals > 80 {
+			return Type{}, fmt.Errorf("unsupported arg type: %s", t)
+		}
+		typ.Kind = reflect.Ptr
+		typ.Type = bigT
+		typ.Size = varSize
+		typ.Decimals = decimals
+		typ.T = FixedPointTy
+		typ.stringKind = fmt.Sprintf("%s%dx%d", varType, varSize, decimals)
 	case "function":
 		typ.Kind = reflect.Array
 		typ.T = FunctionTy
@@ -215,14 +350,140 @@ func NewType(t string, components []ArgumentMarshaling) (typ Type, err error) {
 	return
 }
 
+// parseTupleComponents parses the components of the parenthesized tuple type
+// t, which must not contain whitespace, and returns them along with the array
+// suffix following the closing parenthesis.
+func parseTupleComponents(t string) ([]ArgumentMarshaling, string, error) {
+	depth, end := 0, -1
+	for i, c := range t {
+		if c == '(' {
+			depth++
+		} else if c == ')' {
+			if depth--; depth == 0 {
+				end = i
+				break
+			}
+		}
+	}
+	if end < 0 {
+		return nil, "", fmt.Errorf("abi: unbalanced parentheses in type %q", t)
+	}
+	inner, suffix := t[1:end], t[end+1:]
+	if strings.ContainsAny(suffix, "()") {
+		return nil, "", fmt.Errorf("abi: invalid tuple type %q", t)
+	}
+	if inner == "" {
+		return nil, suffix, nil
+	}
+	// split the components on the commas outside of nested tuples
+	var (
+		elems []string
+		start int
+	)
+	depth = 0
+	for i, c := range inner {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				elems = append(elems, inner[start:i])
+				start = i + 1
+			}
+		}
+	}
+	elems = append(elems, inner[start:])
+
+	components := make([]ArgumentMarshaling, len(elems))
+	for i, elem := range elems {
+		if elem == "" {
+			return nil, "", fmt.Errorf("abi: empty component in tuple type %q", t)
+		}
+		components[i] = ArgumentMarshaling{Name: fmt.Sprintf("arg%d", i), Type: elem}
+		if strings.HasPrefix(elem, "(") {
+			nested, nestedSuffix, err := parseTupleComponents(elem)
+			if err != nil {
+				return nil, "", err
+			}
+			components[i].Type, components[i].Components = "tuple"+nestedSuffix, nested
+		}
+	}
+	return components, suffix, nil
+}
+
 // String implements Stringer
 func (t Type) String() (out string) {
 	return t.stringKind
 }
 
 func (t Type) pack(v reflect.Value) ([]byte, error) {
+	return t.packWithOptions(v, PackOptions{})
+}
+
+func (t Type) packWithOptions(v reflect.Value, opts PackOptions) ([]byte, error) {
+	v, err := resolveValue(v)
+	if err != nil {
+		return nil, err
+	}
+	// strings and bytes may also be given as fmt.Stringer, such as domain types
+	v = stringerValue(t, v)
 	// dereference pointer first if it's a pointer
 	v = indirect(v)
+	if v.IsValid() && v.Type() == rawEncodedT {
+		return packRawEncoded(t, v.Bytes())
+	}
+	if v.Kind() == reflect.Ptr && v.IsNil() {
+		if v.Type() != bigT {
+			return nil, fmt.Errorf("abi: nil %v for %v arg", v.Type(), t)
+		}
+		if !opts.NilBigIntAsZero {
+			return nil, fmt.Errorf("abi: nil *big.Int for %v arg", t)
+		}
+		v = reflect.ValueOf(new(big.Int))
+	}
+	// integers may also be given as decimal or hexadecimal strings
+	if (t.T == IntTy || t.T == UintTy) && v.Kind() == reflect.String {
+		return packNumString(t, v.String())
+	}
+	// or as JSON numbers, quoted or not
+	if (t.T == IntTy || t.T == UintTy) && v.IsValid() && v.Type() == rawMessageT {
+		return packNumJSON(t, json.RawMessage(v.Bytes()))
+	}
+	// mixed case addresses must carry a valid checksum
+	if t.T == AddressTy && v.IsValid() && v.Type() == mixedcaseAddressT && v.CanInterface() {
+		return packMixedcaseAddress(v.Interface().(common.MixedcaseAddress))
+	}
+	// as are address arrays given as hex strings
+	if (t.T == SliceTy || t.T == ArrayTy) && t.Elem.T == AddressTy && (v.Kind() == reflect.Slice || v.Kind() == reflect.Array) && v.Type().Elem().Kind() == reflect.String {
+		addrs, err := parseAddressStrings(v)
+		if err != nil {
+			return nil, err
+		}
+		v = reflect.ValueOf(addrs)
+	}
+	// unsigned Go integers are accepted for signed types as long as they fit
+	if t.T == IntTy && isUnsignedKind(v.Kind()) {
+		return packUnsignedAsSigned(t, v.Uint(), opts.WrapUnsigned)
+	}
+	// and durations by the same rule for every integer width
+	if (t.T == IntTy || t.T == UintTy) && v.IsValid() && v.Type() == durationT {
+		return packDuration(t, time.Duration(v.Int()), opts)
+	}
+	// fixed point numbers may also be given as rationals or floats
+	if t.T == FixedPointTy && v.Kind() != reflect.Ptr {
+		return packFixedPoint(t, v)
+	}
+	// tuples may also be given as maps keyed by component name or as lists
+	// holding the components in order
+	if t.T == TupleTy && (v.Kind() == reflect.Map || v.Kind() == reflect.Slice || v.Kind() == reflect.Array) {
+		fields, err := tupleFields(t, v)
+		if err != nil {
+			return nil, err
+		}
+		return t.packTupleFields(fields, opts)
+	}
 	if err := typeCheck(t, v); err != nil {
 		return nil, err
 	}
@@ -244,7 +505,20 @@ func (t Type) pack(v reflect.Value) ([]byte, error) {
 		}
 		var tail []byte
 		for i := 0; i < v.Len(); i++ {
-			val, err := t.Elem.pack(v.Index(i))
+			if t.Elem.T == FixedBytesTy {
+				if elem := indirect(v.Index(i)); elem.Len() != t.Elem.Size {
+					return nil, fmt.Errorf("abi: element %d of %v has %d bytes, want %d", i, t, elem.Len(), t.Elem.Size)
+				}
+			}
+			if elem := v.Index(i); elem.Type() == bigT && elem.IsNil() && !opts.NilBigIntAsZero {
+				return nil, fmt.Errorf("abi: nil *big.Int at index %d of %v arg", i, t)
+			}
+			elem := v.Index(i)
+			if elem.Kind() == reflect.Interface && t.Elem.T == TupleTy {
+				// such as the objects of decoded JSON arrays
+				elem = elem.Elem()
+			}
+			val, err := t.Elem.packWithOptions(elem, opts)
 			if err != nil {
 				return nil, err
 			}
@@ -258,47 +532,135 @@ func (t Type) pack(v reflect.Value) ([]byte, error) {
 		}
 		return append(ret, tail...), nil
 	case TupleTy:
-		// (T1,...,Tk) for k >= 0 and any types T1, …, Tk
-		// enc(X) = head(X(1)) ... head(X(k)) tail(X(1)) ... tail(X(k))
-		// where X = (X(1), ..., X(k)) and head and tail are defined for Ti being a static
-		// type as
-		//     head(X(i)) = enc(X(i)) and tail(X(i)) = "" (the empty string)
-		// and as
-		//     head(X(i)) = enc(len(head(X(1)) ... head(X(k)) tail(X(1)) ... tail(X(i-1))))
-		//     tail(X(i)) = enc(X(i))
-		// otherwise, i.e. if Ti is a dynamic type.
-		fieldmap, err := mapArgNamesToStructFields(t.TupleRawNames, v)
+		fields, err := tupleFields(t, v)
 		if err != nil {
 			return nil, err
 		}
-		// Calculate prefix occupied size.
-		offset := 0
-		for _, elem := range t.TupleElems {
-			offset += getTypeSize(*elem)
+		return t.packTupleFields(fields, opts)
+
+	default:
+		if (t.T == BytesTy || t.T == StringTy) && opts.MaxDynamicLength > 0 && v.Len() > opts.MaxDynamicLength {
+			return nil, fmt.Errorf("abi: %v arg of %d bytes exceeds limit of %d", t, v.Len(), opts.MaxDynamicLength)
 		}
-		var ret, tail []byte
-		for i, elem := range t.TupleElems {
-			field := v.FieldByName(fieldmap[t.TupleRawNames[i]])
-			if !field.IsValid() {
-				return nil, fmt.Errorf("field %s for tuple not found in the given struct", t.TupleRawNames[i])
+		if t.T == StringTy && opts.StrictUTF8 && !utf8.ValidString(v.String()) {
+			return nil, fmt.Errorf("abi: string arg %q is not valid UTF-8", v.String())
+		}
+		return packElement(t, v), nil
+	}
+}
+
+// packTupleFields packs the given component values of the tuple t, which
+// must be supplied in the order of the tuple's components.
+func (t Type) packTupleFields(fields []reflect.Value, opts PackOptions) ([]byte, error) {
+	// (T1,...,Tk) for k >= 0 and any types T1, …, Tk
+	// enc(X) = head(X(1)) ... head(X(k)) tail(X(1)) ... tail(X(k))
+	// where X = (X(1), ..., X(k)) and head and tail are defined for Ti being a static
+	// type as
+	//     head(X(i)) = enc(X(i)) and tail(X(i)) = "" (the empty string)
+	// and as
+	//     head(X(i)) = enc(len(head(X(1)) ... head(X(k)) tail(X(1)) ... tail(X(i-1))))
+	//     tail(X(i)) = enc(X(i))
+	// otherwise, i.e. if Ti is a dynamic type.
+
+	// Calculate prefix occupied size.
+	offset := 0
+	for _, elem := range t.TupleElems {
+		offset += getTypeSize(*elem)
+	}
+	var ret, tail []byte
+	for i, elem := range t.TupleElems {
+		val, err := elem.packWithOptions(fields[i], opts)
+		if err != nil {
+			return nil, err
+		}
+		if isDynamicType(*elem) {
+			ret = append(ret, packNum(reflect.ValueOf(offset))...)
+			tail = append(tail, val...)
+			offset += len(val)
+		} else {
+			ret = append(ret, val...)
+		}
+	}
+	return append(ret, tail...), nil
+}
+
+// encodedSize returns the number of bytes the encoding of v as type t occupies.
+// Values are only inspected as far as needed to compute the size, so packing
+// them may still fail.
+func (t Type) encodedSize(v reflect.Value) (int, error) {
+	if !isDynamicType(t) {
+		return getTypeSize(t), nil
+	}
+	v = indirect(v)
+	if v.IsValid() && v.Type() == rawEncodedT {
+		return v.Len(), nil
+	}
+	switch t.T {
+	case StringTy, BytesTy:
+		if v.Kind() != reflect.String && v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+			return 0, typeErr(t, v.Kind())
+		}
+		return 32 + (v.Len()+31)/32*32, nil
+	case SliceTy, ArrayTy:
+		if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+			return 0, typeErr(formatSliceString(t.Kind, t.Size), v.Kind())
+		}
+		size := 0
+		if t.requiresLengthPrefix() {
+			size += 32
+		}
+		for i := 0; i < v.Len(); i++ {
+			elemSize, err := t.Elem.encodedSize(v.Index(i))
+			if err != nil {
+				return 0, err
+			}
+			if isDynamicType(*t.Elem) {
+				elemSize += 32
 			}
-			val, err := elem.pack(field)
+			size += elemSize
+		}
+		return size, nil
+	case TupleTy:
+		fields, err := tupleFields(t, v)
+		if err != nil {
+			return 0, err
+		}
+		size := 0
+		for i, elem := range t.TupleElems {
+			elemSize, err := elem.encodedSize(fields[i])
 			if err != nil {
-				return nil, err
+				return 0, err
 			}
 			if isDynamicType(*elem) {
-				ret = append(ret, packNum(reflect.ValueOf(offset))...)
-				tail = append(tail, val...)
-				offset += len(val)
-			} else {
-				ret = append(ret, val...)
+				elemSize += 32
 			}
+			size += elemSize
 		}
-		return append(ret, tail...), nil
-
-	default:
-		return packElement(t, v), nil
+		return size, nil
 	}
+	return 0, fmt.Errorf("abi: cannot compute encoded size of %v", t)
+}
+
+// IsInteger returns whether the type is a signed or unsigned integer type.
+func (t Type) IsInteger() bool {
+	return t.T == IntTy || t.T == UintTy
+}
+
+// IsSigned returns whether the type is a signed integer type. It returns false
+// for all other types, including unsigned integers.
+func (t Type) IsSigned() bool {
+	return t.T == IntTy
+}
+
+// IsDynamic returns whether the type is dynamic, that is whether its values are
+// encoded out of place and referenced by an offset from the enclosing head.
+func (t Type) IsDynamic() bool {
+	return isDynamicType(t)
+}
+
+// unsigned returns whether the type is an unsigned integer or fixed point type.
+func (t Type) unsigned() bool {
+	return t.T == UintTy || (t.T == FixedPointTy && strings.HasPrefix(t.stringKind, "ufixed"))
 }
 
 // requireLengthPrefix returns whether the type requires any sort of length
@@ -336,8 +698,8 @@ func isDynamicType(t Type) bool {
 // to store the location reference for actual value storage.
 func getTypeSize(t Type) int {
 	if t.T == ArrayTy && !isDynamicType(*t.Elem) {
-		// Recursively calculate type size if it is a nested array
-		if t.Elem.T == ArrayTy {
+		// Recursively calculate type size if it is a nested array or tuple
+		if t.Elem.T == ArrayTy || t.Elem.T == TupleTy {
 			return t.Size * getTypeSize(*t.Elem)
 		}
 		return t.Size * 32
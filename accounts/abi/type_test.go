@@ -19,6 +19,7 @@ package abi
 import (
 	"math/big"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/davecgh/go-spew/spew"
@@ -88,13 +89,11 @@ func TestTypeRegexp(t *testing.T) {
 		{"address", nil, Type{Kind: reflect.Array, Type: addressT, Size: 20, T: AddressTy, stringKind: "address"}},
 		{"address[]", nil, Type{T: SliceTy, Kind: reflect.Slice, Type: reflect.TypeOf([]common.Address{}), Elem: &Type{Kind: reflect.Array, Type: addressT, Size: 20, T: AddressTy, stringKind: "address"}, stringKind: "address[]"}},
 		{"address[2]", nil, Type{Kind: reflect.Array, T: ArrayTy, Size: 2, Type: reflect.TypeOf([2]common.Address{}), Elem: &Type{Kind: reflect.Array, Type: addressT, Size: 20, T: AddressTy, stringKind: "address"}, stringKind: "address[2]"}},
-		// TODO when fixed types are implemented properly
-		// {"fixed", nil, Type{}},
-		// {"fixed128x128", nil, Type{}},
-		// {"fixed[]", nil, Type{}},
-		// {"fixed[2]", nil, Type{}},
-		// {"fixed128x128[]", nil, Type{}},
-		// {"fixed128x128[2]", nil, Type{}},
+		{"fixed", nil, Type{Kind: reflect.Ptr, Type: bigT, Size: 128, Decimals: 18, T: FixedPointTy, stringKind: "fixed128x18"}},
+		{"fixed256x80", nil, Type{Kind: reflect.Ptr, Type: bigT, Size: 256, Decimals: 80, T: FixedPointTy, stringKind: "fixed256x80"}},
+		{"ufixed64x10", nil, Type{Kind: reflect.Ptr, Type: bigT, Size: 64, Decimals: 10, T: FixedPointTy, stringKind: "ufixed64x10"}},
+		{"fixed[]", nil, Type{Kind: reflect.Slice, T: SliceTy, Type: reflect.TypeOf([]*big.Int{}), Elem: &Type{Kind: reflect.Ptr, Type: bigT, Size: 128, Decimals: 18, T: FixedPointTy, stringKind: "fixed128x18"}, stringKind: "fixed128x18[]"}},
+		{"ufixed64x10[2]", nil, Type{Kind: reflect.Array, T: ArrayTy, Size: 2, Type: reflect.TypeOf([2]*big.Int{}), Elem: &Type{Kind: reflect.Ptr, Type: bigT, Size: 64, Decimals: 10, T: FixedPointTy, stringKind: "ufixed64x10"}, stringKind: "ufixed64x10[2]"}},
 		{"tuple", []ArgumentMarshaling{{Name: "a", Type: "int64"}}, Type{Kind: reflect.Struct, T: TupleTy, Type: reflect.TypeOf(struct{ A int64 }{}), stringKind: "(int64)",
 			TupleElems: []*Type{{Kind: reflect.Int64, T: IntTy, Type: reflect.TypeOf(int64(0)), Size: 64, stringKind: "int64"}}, TupleRawNames: []string{"a"}}},
 	}
@@ -117,8 +116,8 @@ func TestTypeCheck(t *testing.T) {
 		input      interface{}
 		err        string
 	}{
-		{"uint", nil, big.NewInt(1), "unsupported arg type: uint"},
-		{"int", nil, big.NewInt(1), "unsupported arg type: int"},
+		{"uint", nil, big.NewInt(1), ""},
+		{"int", nil, big.NewInt(1), ""},
 		{"uint256", nil, big.NewInt(1), ""},
 		{"uint256[][3][]", nil, [][3][]*big.Int{{{}}}, ""},
 		{"uint256[][][3]", nil, [3][][]*big.Int{{{}}}, ""},
@@ -188,7 +187,7 @@ func TestTypeCheck(t *testing.T) {
 		{"int232", nil, big.NewInt(1), ""},
 		{"int240", nil, big.NewInt(1), ""},
 		{"int248", nil, big.NewInt(1), ""},
-		{"uint30", nil, uint8(1), "abi: cannot use uint8 as type ptr as argument"},
+		{"uint40", nil, uint8(1), "abi: cannot use uint8 as type ptr as argument"},
 		{"uint8", nil, uint16(1), "abi: cannot use uint16 as type uint8 as argument"},
 		{"uint8", nil, uint32(1), "abi: cannot use uint32 as type uint8 as argument"},
 		{"uint8", nil, uint64(1), "abi: cannot use uint64 as type uint8 as argument"},
@@ -252,6 +251,11 @@ func TestTypeCheck(t *testing.T) {
 		{"string", nil, string(""), ""},
 		{"string", nil, []byte{}, "abi: cannot use slice as type string as argument"},
 		{"bytes32[]", nil, [][32]byte{{}}, ""},
+		{"bytes4[]", nil, [][]byte{{1, 2, 3, 4}}, ""},
+		{"bytes4[2]", nil, [][]byte{{1, 2, 3, 4}, {5}}, ""},
+		{"bytes4[]", nil, []string{"abcd"}, "abi: cannot use []string as type [0]array as argument"},
+		{"bytes4", nil, []byte{1, 2, 3, 4}, ""},
+		{"bytes4", nil, []byte{1, 2, 3}, "abi: cannot use 3 bytes as type bytes4 as argument"},
 		{"function", nil, [24]byte{}, ""},
 		{"bytes20", nil, common.Address{}, ""},
 		{"address", nil, [20]byte{}, ""},
@@ -300,3 +304,349 @@ func TestTypeCheck(t *testing.T) {
 		}
 	}
 }
+
+func TestTypeString(t *testing.T) {
+	pair := []ArgumentMarshaling{{Name: "a", Type: "uint256"}, {Name: "b", Type: "bool"}}
+	nested := []ArgumentMarshaling{
+		{Name: "x", Type: "tuple[]", Components: pair},
+		{Name: "y", Type: "bytes32[2]"},
+		{Name: "z", Type: "tuple", Components: []ArgumentMarshaling{{Name: "s", Type: "string[][3]"}}},
+	}
+	tests := []struct {
+		blob       string
+		components []ArgumentMarshaling
+		want       string
+	}{
+		{"uint256", nil, "uint256"},
+		{"int8", nil, "int8"},
+		{"uint08", nil, "uint8"},
+		{"bytes", nil, "bytes"},
+		{"bytes1", nil, "bytes1"},
+		{"bytes32[]", nil, "bytes32[]"},
+		{"address[2]", nil, "address[2]"},
+		{"string[][]", nil, "string[][]"},
+		{"bool[2][]", nil, "bool[2][]"},
+		{"bool[][2]", nil, "bool[][2]"},
+		{"uint8[02]", nil, "uint8[2]"},
+		{"int256[2][3][4]", nil, "int256[2][3][4]"},
+		{"uint256[][2][]", nil, "uint256[][2][]"},
+		{"function", nil, "function"},
+		{"fixed", nil, "fixed128x18"},
+		{"ufixed", nil, "ufixed128x18"},
+		{"ufixed256x80[2]", nil, "ufixed256x80[2]"},
+		{"function[2]", nil, "function[2]"},
+		{"tuple", pair, "(uint256,bool)"},
+		{"tuple[]", pair, "(uint256,bool)[]"},
+		{"tuple[2]", pair, "(uint256,bool)[2]"},
+		{"tuple[2][]", pair, "(uint256,bool)[2][]"},
+		{"tuple[][2]", pair, "(uint256,bool)[][2]"},
+		{"tuple[][][]", pair, "(uint256,bool)[][][]"},
+		{"tuple[3][2][1]", pair, "(uint256,bool)[3][2][1]"},
+		{"tuple", nested, "((uint256,bool)[],bytes32[2],(string[][3]))"},
+		{"tuple[]", nested, "((uint256,bool)[],bytes32[2],(string[][3]))[]"},
+		{"tuple[2][]", nested, "((uint256,bool)[],bytes32[2],(string[][3]))[2][]"},
+	}
+	for _, tt := range tests {
+		typ, err := NewType(tt.blob, tt.components)
+		if err != nil {
+			t.Errorf("type %q: failed to parse type string: %v", tt.blob, err)
+			continue
+		}
+		if have := typ.String(); have != tt.want {
+			t.Errorf("type %q: string mismatch: have %s, want %s", tt.blob, have, tt.want)
+		}
+	}
+	for _, blob := range []string{"uint256abc", "bool8", "bytes0", "uint256[x]", "uint256[2 ]", "uint256[1][", "tuple8", "uint8x1", "fixed8", "fixed7x1", "fixed8x0", "ufixed264x1"} {
+		if _, err := NewType(blob, nil); err == nil {
+			t.Errorf("type %q: expected parse error", blob)
+		}
+	}
+}
+
+func TestTypeIntegerKind(t *testing.T) {
+	var table = []struct {
+		typ     string
+		integer bool
+		signed  bool
+	}{
+		{"int8", true, true},
+		{"int64", true, true},
+		{"int256", true, true},
+		{"uint8", true, false},
+		{"uint32", true, false},
+		{"uint256", true, false},
+		{"bool", false, false},
+		{"address", false, false},
+		{"bytes32", false, false},
+		{"string", false, false},
+		{"fixed128x18", false, false},
+		{"int8[]", false, false},
+		{"uint256[2]", false, false},
+	}
+	for _, test := range table {
+		typ, err := NewType(test.typ, nil)
+		if err != nil {
+			t.Fatalf("%s: %v", test.typ, err)
+		}
+		if typ.IsInteger() != test.integer {
+			t.Errorf("%s: integer mismatch: have %v, want %v", test.typ, typ.IsInteger(), test.integer)
+		}
+		if typ.IsSigned() != test.signed {
+			t.Errorf("%s: signed mismatch: have %v, want %v", test.typ, typ.IsSigned(), test.signed)
+		}
+	}
+}
+
+func TestNewTypeParenthesized(t *testing.T) {
+	var table = []struct {
+		input string
+		want  string
+	}{
+		{"(uint256,address)", "(uint256,address)"},
+		{"(uint256,address)[]", "(uint256,address)[]"},
+		{"(uint256,(bool,bytes))[2]", "(uint256,(bool,bytes))[2]"},
+		{"((uint8[2],string)[],bool)", "((uint8[2],string)[],bool)"},
+		{" ( uint256 , address ) ", "(uint256,address)"},
+		{"(uint256, (bool, bytes) [2])[ 3 ][]", "(uint256,(bool,bytes)[2])[3][]"},
+	}
+	for _, test := range table {
+		typ, err := NewType(test.input, nil)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", test.input, err)
+			continue
+		}
+		if typ.String() != test.want {
+			t.Errorf("%q: type mismatch: have %s, want %s", test.input, typ, test.want)
+		}
+	}
+	// The parsed type must be identical to the one built from components
+	parsed, err := NewType("(uint256,(bool,bytes))[2]", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	built, err := NewType("tuple[2]", []ArgumentMarshaling{
+		{Name: "arg0", Type: "uint256"},
+		{Name: "arg1", Type: "tuple", Components: []ArgumentMarshaling{{Name: "arg0", Type: "bool"}, {Name: "arg1", Type: "bytes"}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(parsed, built) {
+		t.Errorf("type mismatch: have %+v, want %+v", parsed, built)
+	}
+	for _, input := range []string{"(uint256", "(uint256,)", "(uint256))", "(uint256)(bool)", "(uint256,foo)", "(uint256)[x]"} {
+		if _, err := NewType(input, nil); err == nil {
+			t.Errorf("%q: expected error", input)
+		}
+	}
+}
+
+func TestNewTypeFixedBytesSize(t *testing.T) {
+	for _, typ := range []string{"bytes0", "bytes33", "bytes64", "bytes64[]"} {
+		_, err := NewType(typ, nil)
+		if err == nil {
+			t.Errorf("%s: expected error", typ)
+			continue
+		}
+		if !strings.Contains(err.Error(), "use bytes for longer values") {
+			t.Errorf("%s: error lacks guidance: %v", typ, err)
+		}
+	}
+	for _, typ := range []string{"bytes", "bytes1", "bytes32"} {
+		if _, err := NewType(typ, nil); err != nil {
+			t.Errorf("%s: unexpected error: %v", typ, err)
+		}
+	}
+}
+
+func TestNewTypeIntegerSize(t *testing.T) {
+	for _, typ := range []string{"int0", "uint7", "int7", "uint12", "int255", "uint264", "uint7[]", "int7[2]"} {
+		if _, err := NewType(typ, nil); err == nil || !strings.Contains(err.Error(), "unsupported arg type") {
+			t.Errorf("%s: expected unsupported type error, got %v", typ, err)
+		}
+	}
+	for _, typ := range []string{"int", "uint", "int8", "uint24", "int136", "uint256"} {
+		if _, err := NewType(typ, nil); err != nil {
+			t.Errorf("%s: unexpected error: %v", typ, err)
+		}
+	}
+}
+
+func TestTypeIsDynamic(t *testing.T) {
+	var table = []struct {
+		typ     string
+		dynamic bool
+	}{
+		{"uint256", false},
+		{"address", false},
+		{"bytes32", false},
+		{"bool", false},
+		{"bytes", true},
+		{"string", true},
+		{"uint256[2]", false},
+		{"uint256[]", true},
+		{"uint256[2][3]", false},
+		{"uint256[][3]", true},
+		{"string[2]", true},
+		{"(uint256,address)", false},
+		{"(uint256,string)", true},
+		{"(uint256,(bool,bytes))[2]", true},
+		{"(uint256,(bool,bytes32))[2]", false},
+	}
+	for _, test := range table {
+		typ, err := NewType(test.typ, nil)
+		if err != nil {
+			t.Fatalf("%s: %v", test.typ, err)
+		}
+		if typ.IsDynamic() != test.dynamic {
+			t.Errorf("%s: dynamic mismatch: have %v, want %v", test.typ, typ.IsDynamic(), test.dynamic)
+		}
+	}
+}
+
+func TestNewTypeAddressPayable(t *testing.T) {
+	for _, test := range []struct{ typ, want string }{
+		{"address payable", "address"},
+		{"address payable[]", "address[]"},
+		{"(address payable,uint256)", "(address,uint256)"},
+	} {
+		typ, err := NewType(test.typ, nil)
+		if err != nil {
+			t.Fatalf("%s: %v", test.typ, err)
+		}
+		if typ.String() != test.want {
+			t.Errorf("%s: type mismatch: have %s, want %s", test.typ, typ, test.want)
+		}
+	}
+	abi, err := JSON(strings.NewReader(`[
+		{"type":"function","name":"pay","inputs":[{"name":"to","type":"address payable"}]}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	method := abi.Methods["pay"]
+	if method.Inputs[0].Type.T != AddressTy || method.Sig() != "pay(address)" {
+		t.Errorf("method mismatch: have %s with %v", method.Sig(), method.Inputs[0].Type.T)
+	}
+	packed, err := abi.Pack("pay", common.Address{1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := append(method.Id(), common.LeftPadBytes(common.Address{1}.Bytes(), 32)...); !reflect.DeepEqual(packed, want) {
+		t.Errorf("pack mismatch: have %x, want %x", packed, want)
+	}
+}
+
+func TestNewTypeDuplicateComponents(t *testing.T) {
+	for _, test := range []struct {
+		components []ArgumentMarshaling
+		err        string
+	}{
+		{
+			[]ArgumentMarshaling{{Name: "a", Type: "uint256"}, {Name: "a", Type: "address"}},
+			`abi: duplicate tuple component "a"`,
+		},
+		{
+			[]ArgumentMarshaling{{Name: "token_id", Type: "uint256"}, {Name: "tokenId", Type: "uint256"}},
+			`abi: tuple components "token_id" and "tokenId" map to the same field TokenId`,
+		},
+	} {
+		if _, err := NewType("tuple", test.components); err == nil || err.Error() != test.err {
+			t.Errorf("error mismatch: have %v, want %s", err, test.err)
+		}
+	}
+	// Nested tuples may reuse the names of their parents
+	if _, err := NewType("tuple", []ArgumentMarshaling{{Name: "a", Type: "tuple", Components: []ArgumentMarshaling{{Name: "a", Type: "bool"}}}}); err != nil {
+		t.Errorf("unexpected error for nested component: %v", err)
+	}
+	_, err := JSON(strings.NewReader(`[{"type":"function","name":"f","inputs":[{"name":"p","type":"tuple","components":[{"name":"a","type":"uint8"},{"name":"a","type":"uint8"}]}]}]`))
+	if err == nil || !strings.Contains(err.Error(), `duplicate tuple component "a"`) {
+		t.Errorf("error mismatch: have %v", err)
+	}
+}
+
+func TestNewTypeCache(t *testing.T) {
+	components := []ArgumentMarshaling{{Name: "a", Type: "uint256"}, {Name: "b", Type: "string[]"}}
+	for _, test := range []struct {
+		typ        string
+		components []ArgumentMarshaling
+	}{
+		{"uint256", nil},
+		{"address[2][]", nil},
+		{"tuple[]", components},
+		{"(bytes32,bool)", nil},
+	} {
+		first, err := NewType(test.typ, test.components)
+		if err != nil {
+			t.Fatal(err)
+		}
+		second, err := NewType(test.typ, test.components)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fresh, err := newType(test.typ, test.components)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(first, second) || !reflect.DeepEqual(first, fresh) {
+			t.Errorf("%s: cached type mismatch:\nfirst %v\nsecond %v\nfresh %v", test.typ, spew.Sdump(typeWithoutStringer(first)), spew.Sdump(typeWithoutStringer(second)), spew.Sdump(typeWithoutStringer(fresh)))
+		}
+	}
+	// Modifying a result must not affect later ones
+	nested, _ := NewType("tuple[]", components)
+	nested.Elem.TupleElems[0].Size = 8
+	nested.Elem.TupleElems[1] = nil
+	nested.Elem.TupleRawNames[0] = "z"
+	nested.Elem = nil
+	again, err := NewType("tuple[]", components)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fresh, _ := newType("tuple[]", components)
+	if !reflect.DeepEqual(again, fresh) {
+		t.Errorf("cached type was modified:\nhave %v\nwant %v", spew.Sdump(typeWithoutStringer(again)), spew.Sdump(typeWithoutStringer(fresh)))
+	}
+	// Differing components must not share a cache entry
+	renamed, _ := NewType("tuple", []ArgumentMarshaling{{Name: "c", Type: "uint256"}, {Name: "b", Type: "string[]"}})
+	original, _ := NewType("tuple", components)
+	if renamed.TupleRawNames[0] != "c" || original.TupleRawNames[0] != "a" {
+		t.Errorf("component names mismatch: have %v and %v", renamed.TupleRawNames, original.TupleRawNames)
+	}
+}
+
+func BenchmarkNewTypeLargeABI(b *testing.B) {
+	// The argument types of an ABI with many methods of similar signatures
+	var args []ArgumentMarshaling
+	for i := 0; i < 200; i++ {
+		args = append(args,
+			ArgumentMarshaling{Type: "uint256"},
+			ArgumentMarshaling{Type: "address"},
+			ArgumentMarshaling{Type: "bytes32[]"},
+			ArgumentMarshaling{Type: "tuple", Components: []ArgumentMarshaling{{Name: "x", Type: "uint256"}, {Name: "y", Type: "address"}}},
+			ArgumentMarshaling{Type: "bool"},
+		)
+	}
+	b.Run("cached", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			for _, arg := range args {
+				if _, err := NewType(arg.Type, arg.Components); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+	b.Run("uncached", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			for _, arg := range args {
+				typeCache.Lock()
+				typeCache.types = make(map[string]Type)
+				typeCache.Unlock()
+				if _, err := NewType(arg.Type, arg.Components); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+}
@@ -17,14 +17,56 @@
 package abi
 
 import (
+	"context"
 	"encoding/binary"
 	"fmt"
 	"math/big"
 	"reflect"
+	"strconv"
 
 	"github.com/enode/common"
 )
 
+// DefaultMaxDepth is the nesting depth of arrays and tuples that is decoded
+// when UnpackOptions.MaxDepth is not set. It is far beyond anything a contract
+// would return, but keeps crafted types from recursing without bound.
+const DefaultMaxDepth = 128
+
+// UnpackOptions tweaks how decoded values are assigned to Go values. The zero
+// value corresponds to the behaviour of Unpack.
+type UnpackOptions struct {
+	// KeyField names a tuple component. When set and the single output is a
+	// tuple array, it can be unpacked into a map keyed by that component, which
+	// must be unique across the elements.
+	KeyField string
+
+	// LenientBool decodes any nonzero word as true instead of rejecting bool
+	// values other than 0 and 1.
+	LenientBool bool
+
+	// NumberFactory, if set, converts decoded integers into custom numeric types
+	// such as decimals. It is called for integer values whose destination can
+	// hold neither the decoded value nor any other Go integer, its result being
+	// assigned to the destination.
+	NumberFactory func(*big.Int, Type) (interface{}, error)
+
+	// IntegersAsStrings decodes integer values into their decimal string form
+	// instead of Go integers or *big.Int, sparing the allocation of the latter
+	// for most values. Arrays and tuples hold strings in place of integers.
+	IntegersAsStrings bool
+
+	// TypeHints maps the names of struct fields of interface type to the
+	// concrete types to decode their values into. The hints apply to fields of
+	// that name in nested structs as well.
+	TypeHints map[string]reflect.Type
+
+	// MaxDepth limits how deeply arrays and tuples may be nested in a decoded
+	// value. Zero means DefaultMaxDepth.
+	MaxDepth int
+
+	depth int // nesting depth of the value being decoded
+}
+
 var (
 	maxUint256 = big.NewInt(0).Add(
 		big.NewInt(0).Exp(big.NewInt(2), big.NewInt(256), nil),
@@ -71,8 +113,69 @@ func readInteger(typ byte, kind reflect.Kind, b []byte) interface{} {
 	}
 }
 
-// reads a bool
-func readBool(word []byte) (bool, error) {
+// readIntegerString reads the integer word of type t as a decimal string. Words
+// of 256 bit types are only turned into a big.Int if their value doesn't fit 64
+// bits.
+func readIntegerString(t Type, word []byte) string {
+	if t.Size <= 64 {
+		v := reflect.ValueOf(readInteger(t.T, t.Kind, word))
+		if t.T == IntTy {
+			return strconv.FormatInt(v.Int(), 10)
+		}
+		return strconv.FormatUint(v.Uint(), 10)
+	}
+	low := binary.BigEndian.Uint64(word[24:])
+	switch {
+	case allBytes(word[:24], 0x00) && (t.T == UintTy || low>>63 == 0):
+		return strconv.FormatUint(low, 10)
+	case t.T == IntTy && allBytes(word[:24], 0xff) && low>>63 == 1:
+		return strconv.FormatInt(int64(low), 10)
+	}
+	return readInteger(t.T, t.Kind, word).(*big.Int).String()
+}
+
+// allBytes returns whether all bytes of b equal c.
+func allBytes(b []byte, c byte) bool {
+	for _, x := range b {
+		if x != c {
+			return false
+		}
+	}
+	return true
+}
+
+// decodedType returns the Go type values of type t are decoded into.
+func decodedType(t Type, opts UnpackOptions) reflect.Type {
+	if !opts.IntegersAsStrings {
+		return t.Type
+	}
+	switch t.T {
+	case IntTy, UintTy:
+		return reflect.TypeOf("")
+	case SliceTy:
+		return reflect.SliceOf(decodedType(*t.Elem, opts))
+	case ArrayTy:
+		return reflect.ArrayOf(t.Size, decodedType(*t.Elem, opts))
+	case TupleTy:
+		fields := make([]reflect.StructField, len(t.TupleElems))
+		for i, elem := range t.TupleElems {
+			fields[i] = reflect.StructField{Name: t.Type.Field(i).Name, Type: decodedType(*elem, opts)}
+		}
+		return reflect.StructOf(fields)
+	}
+	return t.Type
+}
+
+// reads a bool, accepting any nonzero word as true if lenient is set
+func readBool(word []byte, lenient bool) (bool, error) {
+	if lenient {
+		for _, b := range word {
+			if b != 0 {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
 	for _, b := range word[:31] {
 		if b != 0 {
 			return false, errBadBool
@@ -116,12 +219,12 @@ func readFixedBytes(t Type, word []byte) (interface{}, error) {
 }
 
 // iteratively unpack elements
-func forEachUnpack(t Type, output []byte, start, size int) (interface{}, error) {
+func forEachUnpack(ctx context.Context, opts UnpackOptions, t Type, output []byte, start, size int) (interface{}, error) {
 	if size < 0 {
-		return nil, fmt.Errorf("cannot marshal input to array, size is negative (%d)", size)
+		return nil, errorf(ErrOutOfBounds, "cannot marshal input to array, size is negative (%d)", size)
 	}
 	if start+32*size > len(output) {
-		return nil, fmt.Errorf("abi: cannot marshal in to go array: offset %d would go over slice boundary (len=%d)", len(output), start+32*size)
+		return nil, errorf(ErrOutOfBounds, "abi: cannot marshal in to go array: offset %d would go over slice boundary (len=%d)", len(output), start+32*size)
 	}
 
 	// this value will become our slice or our array, depending on the type
@@ -129,10 +232,10 @@ func forEachUnpack(t Type, output []byte, start, size int) (interface{}, error)
 
 	if t.T == SliceTy {
 		// declare our slice
-		refSlice = reflect.MakeSlice(t.Type, size, size)
+		refSlice = reflect.MakeSlice(decodedType(t, opts), size, size)
 	} else if t.T == ArrayTy {
 		// declare our array
-		refSlice = reflect.New(t.Type).Elem()
+		refSlice = reflect.New(decodedType(t, opts)).Elem()
 	} else {
 		return nil, fmt.Errorf("abi: invalid type in array/slice unpacking stage")
 	}
@@ -142,7 +245,13 @@ func forEachUnpack(t Type, output []byte, start, size int) (interface{}, error)
 	elemSize := getTypeSize(*t.Elem)
 
 	for i, j := start, 0; j < size; i, j = i+elemSize, j+1 {
-		inter, err := toGoType(i, *t.Elem, output)
+		// bail out early if the caller is no longer interested
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+		}
+		inter, err := toGoType(ctx, opts, i, *t.Elem, output)
 		if err != nil {
 			return nil, err
 		}
@@ -155,11 +264,16 @@ func forEachUnpack(t Type, output []byte, start, size int) (interface{}, error)
 	return refSlice.Interface(), nil
 }
 
-func forTupleUnpack(t Type, output []byte) (interface{}, error) {
-	retval := reflect.New(t.Type).Elem()
+func forTupleUnpack(ctx context.Context, opts UnpackOptions, t Type, output []byte) (interface{}, error) {
+	retval := reflect.New(decodedType(t, opts)).Elem()
 	virtualArgs := 0
 	for index, elem := range t.TupleElems {
-		marshalledValue, err := toGoType((index+virtualArgs)*32, *elem, output)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+		}
+		marshalledValue, err := toGoType(ctx, opts, (index+virtualArgs)*32, *elem, output)
 		if elem.T == ArrayTy && !isDynamicType(*elem) {
 			// If we have a static array, like [3]uint256, these are coded as
 			// just like uint256,uint256,uint256.
@@ -187,9 +301,18 @@ func forTupleUnpack(t Type, output []byte) (interface{}, error) {
 
 // toGoType parses the output bytes and recursively assigns the value of these bytes
 // into a go type with accordance with the ABI spec.
-func toGoType(index int, t Type, output []byte) (interface{}, error) {
+func toGoType(ctx context.Context, opts UnpackOptions, index int, t Type, output []byte) (interface{}, error) {
 	if index+32 > len(output) {
-		return nil, fmt.Errorf("abi: cannot marshal in to go type: length insufficient %d require %d", len(output), index+32)
+		return nil, errorf(ErrOutOfBounds, "abi: cannot marshal in to go type: length insufficient %d require %d", len(output), index+32)
+	}
+	if t.T == TupleTy || t.T == SliceTy || t.T == ArrayTy {
+		max := opts.MaxDepth
+		if max == 0 {
+			max = DefaultMaxDepth
+		}
+		if opts.depth++; opts.depth > max {
+			return nil, fmt.Errorf("abi: %v exceeds maximum nesting depth of %d", t, max)
+		}
 	}
 
 	var (
@@ -215,24 +338,35 @@ func toGoType(index int, t Type, output []byte) (interface{}, error) {
 			if err != nil {
 				return nil, err
 			}
-			return forTupleUnpack(t, output[begin:])
+			return forTupleUnpack(ctx, opts, t, output[begin:])
 		} else {
-			return forTupleUnpack(t, output[index:])
+			return forTupleUnpack(ctx, opts, t, output[index:])
 		}
 	case SliceTy:
-		return forEachUnpack(t, output[begin:], 0, length)
+		return forEachUnpack(ctx, opts, t, output[begin:], 0, length)
 	case ArrayTy:
 		if isDynamicType(*t.Elem) {
-			offset := int64(binary.BigEndian.Uint64(returnOutput[len(returnOutput)-8:]))
-			return forEachUnpack(t, output[offset:], 0, t.Size)
+			offset, err := tuplePointsTo(index, output)
+			if err != nil {
+				return nil, err
+			}
+			return forEachUnpack(ctx, opts, t, output[offset:], 0, t.Size)
 		}
-		return forEachUnpack(t, output[index:], 0, t.Size)
+		return forEachUnpack(ctx, opts, t, output[index:], 0, t.Size)
 	case StringTy: // variable arrays are written at the end of the return bytes
 		return string(output[begin : begin+length]), nil
 	case IntTy, UintTy:
+		if opts.IntegersAsStrings {
+			return readIntegerString(t, returnOutput), nil
+		}
 		return readInteger(t.T, t.Kind, returnOutput), nil
+	case FixedPointTy:
+		if t.unsigned() {
+			return readInteger(UintTy, t.Kind, returnOutput), nil
+		}
+		return readInteger(IntTy, t.Kind, returnOutput), nil
 	case BoolTy:
-		return readBool(returnOutput)
+		return readBool(returnOutput, opts.LenientBool)
 	case AddressTy:
 		return common.BytesToAddress(returnOutput), nil
 	case HashTy:
@@ -255,11 +389,11 @@ func lengthPrefixPointsTo(index int, output []byte) (start int, length int, err
 	outputLength := big.NewInt(int64(len(output)))
 
 	if bigOffsetEnd.Cmp(outputLength) > 0 {
-		return 0, 0, fmt.Errorf("abi: cannot marshal in to go slice: offset %v would go over slice boundary (len=%v)", bigOffsetEnd, outputLength)
+		return 0, 0, errorf(ErrOutOfBounds, "abi: cannot marshal in to go slice: offset %v would go over slice boundary (len=%v)", bigOffsetEnd, outputLength)
 	}
 
 	if bigOffsetEnd.BitLen() > 63 {
-		return 0, 0, fmt.Errorf("abi offset larger than int64: %v", bigOffsetEnd)
+		return 0, 0, errorf(ErrOutOfBounds, "abi offset larger than int64: %v", bigOffsetEnd)
 	}
 
 	offsetEnd := int(bigOffsetEnd.Uint64())
@@ -269,27 +403,28 @@ func lengthPrefixPointsTo(index int, output []byte) (start int, length int, err
 	totalSize.Add(totalSize, bigOffsetEnd)
 	totalSize.Add(totalSize, lengthBig)
 	if totalSize.BitLen() > 63 {
-		return 0, 0, fmt.Errorf("abi length larger than int64: %v", totalSize)
+		return 0, 0, errorf(ErrOutOfBounds, "abi length larger than int64: %v", totalSize)
 	}
 
 	if totalSize.Cmp(outputLength) > 0 {
-		return 0, 0, fmt.Errorf("abi: cannot marshal in to go type: length insufficient %v require %v", outputLength, totalSize)
+		return 0, 0, errorf(ErrOutOfBounds, "abi: cannot marshal in to go type: length insufficient %v require %v", outputLength, totalSize)
 	}
 	start = int(bigOffsetEnd.Uint64())
 	length = int(lengthBig.Uint64())
 	return
 }
 
-// tuplePointsTo resolves the location reference for dynamic tuple.
+// tuplePointsTo resolves the location reference for dynamic tuple and for
+// arrays of dynamic elements.
 func tuplePointsTo(index int, output []byte) (start int, err error) {
 	offset := big.NewInt(0).SetBytes(output[index : index+32])
 	outputLen := big.NewInt(int64(len(output)))
 
 	if offset.Cmp(big.NewInt(int64(len(output)))) > 0 {
-		return 0, fmt.Errorf("abi: cannot marshal in to go slice: offset %v would go over slice boundary (len=%v)", offset, outputLen)
+		return 0, errorf(ErrOutOfBounds, "abi: cannot marshal in to go slice: offset %v would go over slice boundary (len=%v)", offset, outputLen)
 	}
 	if offset.BitLen() > 63 {
-		return 0, fmt.Errorf("abi offset larger than int64: %v", offset)
+		return 0, errorf(ErrOutOfBounds, "abi offset larger than int64: %v", offset)
 	}
 	return int(offset.Uint64()), nil
 }
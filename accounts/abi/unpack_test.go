@@ -18,13 +18,19 @@ package abi
 
 import (
 	"bytes"
+	"context"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
+	gmath "math"
 	"math/big"
 	"reflect"
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/enode/common"
 	"github.com/stretchr/testify/require"
@@ -85,13 +91,13 @@ var unpackTests = []unpackTest{
 		err:  "abi: cannot unmarshal uint32 in to uint16",
 	},
 	{
-		def:  `[{"type": "uint17"}]`,
+		def:  `[{"type": "uint24"}]`,
 		enc:  "0000000000000000000000000000000000000000000000000000000000000001",
 		want: uint16(0),
 		err:  "abi: cannot unmarshal *big.Int in to uint16",
 	},
 	{
-		def:  `[{"type": "uint17"}]`,
+		def:  `[{"type": "uint24"}]`,
 		enc:  "0000000000000000000000000000000000000000000000000000000000000001",
 		want: big.NewInt(1),
 	},
@@ -103,17 +109,22 @@ var unpackTests = []unpackTest{
 	{
 		def:  `[{"type": "int32"}]`,
 		enc:  "0000000000000000000000000000000000000000000000000000000000000001",
+		want: int16(1),
+	},
+	{
+		def:  `[{"type": "int32"}]`,
+		enc:  "0000000000000000000000000000000000000000000000000000000000008000",
 		want: int16(0),
-		err:  "abi: cannot unmarshal int32 in to int16",
+		err:  "abi: 32768 overflows int16",
 	},
 	{
-		def:  `[{"type": "int17"}]`,
-		enc:  "0000000000000000000000000000000000000000000000000000000000000001",
+		def:  `[{"type": "int24"}]`,
+		enc:  "000000000000000000000000000000000000000000000000000000000000ffff",
 		want: int16(0),
-		err:  "abi: cannot unmarshal *big.Int in to int16",
+		err:  "abi: 65535 overflows int16",
 	},
 	{
-		def:  `[{"type": "int17"}]`,
+		def:  `[{"type": "int24"}]`,
 		enc:  "0000000000000000000000000000000000000000000000000000000000000001",
 		want: big.NewInt(1),
 	},
@@ -122,6 +133,16 @@ var unpackTests = []unpackTest{
 		enc:  "ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff",
 		want: big.NewInt(-1),
 	},
+	{
+		def:  `[{"type": "fixed128x18"}]`,
+		enc:  "ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff",
+		want: big.NewInt(-1),
+	},
+	{
+		def:  `[{"type": "ufixed128x18"}]`,
+		enc:  "00000000000000000000000000000000000000000000000014d1120d7b160000",
+		want: big.NewInt(1500000000000000000),
+	},
 	{
 		def:  `[{"type": "address"}]`,
 		enc:  "0000000000000000000000000100000000000000000000000000000000000000",
@@ -1041,6 +1062,160 @@ func TestUnpackTuple(t *testing.T) {
 	}
 }
 
+func TestUnpackMapped(t *testing.T) {
+	const definition = `[{"name":"info","constant":true,"outputs":[
+		{"type":"tuple","name":"pos","components":[{"type":"uint256","name":"amount"},{"type":"address","name":"owner"},{"type":"tuple[]","name":"legs","components":[{"type":"int8","name":"x"},{"type":"string","name":"label"}]}]},
+		{"type":"bool","name":"ok"}
+	]}]`
+	abi, err := JSON(strings.NewReader(definition))
+	if err != nil {
+		t.Fatal(err)
+	}
+	type leg struct {
+		X     int8
+		Label string
+	}
+	type pos struct {
+		Amount *big.Int
+		Owner  common.Address
+		Legs   []leg
+	}
+	owner := common.HexToAddress("0x00Ce0d46d924CC8437c806721496599FC3FFA268")
+	data, err := abi.Methods["info"].Outputs.Pack(pos{big.NewInt(42), owner, []leg{{-1, "a"}, {2, "b"}}}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	values, err := abi.Methods["info"].Outputs.UnpackMapped(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []interface{}{
+		map[string]interface{}{
+			"amount": big.NewInt(42),
+			"owner":  owner,
+			"legs": []interface{}{
+				map[string]interface{}{"x": int8(-1), "label": "a"},
+				map[string]interface{}{"x": int8(2), "label": "b"},
+			},
+		},
+		true,
+	}
+	if !reflect.DeepEqual(values, want) {
+		t.Errorf("unpack mismatch:\nhave %#v\nwant %#v", values, want)
+	}
+}
+
+func TestUnpackValuesPacked(t *testing.T) {
+	for i, test := range packTests {
+		typ, err := NewType(test.typ, test.components)
+		if err != nil {
+			t.Fatalf("test %d: unexpected parse error: %v", i, err)
+		}
+		args := Arguments{{Type: typ}}
+		// Dynamic types are referenced by an offset in the argument list
+		data := test.output
+		if isDynamicType(typ) {
+			data = append(common.LeftPadBytes([]byte{0x20}, 32), data...)
+		}
+		values, err := args.UnpackValues(data)
+		if err != nil {
+			t.Errorf("test %d (%v): unexpected unpack error: %v", i, typ, err)
+			continue
+		}
+		if len(values) != 1 {
+			t.Errorf("test %d (%v): value count mismatch: have %d, want 1", i, typ, len(values))
+			continue
+		}
+		if have := reflect.TypeOf(values[0]); have != typ.Type {
+			t.Errorf("test %d (%v): type mismatch: have %v, want %v", i, typ, have, typ.Type)
+		}
+		// Inputs may use different Go types than the decoded values, compare
+		// them by encoding the decoded values again.
+		packed, err := typ.pack(reflect.ValueOf(values[0]))
+		if err != nil {
+			t.Errorf("test %d (%v): unexpected pack error: %v", i, typ, err)
+			continue
+		}
+		if !bytes.Equal(packed, test.output) {
+			t.Errorf("test %d (%v): round trip mismatch: have %x, want %x", i, typ, packed, test.output)
+		}
+	}
+}
+
+// cancelAfterContext is a context which gets cancelled after its Done channel
+// has been polled a given number of times.
+type cancelAfterContext struct {
+	context.Context
+	cancel func()
+	polls  int
+}
+
+func (ctx *cancelAfterContext) Done() <-chan struct{} {
+	if ctx.polls--; ctx.polls == 0 {
+		ctx.cancel()
+	}
+	return ctx.Context.Done()
+}
+
+func TestUnpackContext(t *testing.T) {
+	typ, _ := NewType("uint256[]", nil)
+	args := Arguments{{Type: typ}}
+
+	const size = 100000
+	data := make([]byte, 32*(size+2))
+	data[31] = 0x20
+	copy(data[32:64], common.LeftPadBytes(big.NewInt(size).Bytes(), 32))
+
+	values, err := args.UnpackContext(context.Background(), data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n := len(values[0].([]*big.Int)); n != size {
+		t.Fatalf("element count mismatch: have %d, want %d", n, size)
+	}
+	parent, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ctx := &cancelAfterContext{Context: parent, cancel: cancel, polls: size / 2}
+	if _, err := args.UnpackContext(ctx, data); err != context.Canceled {
+		t.Fatalf("error mismatch: have %v, want %v", err, context.Canceled)
+	}
+	if ctx.polls != 0 {
+		t.Errorf("decoding continued after cancellation: %d extra polls", -ctx.polls)
+	}
+}
+
+func TestUnpackWithWords(t *testing.T) {
+	abi, err := JSON(strings.NewReader(`[{"type":"function","name":"f","inputs":[
+		{"name":"id","type":"uint256"},{"name":"memo","type":"string"},{"name":"pair","type":"uint8[2]"}
+	]}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	args := abi.Methods["f"].Inputs
+	data := args.MustPack(big.NewInt(7), "hello", [2]uint8{1, 2})
+
+	values, words, err := args.UnpackWithWords(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(values) != 3 || values[0].(*big.Int).Int64() != 7 || values[1] != "hello" || values[2] != [2]uint8{1, 2} {
+		t.Errorf("values mismatch: have %v", values)
+	}
+	// id, offset of memo and the two inlined array elements
+	if len(words) != 4 {
+		t.Fatalf("word count mismatch: have %d, want 4", len(words))
+	}
+	for i, want := range []int64{7, 4 * 32, 1, 2} {
+		if have := new(big.Int).SetBytes(words[i][:]); have.Int64() != want {
+			t.Errorf("word %d mismatch: have %v, want %d", i, have, want)
+		}
+	}
+	if _, _, err := args.UnpackWithWords(data[:100]); err == nil {
+		t.Error("expected error for truncated data")
+	}
+}
+
 func TestOOMMaliciousInput(t *testing.T) {
 	oomTests := []unpackTest{
 		{
@@ -1110,3 +1285,925 @@ func TestOOMMaliciousInput(t *testing.T) {
 		}
 	}
 }
+
+func TestUnpackKeyed(t *testing.T) {
+	abi, err := JSON(strings.NewReader(`[
+		{"type":"function","name":"items","outputs":[{"name":"","type":"tuple[]","components":[{"name":"id","type":"uint64"},{"name":"owner","type":"address"},{"name":"amount","type":"uint256"}]}]},
+		{"type":"function","name":"count","outputs":[{"name":"","type":"uint64[]"}]}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	type item struct {
+		Id     uint64
+		Owner  common.Address
+		Amount *big.Int
+	}
+	items := []item{
+		{Id: 7, Owner: common.Address{1}, Amount: big.NewInt(100)},
+		{Id: 3, Owner: common.Address{2}, Amount: big.NewInt(200)},
+	}
+	output, err := abi.Methods["items"].Outputs.Pack(items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded map[uint64]item
+	if err := abi.UnpackWithOptions(UnpackOptions{KeyField: "id"}, &decoded, "items", output); err != nil {
+		t.Fatal(err)
+	}
+	want := map[uint64]item{7: items[0], 3: items[1]}
+	if !reflect.DeepEqual(decoded, want) {
+		t.Errorf("decoded map mismatch: have %+v, want %+v", decoded, want)
+	}
+
+	// Duplicate keys must be rejected
+	output, err = abi.Methods["items"].Outputs.Pack([]item{items[0], items[0]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := abi.UnpackWithOptions(UnpackOptions{KeyField: "id"}, &decoded, "items", output); err == nil || !strings.Contains(err.Error(), "duplicate key") {
+		t.Errorf("error mismatch: have %v, want duplicate key", err)
+	}
+	// Unknown key fields and non tuple arrays must be rejected
+	if err := abi.UnpackWithOptions(UnpackOptions{KeyField: "missing"}, &decoded, "items", output); err == nil {
+		t.Error("expected error for unknown key field")
+	}
+	output, err = abi.Methods["count"].Outputs.Pack([]uint64{1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := abi.UnpackWithOptions(UnpackOptions{KeyField: "id"}, &decoded, "count", output); err == nil {
+		t.Error("expected error for non tuple array output")
+	}
+}
+
+func TestUnpackLenientBool(t *testing.T) {
+	abi, err := JSON(strings.NewReader(`[
+		{"type":"function","name":"flag","outputs":[{"name":"","type":"bool"}]},
+		{"type":"function","name":"flags","outputs":[{"name":"","type":"bool[2]"}]}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var table = []struct {
+		word    string
+		strict  bool
+		lenient bool
+		err     bool
+	}{
+		{"0000000000000000000000000000000000000000000000000000000000000000", false, false, false},
+		{"0000000000000000000000000000000000000000000000000000000000000001", true, true, false},
+		{"0000000000000000000000000000000000000000000000000000000000000002", false, true, true},
+		{"0100000000000000000000000000000000000000000000000000000000000001", false, true, true},
+	}
+	for i, test := range table {
+		word := common.Hex2Bytes(test.word)
+
+		var strict bool
+		err := abi.Unpack(&strict, "flag", word)
+		if test.err {
+			if err == nil {
+				t.Errorf("test %d: expected strict error", i)
+			}
+		} else if err != nil || strict != test.strict {
+			t.Errorf("test %d: strict mismatch: have %v (%v), want %v", i, strict, err, test.strict)
+		}
+		var lenient bool
+		if err := abi.UnpackWithOptions(UnpackOptions{LenientBool: true}, &lenient, "flag", word); err != nil || lenient != test.lenient {
+			t.Errorf("test %d: lenient mismatch: have %v (%v), want %v", i, lenient, err, test.lenient)
+		}
+	}
+	// Lenient decoding applies to array elements as well
+	output := common.Hex2Bytes("0000000000000000000000000000000000000000000000000000000000000000" +
+		"0000000000000000000000000000000000000000000000000000000000000002")
+	var flags [2]bool
+	if err := abi.Unpack(&flags, "flags", output); err == nil {
+		t.Error("expected strict error for bool array")
+	}
+	if err := abi.UnpackWithOptions(UnpackOptions{LenientBool: true}, &flags, "flags", output); err != nil || flags != [2]bool{false, true} {
+		t.Errorf("lenient array mismatch: have %v (%v), want [false true]", flags, err)
+	}
+}
+
+func TestUnpackNestedDynamicArrays(t *testing.T) {
+	var table = []struct {
+		typ   string
+		value interface{}
+	}{
+		{"string[][]", [][]string{{"a"}, {}, {"bb", "ccc", "d"}}},
+		{"string[][]", [][]string{}},
+		{"string[][][]", [][][]string{{{"a"}, {}}, {}, {{"bb", "ccc", "d"}, {"e"}, {"f", "g"}}}},
+		{"string[][][]", [][][]string{{}, {{}}, {{"", "long string spanning more than a single thirty two byte word"}}}},
+	}
+	for i, test := range table {
+		typ, err := NewType(test.typ, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Surround the value by other arguments to make sure offsets are
+		// followed relative to the right position at every level.
+		str, _ := NewType("string", nil)
+		args := Arguments{{Name: "a", Type: str}, {Name: "b", Type: typ}, {Name: "c", Type: typ}}
+		packed, err := args.Pack("head", test.value, test.value)
+		if err != nil {
+			t.Fatalf("test %d (%s): pack failed: %v", i, test.typ, err)
+		}
+		values, err := args.UnpackValues(packed)
+		if err != nil {
+			t.Fatalf("test %d (%s): unpack failed: %v", i, test.typ, err)
+		}
+		if !reflect.DeepEqual(values[1], test.value) || !reflect.DeepEqual(values[2], test.value) {
+			t.Errorf("test %d (%s): value mismatch: have %v, want %v", i, test.typ, values[1:], test.value)
+		}
+		// Typed destinations must be filled just the same
+		single := Arguments{{Name: "b", Type: typ}}
+		if packed, err = single.Pack(test.value); err != nil {
+			t.Fatalf("test %d (%s): pack failed: %v", i, test.typ, err)
+		}
+		dst := reflect.New(reflect.TypeOf(test.value))
+		if err := single.Unpack(dst.Interface(), packed); err != nil {
+			t.Fatalf("test %d (%s): typed unpack failed: %v", i, test.typ, err)
+		}
+		if !reflect.DeepEqual(dst.Elem().Interface(), test.value) {
+			t.Errorf("test %d (%s): typed value mismatch: have %v, want %v", i, test.typ, dst.Elem(), test.value)
+		}
+	}
+}
+
+func TestUnpackFixedPointRat(t *testing.T) {
+	abi, err := JSON(strings.NewReader(`[
+		{"type":"function","name":"price","outputs":[{"name":"price","type":"ufixed128x18"},{"name":"delta","type":"fixed64x4"}]},
+		{"type":"function","name":"prices","outputs":[{"name":"","type":"fixed128x2[]"}]}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	price, _ := new(big.Rat).SetString("1234.000000000000000001")
+	delta, _ := new(big.Rat).SetString("-0.0125")
+
+	output, err := abi.Methods["price"].Outputs.Pack(price, delta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rats struct {
+		Price *big.Rat
+		Delta big.Rat
+	}
+	if err := abi.Unpack(&rats, "price", output); err != nil {
+		t.Fatal(err)
+	}
+	if rats.Price.Cmp(price) != 0 || rats.Delta.Cmp(delta) != 0 {
+		t.Errorf("rational mismatch: have %v %v, want %v %v", rats.Price, &rats.Delta, price, delta)
+	}
+	var strs struct {
+		Price string
+		Delta string
+	}
+	if err := abi.Unpack(&strs, "price", output); err != nil {
+		t.Fatal(err)
+	}
+	if strs.Price != "1234.000000000000000001" || strs.Delta != "-0.0125" {
+		t.Errorf("string mismatch: have %s %s", strs.Price, strs.Delta)
+	}
+	// The raw scaled integer is still available
+	var raw struct {
+		Price *big.Int
+		Delta *big.Int
+	}
+	if err := abi.Unpack(&raw, "price", output); err != nil {
+		t.Fatal(err)
+	}
+	if raw.Delta.Cmp(big.NewInt(-125)) != 0 {
+		t.Errorf("raw mismatch: have %v, want -125", raw.Delta)
+	}
+	// Arrays of fixed point numbers are converted element by element
+	list := []*big.Rat{big.NewRat(-3, 2), big.NewRat(1, 100), new(big.Rat)}
+	if output, err = abi.Methods["prices"].Outputs.Pack(list); err != nil {
+		t.Fatal(err)
+	}
+	var decoded []*big.Rat
+	if err := abi.Unpack(&decoded, "prices", output); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded) != len(list) {
+		t.Fatalf("length mismatch: have %d, want %d", len(decoded), len(list))
+	}
+	for i := range list {
+		if decoded[i].Cmp(list[i]) != 0 {
+			t.Errorf("element %d mismatch: have %v, want %v", i, decoded[i], list[i])
+		}
+	}
+}
+
+func TestUnpackFixedBytesIntoBigInt(t *testing.T) {
+	abi, err := JSON(strings.NewReader(`[
+		{"type":"function","name":"slot","outputs":[{"name":"key","type":"bytes32"},{"name":"id","type":"bytes4"}]}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	output := common.Hex2Bytes("00000000000000000000000000000000000000000000000000000000deadbeef" +
+		"1234567800000000000000000000000000000000000000000000000000000000")
+
+	var numbers struct {
+		Key *big.Int
+		Id  big.Int
+	}
+	if err := abi.Unpack(&numbers, "slot", output); err != nil {
+		t.Fatal(err)
+	}
+	if numbers.Key.Cmp(big.NewInt(0xdeadbeef)) != 0 {
+		t.Errorf("key mismatch: have %x, want deadbeef", numbers.Key)
+	}
+	if numbers.Id.Cmp(big.NewInt(0x12345678)) != 0 {
+		t.Errorf("id mismatch: have %x, want 12345678", &numbers.Id)
+	}
+	// Byte array destinations keep working
+	var arrays struct {
+		Key [32]byte
+		Id  [4]byte
+	}
+	if err := abi.Unpack(&arrays, "slot", output); err != nil {
+		t.Fatal(err)
+	}
+	if arrays.Key != common.BytesToHash(common.Hex2Bytes("deadbeef")) || arrays.Id != [4]byte{0x12, 0x34, 0x56, 0x78} {
+		t.Errorf("array mismatch: have %x %x", arrays.Key, arrays.Id)
+	}
+}
+
+func TestUnpackNestedTuplePointers(t *testing.T) {
+	abi, err := JSON(strings.NewReader(`[{"type":"function","name":"tree","outputs":[{"name":"root","type":"tuple","components":[
+		{"name":"name","type":"string"},
+		{"name":"child","type":"tuple","components":[
+			{"name":"ids","type":"uint256[]"},
+			{"name":"leaves","type":"tuple[]","components":[{"name":"label","type":"string"},{"name":"value","type":"uint8"}]}
+		]}
+	]}]}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	type leaf struct {
+		Label string
+		Value uint8
+	}
+	type child struct {
+		Ids    []*big.Int
+		Leaves []*leaf
+	}
+	type root struct {
+		Name  string
+		Child *child
+	}
+	want := &root{
+		Name: "root",
+		Child: &child{
+			Ids:    []*big.Int{big.NewInt(1), big.NewInt(2)},
+			Leaves: []*leaf{{"a", 1}, {"b", 2}},
+		},
+	}
+	output, err := abi.Methods["tree"].Outputs.Pack(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded struct {
+		Root *root
+	}
+	if err := abi.Unpack(&decoded, "tree", output); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded.Root, want) {
+		t.Errorf("unpack mismatch: have %+v, want %+v", decoded.Root, want)
+	}
+}
+
+func TestUnpackValuesRepack(t *testing.T) {
+	var (
+		all    Arguments
+		inputs []interface{}
+	)
+	for i, test := range packTests {
+		typ, err := NewType(test.typ, test.components)
+		if err != nil {
+			t.Fatalf("test %d: unexpected parse error: %v", i, err)
+		}
+		args := Arguments{{Type: typ}}
+		data, err := args.Pack(test.input)
+		if err != nil {
+			t.Fatalf("test %d (%v): unexpected pack error: %v", i, typ, err)
+		}
+		values, err := args.UnpackValues(data)
+		if err != nil {
+			t.Fatalf("test %d (%v): unexpected unpack error: %v", i, typ, err)
+		}
+		repacked, err := args.PackValues(values)
+		if err != nil {
+			t.Errorf("test %d (%v): unexpected repack error: %v", i, typ, err)
+			continue
+		}
+		if !bytes.Equal(repacked, data) {
+			t.Errorf("test %d (%v): repack mismatch: have %x, want %x", i, typ, repacked, data)
+		}
+		all = append(all, Argument{Name: fmt.Sprintf("arg%d", i), Type: typ})
+		inputs = append(inputs, test.input)
+	}
+	// All vectors at once, to cover the offsets between arguments too
+	data, err := all.Pack(inputs...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	values, err := all.UnpackValues(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repacked, err := all.PackValues(values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(repacked, data) {
+		t.Errorf("repack mismatch of all vectors: have %x, want %x", repacked, data)
+	}
+}
+
+func TestUnpackUnixTime(t *testing.T) {
+	abi, err := JSON(strings.NewReader(`[
+		{"type":"function","name":"order","outputs":[{"name":"amount","type":"uint256"},{"name":"deadline","type":"uint256"}]}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Date(2030, time.January, 1, 12, 0, 0, 0, time.UTC)
+	output, err := abi.Methods["order"].Outputs.Pack(big.NewInt(5), big.NewInt(deadline.Unix()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var order struct {
+		Amount   *big.Int
+		Deadline time.Time `abi:"deadline,unixtime"`
+	}
+	if err := abi.Unpack(&order, "order", output); err != nil {
+		t.Fatal(err)
+	}
+	if !order.Deadline.Equal(deadline) {
+		t.Errorf("deadline mismatch: have %v, want %v", order.Deadline, deadline)
+	}
+	// Timestamps beyond time.Time's range are rejected
+	huge := new(big.Int).Lsh(common.Big1, 100)
+	if output, err = abi.Methods["order"].Outputs.Pack(big.NewInt(5), huge); err != nil {
+		t.Fatal(err)
+	}
+	if err := abi.Unpack(&order, "order", output); !errors.Is(err, ErrOverflow) {
+		t.Errorf("expected overflow error, got %v", err)
+	}
+	// The option requires a time.Time destination
+	var wrong struct {
+		Amount   *big.Int
+		Deadline uint64 `abi:"deadline,unixtime"`
+	}
+	if err := abi.Unpack(&wrong, "order", output); err == nil {
+		t.Error("expected error for non time.Time unixtime field")
+	}
+}
+
+func TestUnpackNarrowSignedInteger(t *testing.T) {
+	abi, err := JSON(strings.NewReader(`[
+		{"type":"function","name":"delta","outputs":[{"name":"value","type":"int256"}]},
+		{"type":"function","name":"small","outputs":[{"name":"value","type":"int64"}]}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pack := func(method string, value interface{}) []byte {
+		output, err := abi.Methods[method].Outputs.Pack(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return output
+	}
+	var wide int64
+	if err := abi.Unpack(&wide, "delta", pack("delta", big.NewInt(-5))); err != nil {
+		t.Fatal(err)
+	}
+	if wide != -5 {
+		t.Errorf("value mismatch: have %d, want -5", wide)
+	}
+	var field struct{ Value int64 }
+	if err := abi.Unpack(&field, "delta", pack("delta", big.NewInt(gmath.MinInt64))); err != nil {
+		t.Fatal(err)
+	}
+	if field.Value != gmath.MinInt64 {
+		t.Errorf("value mismatch: have %d, want %d", field.Value, int64(gmath.MinInt64))
+	}
+	tooSmall := new(big.Int).Sub(big.NewInt(gmath.MinInt64), common.Big1)
+	if err := abi.Unpack(&wide, "delta", pack("delta", tooSmall)); !errors.Is(err, ErrOverflow) {
+		t.Errorf("expected overflow error, got %v", err)
+	}
+	var narrow int8
+	if err := abi.Unpack(&narrow, "small", pack("small", int64(-128))); err != nil {
+		t.Fatal(err)
+	}
+	if narrow != -128 {
+		t.Errorf("value mismatch: have %d, want -128", narrow)
+	}
+	if err := abi.Unpack(&narrow, "small", pack("small", int64(-129))); !errors.Is(err, ErrOverflow) {
+		t.Errorf("expected overflow error, got %v", err)
+	}
+}
+
+const singleOutputABI = `[
+	{"type":"function","name":"balanceOf","inputs":[{"name":"owner","type":"address"}],"outputs":[{"name":"","type":"uint256"}]},
+	{"type":"function","name":"owner","outputs":[{"name":"","type":"address"}]}
+]`
+
+func TestUnpackSingleWord(t *testing.T) {
+	abi, err := JSON(strings.NewReader(singleOutputABI))
+	if err != nil {
+		t.Fatal(err)
+	}
+	balance, err := abi.Methods["balanceOf"].Outputs.UnpackUint256(common.LeftPadBytes([]byte{0x12, 0x34}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if balance.Cmp(big.NewInt(0x1234)) != 0 {
+		t.Errorf("balance mismatch: have %v, want %v", balance, 0x1234)
+	}
+	owner, err := abi.Methods["owner"].Outputs.UnpackAddress(common.LeftPadBytes(common.Address{1}.Bytes(), 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if owner != (common.Address{1}) {
+		t.Errorf("owner mismatch: have %x", owner)
+	}
+	// The output shape must match
+	if _, err := abi.Methods["owner"].Outputs.UnpackUint256(make([]byte, 32)); !errors.Is(err, ErrTypeMismatch) {
+		t.Errorf("expected type mismatch error, got %v", err)
+	}
+	if _, err := abi.Methods["balanceOf"].Inputs.UnpackAddress(make([]byte, 32)); err != nil {
+		t.Errorf("single address input rejected: %v", err)
+	}
+	if _, err := abi.Methods["balanceOf"].Outputs.UnpackUint256(make([]byte, 31)); !errors.Is(err, ErrOutOfBounds) {
+		t.Errorf("expected out of bounds error, got %v", err)
+	}
+}
+
+func BenchmarkUnpackUint256(b *testing.B) {
+	abi, err := JSON(strings.NewReader(singleOutputABI))
+	if err != nil {
+		b.Fatal(err)
+	}
+	outputs := abi.Methods["balanceOf"].Outputs
+	data := common.LeftPadBytes([]byte{0x12, 0x34}, 32)
+
+	b.Run("fast", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			outputs.UnpackUint256(data)
+		}
+	})
+	b.Run("general", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			var balance *big.Int
+			abi.Unpack(&balance, "balanceOf", data)
+		}
+	})
+}
+
+func BenchmarkUnpackAddress(b *testing.B) {
+	abi, err := JSON(strings.NewReader(singleOutputABI))
+	if err != nil {
+		b.Fatal(err)
+	}
+	outputs := abi.Methods["owner"].Outputs
+	data := common.LeftPadBytes(common.Address{1}.Bytes(), 32)
+
+	b.Run("fast", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			outputs.UnpackAddress(data)
+		}
+	})
+	b.Run("general", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			var owner common.Address
+			abi.Unpack(&owner, "owner", data)
+		}
+	})
+}
+
+func TestUnpackTupleReorderedFields(t *testing.T) {
+	abi, err := JSON(strings.NewReader(`[
+		{"type":"function","name":"order","outputs":[
+			{"name":"o","type":"tuple","components":[{"name":"id","type":"uint256"},{"name":"maker","type":"address"},{"name":"memo","type":"string"},{"name":"fee","type":"uint256"}]},
+			{"name":"count","type":"uint8"}
+		]}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	type order struct {
+		Id    *big.Int
+		Maker common.Address
+		Memo  string
+		Fee   *big.Int
+	}
+	output, err := abi.Methods["order"].Outputs.Pack(order{big.NewInt(1), common.Address{2}, "three", big.NewInt(4)}, uint8(5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var reordered struct {
+		Count uint8
+		O     struct {
+			Memo  string
+			Cost  *big.Int `abi:"fee"`
+			Maker common.Address
+			Id    *big.Int
+		}
+	}
+	if err := abi.Unpack(&reordered, "order", output); err != nil {
+		t.Fatal(err)
+	}
+	o := reordered.O
+	if o.Id.Cmp(big.NewInt(1)) != 0 || o.Maker != (common.Address{2}) || o.Memo != "three" || o.Cost.Cmp(big.NewInt(4)) != 0 || reordered.Count != 5 {
+		t.Errorf("reordered fields mismatch: have %+v, count %d", o, reordered.Count)
+	}
+}
+
+func TestUnpackTupleJSONTags(t *testing.T) {
+	abi, err := JSON(strings.NewReader(`[
+		{"type":"function","name":"order","outputs":[
+			{"name":"o","type":"tuple","components":[{"name":"order_id","type":"uint256"},{"name":"maker_addr","type":"address"},{"name":"memo","type":"string"}]}
+		]}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	output, err := abi.Methods["order"].Outputs.Pack(struct {
+		OrderId   *big.Int
+		MakerAddr common.Address
+		Memo      string
+	}{big.NewInt(1), common.Address{2}, "three"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var result struct {
+		O struct {
+			ID    *big.Int       `json:"order_id"`
+			Maker common.Address `json:"maker_addr,omitempty"`
+			Note  string         `json:"memo" abi:"memo"`
+		}
+	}
+	if err := abi.Unpack(&result, "order", output); err != nil {
+		t.Fatal(err)
+	}
+	if tagged := result.O; tagged.ID.Cmp(big.NewInt(1)) != 0 || tagged.Maker != (common.Address{2}) || tagged.Note != "three" {
+		t.Errorf("json tagged fields mismatch: have %+v", tagged)
+	}
+}
+
+func TestUnpackFieldError(t *testing.T) {
+	abi, err := JSON(strings.NewReader(`[
+		{"type":"function","name":"transfer","outputs":[{"name":"to","type":"address"},{"name":"amount","type":"uint256"}]},
+		{"type":"function","name":"balance","outputs":[{"name":"amount","type":"uint256"}]}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	output := abi.Methods["transfer"].Outputs.MustPack(common.Address{1}, big.NewInt(5))
+	var transfer struct {
+		To     common.Address
+		Amount string
+	}
+	err = abi.Unpack(&transfer, "transfer", output)
+	if want := `abi: output "amount" -> field Amount: cannot unmarshal *big.Int in to string`; err == nil || err.Error() != want {
+		t.Errorf("error mismatch: have %v, want %s", err, want)
+	}
+	if !errors.Is(err, ErrTypeMismatch) {
+		t.Errorf("error kind lost: have %v", err)
+	}
+	// Single outputs unpacked into a struct are annotated too
+	var balance struct {
+		Amount bool `abi:"amount"`
+	}
+	err = abi.Unpack(&balance, "balance", output[32:])
+	if want := `abi: output "amount" -> field Amount: cannot unmarshal *big.Int in to bool`; err == nil || err.Error() != want {
+		t.Errorf("error mismatch: have %v, want %s", err, want)
+	}
+}
+
+// byteCounter is an io.ReaderFrom that only counts the bytes read.
+type byteCounter struct{ n int64 }
+
+func (c *byteCounter) ReadFrom(r io.Reader) (int64, error) {
+	n, err := io.Copy(ioutil.Discard, r)
+	c.n += n
+	return n, err
+}
+
+func TestUnpackBytesReaderFrom(t *testing.T) {
+	abi, err := JSON(strings.NewReader(`[
+		{"type":"function","name":"blob","outputs":[{"name":"data","type":"bytes"},{"name":"size","type":"uint32"}]}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload := bytes.Repeat([]byte{0xab, 0xcd}, 1<<19)
+	output := abi.Methods["blob"].Outputs.MustPack(payload, uint32(len(payload)))
+
+	var buffered struct {
+		Data bytes.Buffer
+		Size uint32
+	}
+	if err := abi.Unpack(&buffered, "blob", output); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buffered.Data.Bytes(), payload) || buffered.Size != uint32(len(payload)) {
+		t.Errorf("buffered payload mismatch: have %d bytes, size %d", buffered.Data.Len(), buffered.Size)
+	}
+	// Nil pointers are allocated, interfaces are read into as they are
+	var counted struct {
+		Data *byteCounter
+		Size uint32
+	}
+	if err := abi.Unpack(&counted, "blob", output); err != nil {
+		t.Fatal(err)
+	}
+	if counted.Data == nil || counted.Data.n != int64(len(payload)) {
+		t.Errorf("counted payload mismatch: have %+v", counted.Data)
+	}
+	counter := new(byteCounter)
+	streamed := struct {
+		Data io.ReaderFrom
+		Size uint32
+	}{Data: counter}
+	if err := abi.Unpack(&streamed, "blob", output); err != nil {
+		t.Fatal(err)
+	}
+	if counter.n != int64(len(payload)) {
+		t.Errorf("streamed payload mismatch: have %d bytes", counter.n)
+	}
+}
+
+func TestUnpackGoArray(t *testing.T) {
+	abi, err := JSON(strings.NewReader(`[
+		{"type":"function","name":"prices","outputs":[{"name":"values","type":"uint256[3]"}]}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [3]*big.Int{big.NewInt(1), big.NewInt(2), big.NewInt(3)}
+	output, err := abi.Methods["prices"].Outputs.Pack(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var values [3]*big.Int
+	if err := abi.Unpack(&values, "prices", output); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(values, want) {
+		t.Errorf("array mismatch: have %v, want %v", values, want)
+	}
+	var field struct{ Values [3]*big.Int }
+	if err := abi.Unpack(&field, "prices", output); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(field.Values, want) {
+		t.Errorf("array field mismatch: have %v, want %v", field.Values, want)
+	}
+	var short [2]*big.Int
+	if err := abi.Unpack(&short, "prices", output); err == nil {
+		t.Error("expected error for array length mismatch")
+	}
+	// Arrays decoded element by element are checked as well
+	abi, err = JSON(strings.NewReader(`[
+		{"type":"function","name":"points","outputs":[{"name":"values","type":"tuple[3]","components":[{"name":"x","type":"uint8"}]}]}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	type point struct{ X uint8 }
+	if output, err = abi.Methods["points"].Outputs.Pack([3]point{{1}, {2}, {3}}); err != nil {
+		t.Fatal(err)
+	}
+	var points [3]point
+	if err := abi.Unpack(&points, "points", output); err != nil {
+		t.Fatal(err)
+	}
+	if points != [3]point{{1}, {2}, {3}} {
+		t.Errorf("tuple array mismatch: have %v", points)
+	}
+	var shortPoints [2]point
+	if err := abi.Unpack(&shortPoints, "points", output); !errors.Is(err, ErrTypeMismatch) {
+		t.Errorf("expected type mismatch error, got %v", err)
+	}
+	var longPoints [4]point
+	if err := abi.Unpack(&longPoints, "points", output); !errors.Is(err, ErrTypeMismatch) {
+		t.Errorf("expected type mismatch error, got %v", err)
+	}
+}
+
+// testDecimal is a stand-in for third party decimal types.
+type testDecimal struct {
+	units *big.Int
+	exp   int
+}
+
+func TestUnpackNumberFactory(t *testing.T) {
+	abi, err := JSON(strings.NewReader(`[
+		{"type":"function","name":"quote","outputs":[{"name":"price","type":"uint256"},{"name":"count","type":"uint8"},{"name":"raw","type":"uint256"}]}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	output, err := abi.Methods["quote"].Outputs.Pack(big.NewInt(1500), uint8(3), big.NewInt(7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var calls int
+	opts := UnpackOptions{NumberFactory: func(n *big.Int, typ Type) (interface{}, error) {
+		calls++
+		if typ.String() != "uint256" {
+			return nil, fmt.Errorf("unexpected type %v", typ)
+		}
+		return testDecimal{units: n, exp: -2}, nil
+	}}
+	var quote struct {
+		Price testDecimal
+		Count uint8
+		Raw   *big.Int
+	}
+	if err := abi.UnpackWithOptions(opts, &quote, "quote", output); err != nil {
+		t.Fatal(err)
+	}
+	if quote.Price.units.Cmp(big.NewInt(1500)) != 0 || quote.Price.exp != -2 {
+		t.Errorf("price mismatch: have %v", quote.Price)
+	}
+	if quote.Count != 3 || quote.Raw.Cmp(big.NewInt(7)) != 0 {
+		t.Errorf("plain values mismatch: have %d %v", quote.Count, quote.Raw)
+	}
+	if calls != 1 {
+		t.Errorf("factory call count mismatch: have %d, want 1", calls)
+	}
+	// Factory errors are propagated
+	errNoDecimals := errors.New("no decimals")
+	opts.NumberFactory = func(*big.Int, Type) (interface{}, error) { return nil, errNoDecimals }
+	if err := abi.UnpackWithOptions(opts, &quote, "quote", output); !errors.Is(err, errNoDecimals) {
+		t.Errorf("factory error not propagated: %v", err)
+	}
+}
+
+func TestUnpackIntegersAsStrings(t *testing.T) {
+	abi, err := JSON(strings.NewReader(`[
+		{"type":"function","name":"stats","outputs":[
+			{"name":"total","type":"uint256"},
+			{"name":"delta","type":"int256"},
+			{"name":"small","type":"int8"},
+			{"name":"history","type":"uint256[]"},
+			{"name":"point","type":"tuple","components":[{"name":"x","type":"int128"},{"name":"label","type":"string"}]}
+		]}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	type point struct {
+		X     *big.Int
+		Label string
+	}
+	huge, _ := new(big.Int).SetString("-123456789012345678901234567890", 10)
+	output, err := abi.Methods["stats"].Outputs.Pack(
+		new(big.Int).Lsh(common.Big1, 255), big.NewInt(-42), int8(-7),
+		[]*big.Int{big.NewInt(1), new(big.Int).SetUint64(gmath.MaxUint64)}, point{huge, "p"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stats struct {
+		Total   string
+		Delta   string
+		Small   string
+		History []string
+		Point   struct {
+			X     string
+			Label string
+		}
+	}
+	if err := abi.UnpackWithOptions(UnpackOptions{IntegersAsStrings: true}, &stats, "stats", output); err != nil {
+		t.Fatal(err)
+	}
+	if stats.Total != new(big.Int).Lsh(common.Big1, 255).String() || stats.Delta != "-42" || stats.Small != "-7" {
+		t.Errorf("integer mismatch: have %s %s %s", stats.Total, stats.Delta, stats.Small)
+	}
+	if !reflect.DeepEqual(stats.History, []string{"1", "18446744073709551615"}) {
+		t.Errorf("history mismatch: have %v", stats.History)
+	}
+	if stats.Point.X != "-123456789012345678901234567890" || stats.Point.Label != "p" {
+		t.Errorf("tuple mismatch: have %+v", stats.Point)
+	}
+}
+
+func BenchmarkUnpackIntegersAsStrings(b *testing.B) {
+	abi, err := JSON(strings.NewReader(singleOutputABI))
+	if err != nil {
+		b.Fatal(err)
+	}
+	data := common.LeftPadBytes(big.NewInt(1234567890).Bytes(), 32)
+
+	b.Run("string", func(b *testing.B) {
+		opts := UnpackOptions{IntegersAsStrings: true}
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			var balance string
+			abi.UnpackWithOptions(opts, &balance, "balanceOf", data)
+		}
+	})
+	b.Run("big", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			var balance *big.Int
+			abi.Unpack(&balance, "balanceOf", data)
+			_ = balance.String()
+		}
+	})
+}
+
+type hintedPayload interface{ kind() string }
+
+type transferPayload struct {
+	To     common.Address
+	Amount *big.Int
+}
+
+func (transferPayload) kind() string { return "transfer" }
+
+func TestUnpackTypeHints(t *testing.T) {
+	abi, err := JSON(strings.NewReader(`[
+		{"type":"function","name":"action","outputs":[
+			{"name":"kind","type":"uint8"},
+			{"name":"payload","type":"tuple","components":[{"name":"to","type":"address"},{"name":"amount","type":"uint256"}]}
+		]}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	output, err := abi.Methods["action"].Outputs.Pack(uint8(1), transferPayload{common.Address{1}, big.NewInt(10)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var action struct {
+		Kind    uint8
+		Payload hintedPayload
+	}
+	opts := UnpackOptions{TypeHints: map[string]reflect.Type{"Payload": reflect.TypeOf(transferPayload{})}}
+	if err := abi.UnpackWithOptions(opts, &action, "action", output); err != nil {
+		t.Fatal(err)
+	}
+	payload, ok := action.Payload.(transferPayload)
+	if !ok {
+		t.Fatalf("payload type mismatch: have %T", action.Payload)
+	}
+	if payload.To != (common.Address{1}) || payload.Amount.Cmp(big.NewInt(10)) != 0 {
+		t.Errorf("payload mismatch: have %+v", payload)
+	}
+	// Pointer hints allocate the pointed to value
+	var generic struct {
+		Kind    uint8
+		Payload interface{}
+	}
+	opts.TypeHints["Payload"] = reflect.TypeOf(&transferPayload{})
+	if err := abi.UnpackWithOptions(opts, &generic, "action", output); err != nil {
+		t.Fatal(err)
+	}
+	if ptr, ok := generic.Payload.(*transferPayload); !ok || ptr.Amount.Cmp(big.NewInt(10)) != 0 {
+		t.Errorf("pointer payload mismatch: have %#v", generic.Payload)
+	}
+	// Hints must satisfy the field's interface
+	opts.TypeHints["Payload"] = reflect.TypeOf(struct{ To common.Address }{})
+	if err := abi.UnpackWithOptions(opts, &action, "action", output); !errors.Is(err, ErrTypeMismatch) {
+		t.Errorf("expected type mismatch error, got %v", err)
+	}
+}
+
+func TestUnpackMaxDepth(t *testing.T) {
+	// A static array nested far deeper than any contract would return still
+	// encodes into a single word, so the depth is the only thing to trip on.
+	deep, err := NewType("uint8"+strings.Repeat("[1]", DefaultMaxDepth+1), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	word := make([]byte, 32)
+	if _, err := (Arguments{{Type: deep}}).UnpackValues(word); err == nil || !strings.Contains(err.Error(), "nesting depth") {
+		t.Fatalf("expected nesting depth error, got %v", err)
+	}
+	shallow, err := NewType("uint8[1][1][1]", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	args := Arguments{{Type: shallow}}
+	var out [1][1][1]uint8
+	if err := args.UnpackWithOptions(UnpackOptions{MaxDepth: 2}, &out, word); err == nil {
+		t.Fatal("expected nesting depth error with MaxDepth 2")
+	}
+	if err := args.UnpackWithOptions(UnpackOptions{MaxDepth: 3}, &out, word); err != nil {
+		t.Fatalf("unexpected error with MaxDepth 3: %v", err)
+	}
+}